@@ -1,36 +1,38 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
+	_ "time/tzdata" // user time zones must load even without system zoneinfo
 
 	"habit-tracker-backend/internal/auth"
 	"habit-tracker-backend/internal/database"
 	"habit-tracker-backend/internal/handlers"
 	"habit-tracker-backend/internal/middleware"
+	"habit-tracker-backend/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Load environment variables from backend directory
-	// Try multiple paths: current dir, parent dir (backend), and two levels up
-	envPaths := []string{"config.env", "../config.env", "../../config.env"}
-	var envLoaded bool
-	for _, path := range envPaths {
-		if err := godotenv.Load(path); err == nil {
-			log.Printf("Loaded config.env from: %s", path)
-			envLoaded = true
-			break
+	loadEnv()
+
+	// "server migrate <command>" manages the schema instead of serving HTTP
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal("Migration failed: ", err)
 		}
-	}
-	if !envLoaded {
-		log.Println("No config.env file found, using system environment variables")
+		return
 	}
 
 	// Initialize JWT
-	auth.InitJWT()
+	if err := auth.InitJWT(); err != nil {
+		log.Fatal("Failed to initialize JWT: ", err)
+	}
 
 	// Initialize database
 	if err := database.InitDB(); err != nil {
@@ -38,6 +40,20 @@ func main() {
 	}
 	defer database.CloseDB()
 
+	// Keep instances of recurring tasks created up to TASK_RECURRENCE_HORIZON_DAYS ahead
+	services.StartRecurringTaskJob(time.Hour)
+
+	// Spend streak freezes on missed days once each user's day is over
+	services.StartStreakFreezeJob(services.StreakFreezesPerMonth(), time.Hour)
+
+	// Send task, habit and journal reminders through the inbox, email and Web Push
+	dispatcher := services.NewNotificationDispatcher()
+	services.StartNotificationJob(dispatcher, time.Minute)
+
+	// Generate weekly and monthly journal reviews for users who turned them on
+	summarizer := services.NewJournalSummarizer()
+	services.StartJournalReviewJob(summarizer, dispatcher, time.Hour)
+
 	// Set Gin mode
 	ginMode := os.Getenv("GIN_MODE")
 	if ginMode == "" {
@@ -47,19 +63,33 @@ func main() {
 
 	// Create Gin router
 	r := gin.Default()
+	if err := r.SetTrustedProxies(middleware.TrustedProxiesFromEnv()); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
 
 	// Add CORS middleware
-	r.Use(middleware.CORSMiddleware())
+	r.Use(middleware.CORSMiddleware(middleware.CORSConfigFromEnv()))
 
 	// Initialize handlers
-	authHandler := &handlers.AuthHandler{}
-	habitHandler := &handlers.HabitHandler{}
+	authHandler := handlers.NewAuthHandler()
+	habitHandler := handlers.NewHabitHandler()
+	routineHandler := handlers.NewRoutineHandler()
 	taskHandler := &handlers.TaskHandler{}
-		journalHandler := handlers.NewJournalHandler()
+	projectHandler := handlers.NewProjectHandler()
+	labelHandler := handlers.NewLabelHandler()
+	notificationHandler := handlers.NewNotificationHandler(dispatcher)
+		journalHandler := handlers.NewJournalHandler(summarizer)
+	insightsHandler := handlers.NewInsightsHandler()
 		chatHandler := handlers.NewChatHandler()
 		noteHandler := handlers.NewNoteHandler()
 		meditationHandler := handlers.NewMeditationHandler()
 
+	// Rate limits, overridable as "<requests>/<duration>" via RATE_LIMIT_* env vars
+	rateLimitStore := middleware.NewMemoryStore()
+	authLimit := middleware.RateLimit(rateLimitStore, "auth", middleware.LimitFromEnv("RATE_LIMIT_AUTH", middleware.PerPeriod(20, time.Minute)))
+	apiLimit := middleware.RateLimit(rateLimitStore, "api", middleware.LimitFromEnv("RATE_LIMIT_API", middleware.PerPeriod(300, time.Minute)))
+	aiLimit := middleware.RateLimit(rateLimitStore, "ai", middleware.LimitFromEnv("RATE_LIMIT_AI", middleware.PerPeriod(30, time.Hour)))
+
 	// Health check endpoint
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -73,57 +103,153 @@ func main() {
 	{
 		// Authentication routes
 		auth := api.Group("/auth")
+		auth.Use(authLimit)
 		{
 			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
+			auth.POST("/login", middleware.LoginLockout(rateLimitStore), authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/logout-all", middleware.AuthMiddleware(), authHandler.LogoutAll)
+			auth.POST("/forgot-password", authHandler.ForgotPassword)
+			auth.POST("/reset-password", authHandler.ResetPassword)
+			auth.POST("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/resend-verification", middleware.AuthMiddleware(), authHandler.ResendVerification)
 			auth.GET("/me", middleware.AuthMiddleware(), authHandler.GetMe)
+			auth.PUT("/settings", middleware.AuthMiddleware(), authHandler.UpdateSettings)
 		}
 
 		// Habit routes
 		habits := api.Group("/habits")
-		habits.Use(middleware.AuthMiddleware())
+		habits.Use(middleware.AuthMiddleware(), apiLimit)
 		{
 		habits.GET("", habitHandler.GetHabits)
 		habits.POST("", habitHandler.CreateHabit)
 		habits.POST("/:id/complete", habitHandler.CompleteHabit)
+		habits.POST("/:id/log", habitHandler.LogHabit)
+		habits.PUT("/:id", habitHandler.UpdateHabit)
+		habits.DELETE("/:id", habitHandler.DeleteHabit)
+		habits.POST("/:id/archive", habitHandler.ArchiveHabit)
+		habits.POST("/:id/unarchive", habitHandler.UnarchiveHabit)
 		habits.GET("/completions", habitHandler.GetHabitCompletions)
+		habits.GET("/stats", habitHandler.GetAllHabitStats)
+		habits.GET("/:id/stats", habitHandler.GetHabitStats)
+		habits.GET("/rest-periods", habitHandler.GetRestPeriods)
+		habits.POST("/rest-periods", habitHandler.CreateRestPeriod)
+		habits.DELETE("/rest-periods/:id", habitHandler.DeleteRestPeriod)
+		habits.GET("/freezes", habitHandler.GetStreakFreezes)
+		habits.GET("/categories", habitHandler.GetCategories)
+		habits.POST("/categories", habitHandler.CreateCategory)
+		habits.PUT("/categories/:id", habitHandler.UpdateCategory)
+		habits.DELETE("/categories/:id", habitHandler.DeleteCategory)
+		habits.PUT("/:id/completions/:date", habitHandler.SetHabitCompletion)
+		}
+
+		// Routine routes
+		routines := api.Group("/routines")
+		routines.Use(middleware.AuthMiddleware(), apiLimit)
+		{
+			routines.GET("", routineHandler.GetRoutines)
+			routines.POST("", routineHandler.CreateRoutine)
+			routines.GET("/:id", routineHandler.GetRoutine)
+			routines.PUT("/:id", routineHandler.UpdateRoutine)
+			routines.DELETE("/:id", routineHandler.DeleteRoutine)
+			routines.POST("/:id/complete", routineHandler.CompleteRoutine)
+			routines.POST("/:id/step", routineHandler.StepRoutine)
 		}
 
 		// Task routes
 		tasks := api.Group("/tasks")
-		tasks.Use(middleware.AuthMiddleware())
+		tasks.Use(middleware.AuthMiddleware(), apiLimit)
 		{
 			tasks.GET("", taskHandler.GetTasks)
+			tasks.GET("/views", taskHandler.GetTaskViews)
+			tasks.GET("/recurring", taskHandler.GetRecurringTasks)
 			tasks.POST("", taskHandler.CreateTask)
+			tasks.POST("/quick", taskHandler.QuickAddTask)
 			tasks.POST("/:id/complete", taskHandler.CompleteTask)
+			tasks.PUT("/reorder", taskHandler.ReorderTasks)
+			tasks.PUT("/:id", taskHandler.UpdateTask)
+			tasks.DELETE("/:id", taskHandler.DeleteTask)
+		}
+
+		// Project routes
+		projects := api.Group("/projects")
+		projects.Use(middleware.AuthMiddleware(), apiLimit)
+		{
+			projects.GET("", projectHandler.GetProjects)
+			projects.POST("", projectHandler.CreateProject)
+			projects.GET("/:id", projectHandler.GetProject)
+			projects.PUT("/:id", projectHandler.UpdateProject)
+			projects.DELETE("/:id", projectHandler.DeleteProject)
+			projects.POST("/:id/archive", projectHandler.ArchiveProject)
+			projects.POST("/:id/unarchive", projectHandler.UnarchiveProject)
+		}
+
+		// Label routes
+		labels := api.Group("/labels")
+		labels.Use(middleware.AuthMiddleware(), apiLimit)
+		{
+			labels.GET("", labelHandler.GetLabels)
+			labels.POST("", labelHandler.CreateLabel)
+			labels.PUT("/:id", labelHandler.UpdateLabel)
+			labels.DELETE("/:id", labelHandler.DeleteLabel)
+		}
+
+		// Notification routes
+		notifications := api.Group("/notifications")
+		notifications.Use(middleware.AuthMiddleware(), apiLimit)
+		{
+			notifications.GET("", notificationHandler.GetNotifications)
+			notifications.POST("/read-all", notificationHandler.MarkAllNotificationsRead)
+			notifications.POST("/:id/read", notificationHandler.MarkNotificationRead)
+			notifications.GET("/push/key", notificationHandler.GetPushKey)
+			notifications.POST("/push/subscriptions", notificationHandler.CreatePushSubscription)
+			notifications.DELETE("/push/subscriptions", notificationHandler.DeletePushSubscription)
 		}
 
 		// Journal routes
 		journal := api.Group("/journal")
-		journal.Use(middleware.AuthMiddleware())
+		journal.Use(middleware.AuthMiddleware(), apiLimit)
 		{
 			journal.GET("", journalHandler.GetJournalEntries)
-			journal.POST("/generate-questions", journalHandler.GenerateJournalQuestions)
-			journal.POST("/summarize", journalHandler.SummarizeJournalEntries)
+			journal.GET("/search", journalHandler.SearchJournalEntries)
+			journal.GET("/tags", journalHandler.GetJournalTags)
+			journal.PUT("/tags/:id", journalHandler.RenameJournalTag)
+			journal.DELETE("/tags/:id", journalHandler.DeleteJournalTag)
+			journal.GET("/templates", journalHandler.GetJournalTemplates)
+			journal.POST("/templates", journalHandler.CreateJournalTemplate)
+			journal.PUT("/templates/:id", journalHandler.UpdateJournalTemplate)
+			journal.DELETE("/templates/:id", journalHandler.DeleteJournalTemplate)
+			journal.GET("/templates/:id/insights", journalHandler.GetJournalTemplateInsights)
+			journal.POST("/generate-questions", aiLimit, journalHandler.GenerateJournalQuestions)
+			journal.POST("/summarize", aiLimit, journalHandler.SummarizeJournalEntries)
+			journal.GET("/summaries", journalHandler.GetJournalSummaries)
 			journal.POST("", journalHandler.CreateOrUpdateJournalEntry)
 			journal.GET("/:date", journalHandler.GetJournalEntryByDate)
 			journal.PUT("/:id", journalHandler.UpdateJournalEntry)
 			journal.DELETE("/:id", journalHandler.DeleteJournalEntry)
 		}
 
+		// Insights routes
+		insights := api.Group("/insights")
+		insights.Use(middleware.AuthMiddleware(), apiLimit)
+		{
+			insights.GET("/mood", insightsHandler.GetMoodInsights)
+		}
+
 		// Chat routes (AI Coach)
 		chat := api.Group("/chat")
-		chat.Use(middleware.AuthMiddleware())
+		chat.Use(middleware.AuthMiddleware(), apiLimit)
 		{
 			chat.GET("/sessions", chatHandler.GetChatSessions)
 			chat.POST("/sessions", chatHandler.CreateChatSession)
 			chat.GET("/sessions/:id/messages", chatHandler.GetChatMessages)
-			chat.POST("/sessions/:id/messages", chatHandler.SendMessage)
+			chat.POST("/sessions/:id/messages", aiLimit, chatHandler.SendMessage)
 		}
 
 		// Notes/Plans routes
 		notes := api.Group("/notes")
-		notes.Use(middleware.AuthMiddleware())
+		notes.Use(middleware.AuthMiddleware(), apiLimit)
 		{
 			notes.GET("", noteHandler.GetNotes)
 			notes.POST("", noteHandler.CreateNote)
@@ -132,11 +258,13 @@ func main() {
 			notes.POST("/:id/checklist", noteHandler.CreateChecklistItem)
 			notes.PUT("/:id/checklist/:itemId", noteHandler.UpdateChecklistItem)
 			notes.DELETE("/:id/checklist/:itemId", noteHandler.DeleteChecklistItem)
+			notes.POST("/:id/checklist/:itemId/promote", noteHandler.PromoteChecklistItem)
 			notes.GET("/:id/plan", noteHandler.GetPlanData)
-			notes.POST("/:id/plan/answers", noteHandler.SavePlanAnswers)
-			notes.POST("/:id/plan/chat", noteHandler.UpdatePlanViaChat)
-			notes.POST("/:id/plan/adopt", noteHandler.AdoptPlan)
-			notes.POST("/:id/plan/generate-checklist", noteHandler.GenerateChecklist)
+			notes.GET("/:id/plan/progress", noteHandler.GetPlanProgress)
+			notes.POST("/:id/plan/answers", aiLimit, noteHandler.SavePlanAnswers)
+			notes.POST("/:id/plan/chat", aiLimit, noteHandler.UpdatePlanViaChat)
+			notes.POST("/:id/plan/adopt", aiLimit, noteHandler.AdoptPlan)
+			notes.POST("/:id/plan/generate-checklist", aiLimit, noteHandler.GenerateChecklist)
 			notes.POST("/:id/media", noteHandler.UploadMedia)
 			notes.GET("/:id/media", noteHandler.GetMediaAttachments)
 			notes.DELETE("/:id/media/:attachmentId", noteHandler.DeleteMediaAttachment)
@@ -144,14 +272,14 @@ func main() {
 
 		// Meditation/Reflection routes
 		meditation := api.Group("/meditation")
-		meditation.Use(middleware.AuthMiddleware())
+		meditation.Use(middleware.AuthMiddleware(), apiLimit)
 		{
 			meditation.GET("", meditationHandler.GetMeditationSessions)
-			meditation.POST("", meditationHandler.StartMeditation)
+			meditation.POST("", aiLimit, meditationHandler.StartMeditation)
 			meditation.GET("/:id", meditationHandler.GetMeditationSession)
-			meditation.POST("/:id/message", meditationHandler.SendMeditationMessage)
+			meditation.POST("/:id/message", aiLimit, meditationHandler.SendMeditationMessage)
 			meditation.POST("/:id/resume", meditationHandler.ResumeMeditation)
-			meditation.POST("/:id/end", meditationHandler.EndMeditation)
+			meditation.POST("/:id/end", aiLimit, meditationHandler.EndMeditation)
 		}
 	}
 
@@ -164,3 +292,73 @@ func main() {
 	log.Printf("Server starting on port %s", port)
 	log.Fatal(r.Run(":" + port))
 }
+
+// loadEnv loads environment variables from the backend directory.
+// Tries multiple paths: current dir, parent dir (backend), and two levels up.
+func loadEnv() {
+	envPaths := []string{"config.env", "../config.env", "../../config.env"}
+	for _, path := range envPaths {
+		if err := godotenv.Load(path); err == nil {
+			log.Printf("Loaded config.env from: %s", path)
+			return
+		}
+	}
+	log.Println("No config.env file found, using system environment variables")
+}
+
+// runMigrate implements the migrate subcommand: up, down, status and to <version>
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up | down | status | to <version>")
+	}
+
+	db, err := database.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db, database.MigrationsFS())
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied %d migration(s)", applied)
+	case "down":
+		return migrator.Down()
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: migrate to <version>")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return migrator.To(version)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if status.Modified {
+				state += " (MODIFIED since applied)"
+			}
+			fmt.Printf("%03d  %-35s %s\n", status.Version, status.Name, state)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q (expected up, down, status or to)", args[0])
+	}
+
+	return nil
+}
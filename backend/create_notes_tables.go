@@ -1,95 +0,0 @@
-package main
-
-import (
-	"database/sql"
-	"fmt"
-	"log"
-	"os"
-
-	"github.com/joho/godotenv"
-	_ "github.com/go-sql-driver/mysql"
-)
-
-func main() {
-	// Load environment variables
-	if err := godotenv.Load("config.env"); err != nil {
-		log.Println("No config.env file found, using system environment variables")
-	}
-
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-
-	if host == "" {
-		host = "localhost"
-	}
-	if port == "" {
-		port = "3306"
-	}
-
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		user, password, host, port, dbname)
-
-	fmt.Printf("Connecting to database...\n")
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
-	}
-	defer db.Close()
-
-	// Test the connection
-	if err = db.Ping(); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
-	}
-
-	fmt.Println("✅ Database connection successful!")
-	fmt.Println("\n📋 Creating notes tables...\n")
-
-	// Create notes table
-	fmt.Println("Creating notes table...")
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS notes (
-			id INT PRIMARY KEY AUTO_INCREMENT,
-			user_id INT NOT NULL,
-			title VARCHAR(200) NOT NULL,
-			content TEXT,
-			color VARCHAR(20) DEFAULT 'primary',
-			is_pinned BOOLEAN DEFAULT FALSE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-			INDEX idx_user_created (user_id, created_at DESC)
-		)
-	`)
-	if err != nil {
-		log.Printf("⚠️  Error creating notes table: %v", err)
-	} else {
-		fmt.Println("  ✅ Notes table created")
-	}
-
-	// Create checklist_items table
-	fmt.Println("Creating checklist_items table...")
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS checklist_items (
-			id INT PRIMARY KEY AUTO_INCREMENT,
-			note_id INT NOT NULL,
-			text VARCHAR(500) NOT NULL,
-			is_checked BOOLEAN DEFAULT FALSE,
-			position INT DEFAULT 0,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-			FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE,
-			INDEX idx_note_position (note_id, position)
-		)
-	`)
-	if err != nil {
-		log.Printf("⚠️  Error creating checklist_items table: %v", err)
-	} else {
-		fmt.Println("  ✅ Checklist_items table created")
-	}
-
-	fmt.Println("\n✅ Tables created successfully!")
-}
-
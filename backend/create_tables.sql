@@ -1,62 +0,0 @@
--- Create all required tables for habit tracker
-
--- Tasks table
-CREATE TABLE IF NOT EXISTS tasks (
-    id INT PRIMARY KEY AUTO_INCREMENT,
-    user_id INT NOT NULL,
-    title VARCHAR(200) NOT NULL,
-    description TEXT,
-    priority ENUM('high', 'medium', 'low') DEFAULT 'medium',
-    due_date TIMESTAMP NULL,
-    completed_at TIMESTAMP NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-);
-
--- Habit completions table (without generated column for compatibility)
-CREATE TABLE IF NOT EXISTS habit_completions (
-    id INT PRIMARY KEY AUTO_INCREMENT,
-    habit_id INT NOT NULL,
-    user_id INT NOT NULL,
-    completed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    streak_count INT DEFAULT 1,
-    FOREIGN KEY (habit_id) REFERENCES habits(id) ON DELETE CASCADE,
-    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-);
-
--- Journal entries table
-CREATE TABLE IF NOT EXISTS journal_entries (
-    id INT PRIMARY KEY AUTO_INCREMENT,
-    user_id INT NOT NULL,
-    entry_date DATE NOT NULL,
-    mood VARCHAR(50),
-    content TEXT,
-    tags JSON,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-    UNIQUE KEY unique_daily_entry (user_id, entry_date)
-);
-
--- Chat sessions table
-CREATE TABLE IF NOT EXISTS chat_sessions (
-    id INT PRIMARY KEY AUTO_INCREMENT,
-    user_id INT NOT NULL,
-    title VARCHAR(200),
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-);
-
--- Chat messages table
-CREATE TABLE IF NOT EXISTS chat_messages (
-    id INT PRIMARY KEY AUTO_INCREMENT,
-    session_id INT NOT NULL,
-    type ENUM('user', 'ai') NOT NULL,
-    content TEXT NOT NULL,
-    suggestions JSON,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (session_id) REFERENCES chat_sessions(id) ON DELETE CASCADE
-);
-
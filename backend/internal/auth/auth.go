@@ -11,31 +11,47 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-var jwtSecret []byte
+// Lifetimes of issued tokens, overridable via ACCESS_TOKEN_TTL / REFRESH_TOKEN_TTL
+var (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// InitJWT initializes the signing keyring and token lifetimes.
+// In release mode it refuses to start without a proper JWT_SECRET.
+func InitJWT() error {
+	ring, err := loadKeyring(os.Getenv("GIN_MODE") == "release")
+	if err != nil {
+		return err
+	}
+	signingKeys = ring
 
-// InitJWT initializes JWT secret
-func InitJWT() {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "default-secret-key"
+	if ttl, err := time.ParseDuration(os.Getenv("ACCESS_TOKEN_TTL")); err == nil && ttl > 0 {
+		accessTokenTTL = ttl
 	}
-	jwtSecret = []byte(secret)
+	if ttl, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_TTL")); err == nil && ttl > 0 {
+		refreshTokenTTL = ttl
+	}
+
+	return nil
 }
 
 // Claims represents JWT claims
 type Claims struct {
-	UserID int    `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    int    `json:"user_id"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid"` // refresh token family the access token belongs to
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a JWT token for a user
-func GenerateToken(userID int, email string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour) // 24 hours
-	
+// GenerateAccessToken generates a short-lived JWT access token bound to a session
+func GenerateAccessToken(userID int, email, sessionID string) (string, error) {
+	expirationTime := time.Now().Add(accessTokenTTL)
+
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -44,7 +60,8 @@ func GenerateToken(userID int, email string) (string, error) {
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
+	token.Header["kid"] = signingKeys.current.ID
+	tokenString, err := token.SignedString(signingKeys.current.Secret)
 	if err != nil {
 		return "", err
 	}
@@ -57,8 +74,17 @@ func ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 	
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+		// Tokens issued before key rotation support carry no kid
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return signingKeys.current.Secret, nil
+		}
+		key, ok := signingKeys.lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown or retired signing key %q", kid)
+		}
+		return key.Secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"habit-tracker-backend/internal/database"
+)
+
+// fakeHandler answers one SQL statement of the code under test
+type fakeHandler func(args []driver.Value) (fakeResult, error)
+
+// fakeResult is what a statement returns: rows for queries, counts for the others
+type fakeResult struct {
+	lastInsertID int64
+	rowsAffected int64
+	columns      []string
+	rows         [][]driver.Value
+}
+
+// useFakeDB points database.DB at an in-memory database for the duration of the test.
+// It answers the statements in handlers, keyed by their text with whitespace collapsed,
+// and fails on any other. Transactions aren't isolated, so tests can't rely on rollbacks.
+func useFakeDB(t *testing.T, handlers map[string]fakeHandler) {
+	t.Helper()
+	previous := database.DB
+	database.DB = sql.OpenDB(fakeConnector{handlers: handlers})
+	t.Cleanup(func() {
+		database.DB.Close()
+		database.DB = previous
+	})
+}
+
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+type fakeConnector struct {
+	handlers map[string]fakeHandler
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (c fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("fake database is opened with a connector")
+}
+
+type fakeConn fakeConnector
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	handler, ok := c.handlers[normalizeQuery(query)]
+	if !ok {
+		return nil, fmt.Errorf("unexpected statement %q", normalizeQuery(query))
+	}
+	return fakeStmt{handler: handler}, nil
+}
+
+func (fakeConn) Close() error              { return nil }
+func (fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	handler fakeHandler
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	result, err := s.handler(args)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	result, err := s.handler(args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{result: result}, nil
+}
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+type fakeRows struct {
+	result fakeResult
+	next   int
+}
+
+func (r *fakeRows) Columns() []string { return r.result.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.next])
+	r.next++
+	return nil
+}
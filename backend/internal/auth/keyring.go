@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// defaultSecret is only used outside release mode when JWT_SECRET is unset
+const defaultSecret = "default-secret-key"
+
+// minSecretLength is the shortest signing secret accepted in release mode
+const minSecretLength = 32
+
+// Placeholder secrets from the sample configs that must never sign real tokens
+var placeholderSecrets = map[string]bool{
+	defaultSecret:                                    true,
+	"your-secret-key-change-in-production":           true,
+	"your-super-secret-jwt-key-change-in-production": true,
+	"your-super-secret-jwt-key":                      true,
+	"very-long-random-secret-key":                    true,
+}
+
+// signingKey is an HMAC secret identified by the kid JWT header
+type signingKey struct {
+	ID        string
+	Secret    []byte
+	RetiresAt time.Time // zero for the current key
+}
+
+// keyring holds the key new tokens are signed with plus retired keys that are
+// still accepted until their grace period ends
+type keyring struct {
+	current signingKey
+	keys    map[string]signingKey
+}
+
+// retiredKeyConfig is one entry of JWT_RETIRED_KEYS
+type retiredKeyConfig struct {
+	ID        string    `json:"kid"`
+	Secret    string    `json:"secret"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+var signingKeys *keyring
+
+// loadKeyring builds the keyring from the environment:
+//
+//	JWT_SECRET        current signing secret (required in release mode)
+//	JWT_KEY_ID        kid of the current secret (defaults to a hash of the secret)
+//	JWT_RETIRED_KEYS  JSON array of {"kid", "secret", "expires_at"} still accepted for validation
+func loadKeyring(release bool) (*keyring, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if err := checkSecret(secret, release); err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		log.Println("WARNING: JWT_SECRET is not set, using an insecure default secret (not allowed with GIN_MODE=release)")
+		secret = defaultSecret
+	}
+
+	current := signingKey{ID: os.Getenv("JWT_KEY_ID"), Secret: []byte(secret)}
+	if current.ID == "" {
+		current.ID = deriveKeyID(secret)
+	}
+
+	ring := &keyring{
+		current: current,
+		keys:    map[string]signingKey{current.ID: current},
+	}
+
+	if raw := os.Getenv("JWT_RETIRED_KEYS"); raw != "" {
+		var retired []retiredKeyConfig
+		if err := json.Unmarshal([]byte(raw), &retired); err != nil {
+			return nil, fmt.Errorf("JWT_RETIRED_KEYS is not valid JSON: %v", err)
+		}
+		for _, cfg := range retired {
+			if cfg.Secret == "" || cfg.ExpiresAt.IsZero() {
+				return nil, fmt.Errorf("JWT_RETIRED_KEYS entries need a secret and expires_at")
+			}
+			if cfg.ID == "" {
+				cfg.ID = deriveKeyID(cfg.Secret)
+			}
+			if _, exists := ring.keys[cfg.ID]; exists {
+				return nil, fmt.Errorf("duplicate JWT key id %q", cfg.ID)
+			}
+			if time.Now().After(cfg.ExpiresAt) {
+				log.Printf("Ignoring retired JWT key %q, its grace period ended at %s", cfg.ID, cfg.ExpiresAt.Format(time.RFC3339))
+				continue
+			}
+			ring.keys[cfg.ID] = signingKey{ID: cfg.ID, Secret: []byte(cfg.Secret), RetiresAt: cfg.ExpiresAt}
+		}
+	}
+
+	return ring, nil
+}
+
+// checkSecret rejects missing, placeholder or short secrets in release mode
+func checkSecret(secret string, release bool) error {
+	if !release {
+		return nil
+	}
+	if secret == "" {
+		return fmt.Errorf("JWT_SECRET must be set when GIN_MODE=release")
+	}
+	if placeholderSecrets[secret] {
+		return fmt.Errorf("JWT_SECRET is still set to a sample value, generate a random secret")
+	}
+	if len(secret) < minSecretLength {
+		return fmt.Errorf("JWT_SECRET must be at least %d characters long", minSecretLength)
+	}
+	return nil
+}
+
+// lookup returns the key for a kid if it is current or still within its grace period
+func (r *keyring) lookup(kid string) (signingKey, bool) {
+	key, ok := r.keys[kid]
+	if !ok {
+		return signingKey{}, false
+	}
+	if !key.RetiresAt.IsZero() && time.Now().After(key.RetiresAt) {
+		return signingKey{}, false
+	}
+	return key, true
+}
+
+// deriveKeyID gives a stable, non-secret identifier for a secret
+func deriveKeyID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestCheckSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		release bool
+		wantErr bool
+	}{
+		{name: "anything goes outside release mode", secret: ""},
+		{name: "random secret", secret: "3f9a1c7e5b2d8f4a6c0e9b1d7f3a5c8e", release: true},
+		{name: "missing", secret: "", release: true, wantErr: true},
+		{name: "sample value", secret: "your-super-secret-jwt-key-change-in-production", release: true, wantErr: true},
+		{name: "default", secret: defaultSecret, release: true, wantErr: true},
+		{name: "too short", secret: "3f9a1c7e5b2d8f4a", release: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkSecret(tt.secret, tt.release); (err != nil) != tt.wantErr {
+				t.Errorf("checkSecret(%q, %v) error = %v, want error %v", tt.secret, tt.release, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	const secret = "3f9a1c7e5b2d8f4a6c0e9b1d7f3a5c8e"
+	future := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	tests := []struct {
+		name      string
+		keyID     string
+		retired   string
+		wantErr   bool
+		currentID string
+		wantKeys  []string
+	}{
+		{name: "key id derived from the secret", currentID: deriveKeyID(secret), wantKeys: []string{deriveKeyID(secret)}},
+		{name: "configured key id", keyID: "2026-10", currentID: "2026-10", wantKeys: []string{"2026-10"}},
+		{
+			name:      "retired keys",
+			keyID:     "2026-10",
+			retired:   `[{"kid":"2026-09","secret":"old","expires_at":"` + future + `"},{"secret":"older","expires_at":"` + future + `"}]`,
+			currentID: "2026-10",
+			wantKeys:  []string{"2026-10", "2026-09", deriveKeyID("older")},
+		},
+		{
+			name:      "retired key past its grace period",
+			keyID:     "2026-10",
+			retired:   `[{"kid":"2026-09","secret":"old","expires_at":"` + past + `"}]`,
+			currentID: "2026-10",
+			wantKeys:  []string{"2026-10"},
+		},
+		{name: "invalid json", retired: `{"kid":"2026-09"}`, wantErr: true},
+		{name: "retired key without secret", retired: `[{"kid":"2026-09","expires_at":"` + future + `"}]`, wantErr: true},
+		{name: "retired key without expiry", retired: `[{"kid":"2026-09","secret":"old"}]`, wantErr: true},
+		{name: "duplicate key id", keyID: "2026-10", retired: `[{"kid":"2026-10","secret":"old","expires_at":"` + future + `"}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", secret)
+			t.Setenv("JWT_KEY_ID", tt.keyID)
+			t.Setenv("JWT_RETIRED_KEYS", tt.retired)
+
+			ring, err := loadKeyring(true)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadKeyring() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if ring.current.ID != tt.currentID || string(ring.current.Secret) != secret {
+				t.Errorf("current key = %q, want %q", ring.current.ID, tt.currentID)
+			}
+			if len(ring.keys) != len(tt.wantKeys) {
+				t.Errorf("keys = %v, want %v", ring.keys, tt.wantKeys)
+			}
+			for _, kid := range tt.wantKeys {
+				if _, ok := ring.lookup(kid); !ok {
+					t.Errorf("lookup(%q) found no key", kid)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateTokenKeys(t *testing.T) {
+	current := signingKey{ID: "2026-10", Secret: []byte("current-secret-of-at-least-32-chars")}
+	retired := signingKey{ID: "2026-09", Secret: []byte("retired-secret-of-at-least-32-chars"), RetiresAt: time.Now().Add(time.Hour)}
+	expired := signingKey{ID: "2026-08", Secret: []byte("expired-secret-of-at-least-32-chars"), RetiresAt: time.Now().Add(-time.Hour)}
+
+	previous := signingKeys
+	signingKeys = &keyring{current: current, keys: map[string]signingKey{current.ID: current, retired.ID: retired, expired.ID: expired}}
+	t.Cleanup(func() { signingKeys = previous })
+
+	sign := func(method jwt.SigningMethod, kid string, secret []byte, expiresIn time.Duration) string {
+		claims := &Claims{
+			UserID: 1,
+			Email:  "user1@example.com",
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			},
+		}
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(secret)
+		if err != nil {
+			t.Fatalf("SignedString() failed: %v", err)
+		}
+		return signed
+	}
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "current key", token: sign(jwt.SigningMethodHS256, current.ID, current.Secret, time.Minute)},
+		{name: "retired key in its grace period", token: sign(jwt.SigningMethodHS256, retired.ID, retired.Secret, time.Minute)},
+		{name: "without kid, signed with the current key", token: sign(jwt.SigningMethodHS256, "", current.Secret, time.Minute)},
+		{name: "retired key past its grace period", token: sign(jwt.SigningMethodHS256, expired.ID, expired.Secret, time.Minute), wantErr: true},
+		{name: "unknown kid", token: sign(jwt.SigningMethodHS256, "2025-01", current.Secret, time.Minute), wantErr: true},
+		{name: "kid of another key", token: sign(jwt.SigningMethodHS256, current.ID, retired.Secret, time.Minute), wantErr: true},
+		{name: "without kid, signed with a retired key", token: sign(jwt.SigningMethodHS256, "", retired.Secret, time.Minute), wantErr: true},
+		{name: "other algorithm", token: sign(jwt.SigningMethodHS512, current.ID, current.Secret, time.Minute), wantErr: true},
+		{name: "expired token", token: sign(jwt.SigningMethodHS256, current.ID, current.Secret, -time.Minute), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ValidateToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateToken() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && claims.UserID != 1 {
+				t.Errorf("ValidateToken() user = %d, want 1", claims.UserID)
+			}
+		})
+	}
+}
+
+// New tokens are always signed with the current key, even while retired ones are accepted
+func TestGenerateAccessTokenSignsWithCurrentKey(t *testing.T) {
+	current := signingKey{ID: "2026-10", Secret: []byte("current-secret-of-at-least-32-chars")}
+	retired := signingKey{ID: "2026-09", Secret: []byte("retired-secret-of-at-least-32-chars"), RetiresAt: time.Now().Add(time.Hour)}
+	previous := signingKeys
+	signingKeys = &keyring{current: current, keys: map[string]signingKey{current.ID: current, retired.ID: retired}}
+	t.Cleanup(func() { signingKeys = previous })
+
+	signed, err := GenerateAccessToken(1, "user1@example.com", "family")
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() failed: %v", err)
+	}
+	token, err := jwt.ParseWithClaims(signed, &Claims{}, func(*jwt.Token) (interface{}, error) { return current.Secret, nil })
+	if err != nil {
+		t.Fatalf("token isn't signed with the current key: %v", err)
+	}
+	if kid := token.Header["kid"]; kid != current.ID {
+		t.Errorf("kid = %v, want %q", kid, current.ID)
+	}
+	if claims := token.Claims.(*Claims); claims.SessionID != "family" {
+		t.Errorf("sid = %q, want %q", claims.SessionID, "family")
+	}
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"habit-tracker-backend/internal/database"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown or expired refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is presented again
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+// TokenPair is an access token together with the refresh token that renews it
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int // access token lifetime in seconds
+}
+
+// IssueSession starts a new refresh token family for a user and returns its first token pair
+func IssueSession(userID int, email, userAgent, ipAddress string) (*TokenPair, error) {
+	familyID, err := GenerateRandomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := GenerateRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = database.DB.Exec(`
+		INSERT INTO user_sessions (user_id, family_id, token, expires_at, user_agent, ip_address)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, userID, familyID, hashToken(refreshToken), time.Now().Add(refreshTokenTTL), truncate(userAgent, 255), ipAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store session: %v", err)
+	}
+
+	return newTokenPair(userID, email, familyID, refreshToken)
+}
+
+// RotateRefreshToken exchanges a refresh token for a new token pair.
+// The presented token is invalidated; presenting it again revokes the whole family.
+func RotateRefreshToken(refreshToken, userAgent, ipAddress string) (*TokenPair, error) {
+	var sessionID, userID int
+	var familyID, email string
+	var expiresAt time.Time
+	var revokedAt sql.NullTime
+	err := database.DB.QueryRow(`
+		SELECT s.id, s.user_id, s.family_id, s.expires_at, s.revoked_at, u.email
+		FROM user_sessions s
+		INNER JOIN users u ON s.user_id = u.id
+		WHERE s.token = ?
+	`, hashToken(refreshToken)).Scan(&sessionID, &userID, &familyID, &expiresAt, &revokedAt, &email)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up session: %v", err)
+	}
+
+	if revokedAt.Valid {
+		log.Printf("Refresh token reuse detected for user %d, revoking session family %s", userID, familyID)
+		if err := RevokeFamily(familyID); err != nil {
+			log.Printf("Failed to revoke session family %s: %v", familyID, err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if time.Now().After(expiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	newRefreshToken, err := GenerateRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Only one concurrent request may consume the token
+	result, err := tx.Exec(`
+		UPDATE user_sessions SET revoked_at = NOW() WHERE id = ? AND revoked_at IS NULL
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke session: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		tx.Rollback()
+		if err := RevokeFamily(familyID); err != nil {
+			log.Printf("Failed to revoke session family %s: %v", familyID, err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	result, err = tx.Exec(`
+		INSERT INTO user_sessions (user_id, family_id, token, expires_at, user_agent, ip_address)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, userID, familyID, hashToken(newRefreshToken), time.Now().Add(refreshTokenTTL), truncate(userAgent, 255), ipAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store session: %v", err)
+	}
+	newSessionID, _ := result.LastInsertId()
+
+	if _, err := tx.Exec(`UPDATE user_sessions SET replaced_by = ? WHERE id = ?`, newSessionID, sessionID); err != nil {
+		return nil, fmt.Errorf("failed to link rotated session: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit session rotation: %v", err)
+	}
+
+	return newTokenPair(userID, email, familyID, newRefreshToken)
+}
+
+// RevokeRefreshToken ends the session family the given refresh token belongs to
+func RevokeRefreshToken(refreshToken string) error {
+	var familyID string
+	err := database.DB.QueryRow(`
+		SELECT family_id FROM user_sessions WHERE token = ?
+	`, hashToken(refreshToken)).Scan(&familyID)
+	if err == sql.ErrNoRows {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up session: %v", err)
+	}
+
+	return RevokeFamily(familyID)
+}
+
+// RevokeFamily revokes every refresh token of a session family
+func RevokeFamily(familyID string) error {
+	_, err := database.DB.Exec(`
+		UPDATE user_sessions SET revoked_at = NOW() WHERE family_id = ? AND revoked_at IS NULL
+	`, familyID)
+	return err
+}
+
+// RevokeAllSessions revokes every session of a user, logging them out everywhere
+func RevokeAllSessions(userID int) error {
+	_, err := database.DB.Exec(`
+		UPDATE user_sessions SET revoked_at = NOW() WHERE user_id = ? AND revoked_at IS NULL
+	`, userID)
+	return err
+}
+
+// IsSessionActive reports whether a session family still has a live refresh token
+func IsSessionActive(familyID string) (bool, error) {
+	var exists int
+	err := database.DB.QueryRow(`
+		SELECT 1 FROM user_sessions
+		WHERE family_id = ? AND revoked_at IS NULL AND expires_at > NOW()
+		LIMIT 1
+	`, familyID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func newTokenPair(userID int, email, familyID, refreshToken string) (*TokenPair, error) {
+	accessToken, err := GenerateAccessToken(userID, email, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+	}, nil
+}
+
+// hashToken returns the SHA-256 hex digest stored in place of a refresh token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
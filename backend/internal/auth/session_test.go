@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// useSigningKey signs and validates access tokens with a test key
+func useSigningKey(t *testing.T) {
+	t.Helper()
+	previous := signingKeys
+	key := signingKey{ID: "test", Secret: []byte("a-test-secret-of-at-least-32-chars")}
+	signingKeys = &keyring{current: key, keys: map[string]signingKey{key.ID: key}}
+	t.Cleanup(func() { signingKeys = previous })
+}
+
+// fakeSession is a row of user_sessions
+type fakeSession struct {
+	ID         int64
+	UserID     int64
+	FamilyID   string
+	Token      string // hashed
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	ReplacedBy int64
+}
+
+// sessionTable is an in-memory user_sessions table. User n has the email userN@example.com.
+type sessionTable struct {
+	sessions []*fakeSession
+}
+
+// revoke revokes the live sessions matching and returns how many there were
+func (s *sessionTable) revoke(matches func(row *fakeSession) bool) fakeResult {
+	now := time.Now()
+	var affected int64
+	for _, row := range s.sessions {
+		if row.RevokedAt == nil && matches(row) {
+			row.RevokedAt = &now
+			affected++
+		}
+	}
+	return fakeResult{rowsAffected: affected}
+}
+
+func (s *sessionTable) byToken(hash driver.Value) *fakeSession {
+	for _, row := range s.sessions {
+		if row.Token == hash {
+			return row
+		}
+	}
+	return nil
+}
+
+func (s *sessionTable) handlers() map[string]fakeHandler {
+	return map[string]fakeHandler{
+		"INSERT INTO user_sessions (user_id, family_id, token, expires_at, user_agent, ip_address) VALUES (?, ?, ?, ?, ?, ?)": func(args []driver.Value) (fakeResult, error) {
+			row := &fakeSession{
+				ID:        int64(len(s.sessions) + 1),
+				UserID:    args[0].(int64),
+				FamilyID:  args[1].(string),
+				Token:     args[2].(string),
+				ExpiresAt: args[3].(time.Time),
+			}
+			s.sessions = append(s.sessions, row)
+			return fakeResult{lastInsertID: row.ID, rowsAffected: 1}, nil
+		},
+		"SELECT s.id, s.user_id, s.family_id, s.expires_at, s.revoked_at, u.email FROM user_sessions s INNER JOIN users u ON s.user_id = u.id WHERE s.token = ?": func(args []driver.Value) (fakeResult, error) {
+			result := fakeResult{columns: []string{"id", "user_id", "family_id", "expires_at", "revoked_at", "email"}}
+			if row := s.byToken(args[0]); row != nil {
+				var revokedAt driver.Value
+				if row.RevokedAt != nil {
+					revokedAt = *row.RevokedAt
+				}
+				email := fmt.Sprintf("user%d@example.com", row.UserID)
+				result.rows = append(result.rows, []driver.Value{row.ID, row.UserID, row.FamilyID, row.ExpiresAt, revokedAt, email})
+			}
+			return result, nil
+		},
+		"SELECT family_id FROM user_sessions WHERE token = ?": func(args []driver.Value) (fakeResult, error) {
+			result := fakeResult{columns: []string{"family_id"}}
+			if row := s.byToken(args[0]); row != nil {
+				result.rows = append(result.rows, []driver.Value{row.FamilyID})
+			}
+			return result, nil
+		},
+		"SELECT 1 FROM user_sessions WHERE family_id = ? AND revoked_at IS NULL AND expires_at > NOW() LIMIT 1": func(args []driver.Value) (fakeResult, error) {
+			result := fakeResult{columns: []string{"1"}}
+			for _, row := range s.sessions {
+				if row.FamilyID == args[0] && row.RevokedAt == nil && row.ExpiresAt.After(time.Now()) {
+					result.rows = append(result.rows, []driver.Value{int64(1)})
+					break
+				}
+			}
+			return result, nil
+		},
+		"UPDATE user_sessions SET revoked_at = NOW() WHERE id = ? AND revoked_at IS NULL": func(args []driver.Value) (fakeResult, error) {
+			return s.revoke(func(row *fakeSession) bool { return row.ID == args[0] }), nil
+		},
+		"UPDATE user_sessions SET revoked_at = NOW() WHERE family_id = ? AND revoked_at IS NULL": func(args []driver.Value) (fakeResult, error) {
+			return s.revoke(func(row *fakeSession) bool { return row.FamilyID == args[0] }), nil
+		},
+		"UPDATE user_sessions SET revoked_at = NOW() WHERE user_id = ? AND revoked_at IS NULL": func(args []driver.Value) (fakeResult, error) {
+			return s.revoke(func(row *fakeSession) bool { return row.UserID == args[0] }), nil
+		},
+		"UPDATE user_sessions SET replaced_by = ? WHERE id = ?": func(args []driver.Value) (fakeResult, error) {
+			var affected int64
+			for _, row := range s.sessions {
+				if row.ID == args[1] {
+					row.ReplacedBy = args[0].(int64)
+					affected++
+				}
+			}
+			return fakeResult{rowsAffected: affected}, nil
+		},
+	}
+}
+
+// sessionFamily returns the session family an access token belongs to
+func sessionFamily(t *testing.T, pair *TokenPair) string {
+	t.Helper()
+	claims, err := ValidateToken(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken() failed: %v", err)
+	}
+	return claims.SessionID
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		present    func(first, second string) string // the token presented after first was rotated to second
+		expired    bool                              // second expired before it is presented
+		wantErr    error
+		wantActive bool // the session family is still active afterwards
+	}{
+		{name: "latest token", present: func(_, second string) string { return second }, wantActive: true},
+		{name: "rotated token presented again", present: func(first, _ string) string { return first }, wantErr: ErrRefreshTokenReused},
+		{name: "unknown token", present: func(string, string) string { return "unknown" }, wantErr: ErrInvalidRefreshToken, wantActive: true},
+		{name: "expired token", present: func(_, second string) string { return second }, expired: true, wantErr: ErrInvalidRefreshToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSigningKey(t)
+			table := &sessionTable{}
+			useFakeDB(t, table.handlers())
+
+			issued, err := IssueSession(1, "user1@example.com", "test", "127.0.0.1")
+			if err != nil {
+				t.Fatalf("IssueSession() failed: %v", err)
+			}
+			other, err := IssueSession(1, "user1@example.com", "other device", "127.0.0.1")
+			if err != nil {
+				t.Fatalf("IssueSession() failed: %v", err)
+			}
+			family := sessionFamily(t, issued)
+
+			rotated, err := RotateRefreshToken(issued.RefreshToken, "test", "127.0.0.1")
+			if err != nil {
+				t.Fatalf("RotateRefreshToken() failed: %v", err)
+			}
+			if rotated.RefreshToken == issued.RefreshToken || sessionFamily(t, rotated) != family {
+				t.Fatalf("rotation gave refresh token %q of family %q, want a new token of family %q", rotated.RefreshToken, sessionFamily(t, rotated), family)
+			}
+			first, second := table.byToken(hashToken(issued.RefreshToken)), table.byToken(hashToken(rotated.RefreshToken))
+			if first.RevokedAt == nil || first.ReplacedBy != second.ID {
+				t.Fatalf("rotated session = %+v, want it revoked and replaced by session %d", first, second.ID)
+			}
+			if tt.expired {
+				second.ExpiresAt = time.Now().Add(-time.Minute)
+			}
+
+			pair, err := RotateRefreshToken(tt.present(issued.RefreshToken, rotated.RefreshToken), "test", "127.0.0.1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RotateRefreshToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && sessionFamily(t, pair) != family {
+				t.Errorf("RotateRefreshToken() continued family %q, want %q", sessionFamily(t, pair), family)
+			}
+			if active, err := IsSessionActive(family); err != nil || active != tt.wantActive {
+				t.Errorf("IsSessionActive() = %v, %v, want %v", active, err, tt.wantActive)
+			}
+			if active, err := IsSessionActive(sessionFamily(t, other)); err != nil || !active {
+				t.Errorf("the user's other session is active = %v, %v, want it untouched", active, err)
+			}
+		})
+	}
+}
+
+// After a reused token revoked its family, none of the family's tokens can be rotated
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	useSigningKey(t)
+	table := &sessionTable{}
+	useFakeDB(t, table.handlers())
+
+	issued, err := IssueSession(1, "user1@example.com", "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("IssueSession() failed: %v", err)
+	}
+	rotated, err := RotateRefreshToken(issued.RefreshToken, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("RotateRefreshToken() failed: %v", err)
+	}
+
+	// An attacker replays the stolen first token
+	if _, err := RotateRefreshToken(issued.RefreshToken, "attacker", "10.0.0.1"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replaying a rotated token: error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	if _, err := RotateRefreshToken(rotated.RefreshToken, "test", "127.0.0.1"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("rotating the latest token after the replay: error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	for _, row := range table.sessions {
+		if row.RevokedAt == nil {
+			t.Errorf("session %d is still live after the family was revoked", row.ID)
+		}
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+	tests := []struct {
+		name       string
+		revoke     func(sessions []*TokenPair) error
+		wantErr    error
+		wantActive []bool // of two sessions of user 1 and one of user 2
+	}{
+		{
+			name:       "log out",
+			revoke:     func(sessions []*TokenPair) error { return RevokeRefreshToken(sessions[0].RefreshToken) },
+			wantActive: []bool{false, true, true},
+		},
+		{
+			name: "log out with a rotated token",
+			revoke: func(sessions []*TokenPair) error {
+				rotated, err := RotateRefreshToken(sessions[1].RefreshToken, "test", "127.0.0.1")
+				if err != nil {
+					return err
+				}
+				return RevokeRefreshToken(rotated.RefreshToken)
+			},
+			wantActive: []bool{true, false, true},
+		},
+		{
+			name:       "unknown token",
+			revoke:     func([]*TokenPair) error { return RevokeRefreshToken("unknown") },
+			wantErr:    ErrInvalidRefreshToken,
+			wantActive: []bool{true, true, true},
+		},
+		{
+			name:       "log out everywhere",
+			revoke:     func([]*TokenPair) error { return RevokeAllSessions(1) },
+			wantActive: []bool{false, false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSigningKey(t)
+			table := &sessionTable{}
+			useFakeDB(t, table.handlers())
+
+			var sessions []*TokenPair
+			for _, userID := range []int{1, 1, 2} {
+				pair, err := IssueSession(userID, fmt.Sprintf("user%d@example.com", userID), "test", "127.0.0.1")
+				if err != nil {
+					t.Fatalf("IssueSession() failed: %v", err)
+				}
+				sessions = append(sessions, pair)
+			}
+
+			if err := tt.revoke(sessions); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("revoking: error = %v, want %v", err, tt.wantErr)
+			}
+			for i, pair := range sessions {
+				if active, err := IsSessionActive(sessionFamily(t, pair)); err != nil || active != tt.wantActive[i] {
+					t.Errorf("session %d active = %v, %v, want %v", i, active, err, tt.wantActive[i])
+				}
+			}
+		})
+	}
+}
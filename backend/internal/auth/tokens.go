@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"habit-tracker-backend/internal/database"
+)
+
+// Purposes of single-use tokens sent by email
+const (
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailVerification = "email_verification"
+)
+
+// Lifetimes of emailed tokens
+const (
+	PasswordResetTTL     = time.Hour
+	EmailVerificationTTL = 48 * time.Hour
+)
+
+// ErrInvalidActionToken is returned for unknown, expired or already used tokens
+var ErrInvalidActionToken = errors.New("invalid or expired token")
+
+// CreateActionToken issues a single-use token for a user and purpose.
+// Earlier unused tokens of the same purpose are invalidated so only the newest link works.
+func CreateActionToken(userID int, purpose string, ttl time.Duration) (string, error) {
+	token, err := GenerateRandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE auth_tokens SET used_at = NOW()
+		WHERE user_id = ? AND purpose = ? AND used_at IS NULL
+	`, userID, purpose)
+	if err != nil {
+		return "", fmt.Errorf("failed to invalidate previous tokens: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO auth_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES (?, ?, ?, ?)
+	`, userID, purpose, hashToken(token), time.Now().Add(ttl))
+	if err != nil {
+		return "", fmt.Errorf("failed to store token: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit token: %v", err)
+	}
+
+	return token, nil
+}
+
+// ConsumeActionToken redeems a token for the given purpose and returns the user it belongs to.
+// A token can only be consumed once.
+func ConsumeActionToken(token, purpose string) (int, error) {
+	var tokenID, userID int
+	var expiresAt time.Time
+	err := database.DB.QueryRow(`
+		SELECT id, user_id, expires_at FROM auth_tokens
+		WHERE token_hash = ? AND purpose = ? AND used_at IS NULL
+	`, hashToken(token), purpose).Scan(&tokenID, &userID, &expiresAt)
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidActionToken
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up token: %v", err)
+	}
+	if time.Now().After(expiresAt) {
+		return 0, ErrInvalidActionToken
+	}
+
+	// Only one concurrent request may redeem the token
+	result, err := database.DB.Exec(`
+		UPDATE auth_tokens SET used_at = NOW() WHERE id = ? AND used_at IS NULL
+	`, tokenID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark token as used: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return 0, ErrInvalidActionToken
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeActionToken is a row of auth_tokens
+type fakeActionToken struct {
+	ID        int64
+	UserID    int64
+	Purpose   string
+	Hash      string
+	ExpiresAt time.Time
+	Used      bool
+}
+
+// actionTokenTable is an in-memory auth_tokens table
+type actionTokenTable struct {
+	tokens []*fakeActionToken
+}
+
+func (a *actionTokenTable) handlers() map[string]fakeHandler {
+	return map[string]fakeHandler{
+		"UPDATE auth_tokens SET used_at = NOW() WHERE user_id = ? AND purpose = ? AND used_at IS NULL": func(args []driver.Value) (fakeResult, error) {
+			var affected int64
+			for _, row := range a.tokens {
+				if row.UserID == args[0] && row.Purpose == args[1] && !row.Used {
+					row.Used = true
+					affected++
+				}
+			}
+			return fakeResult{rowsAffected: affected}, nil
+		},
+		"INSERT INTO auth_tokens (user_id, purpose, token_hash, expires_at) VALUES (?, ?, ?, ?)": func(args []driver.Value) (fakeResult, error) {
+			row := &fakeActionToken{
+				ID:        int64(len(a.tokens) + 1),
+				UserID:    args[0].(int64),
+				Purpose:   args[1].(string),
+				Hash:      args[2].(string),
+				ExpiresAt: args[3].(time.Time),
+			}
+			a.tokens = append(a.tokens, row)
+			return fakeResult{lastInsertID: row.ID, rowsAffected: 1}, nil
+		},
+		"SELECT id, user_id, expires_at FROM auth_tokens WHERE token_hash = ? AND purpose = ? AND used_at IS NULL": func(args []driver.Value) (fakeResult, error) {
+			result := fakeResult{columns: []string{"id", "user_id", "expires_at"}}
+			for _, row := range a.tokens {
+				if row.Hash == args[0] && row.Purpose == args[1] && !row.Used {
+					result.rows = append(result.rows, []driver.Value{row.ID, row.UserID, row.ExpiresAt})
+				}
+			}
+			return result, nil
+		},
+		"UPDATE auth_tokens SET used_at = NOW() WHERE id = ? AND used_at IS NULL": func(args []driver.Value) (fakeResult, error) {
+			var affected int64
+			for _, row := range a.tokens {
+				if row.ID == args[0] && !row.Used {
+					row.Used = true
+					affected++
+				}
+			}
+			return fakeResult{rowsAffected: affected}, nil
+		},
+	}
+}
+
+func TestConsumeActionToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		purpose  string // the token is consumed for
+		consumed bool   // the token was consumed before
+		reissued bool   // a newer token of the same purpose was issued since
+		other    bool   // a token for another purpose was issued since
+		wantErr  error
+	}{
+		{name: "fresh token", ttl: PasswordResetTTL, purpose: PurposePasswordReset},
+		{name: "used twice", ttl: PasswordResetTTL, purpose: PurposePasswordReset, consumed: true, wantErr: ErrInvalidActionToken},
+		{name: "expired", ttl: -time.Minute, purpose: PurposePasswordReset, wantErr: ErrInvalidActionToken},
+		{name: "other purpose", ttl: PasswordResetTTL, purpose: PurposeEmailVerification, wantErr: ErrInvalidActionToken},
+		{name: "superseded by a newer link", ttl: PasswordResetTTL, purpose: PurposePasswordReset, reissued: true, wantErr: ErrInvalidActionToken},
+		{name: "link for another purpose issued since", ttl: PasswordResetTTL, purpose: PurposePasswordReset, other: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeDB(t, (&actionTokenTable{}).handlers())
+
+			token, err := CreateActionToken(7, PurposePasswordReset, tt.ttl)
+			if err != nil {
+				t.Fatalf("CreateActionToken() failed: %v", err)
+			}
+			if tt.consumed {
+				if _, err := ConsumeActionToken(token, PurposePasswordReset); err != nil {
+					t.Fatalf("ConsumeActionToken() failed: %v", err)
+				}
+			}
+			if tt.reissued {
+				newer, err := CreateActionToken(7, PurposePasswordReset, PasswordResetTTL)
+				if err != nil {
+					t.Fatalf("CreateActionToken() failed: %v", err)
+				}
+				if userID, err := ConsumeActionToken(newer, PurposePasswordReset); err != nil || userID != 7 {
+					t.Errorf("ConsumeActionToken(newer) = %d, %v, want user 7", userID, err)
+				}
+			}
+			if tt.other {
+				if _, err := CreateActionToken(7, PurposeEmailVerification, EmailVerificationTTL); err != nil {
+					t.Fatalf("CreateActionToken() failed: %v", err)
+				}
+			}
+
+			userID, err := ConsumeActionToken(token, tt.purpose)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ConsumeActionToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && userID != 7 {
+				t.Errorf("ConsumeActionToken() = user %d, want 7", userID)
+			}
+		})
+	}
+}
+
+// Only a hash of the emailed token is stored
+func TestCreateActionTokenStoresHash(t *testing.T) {
+	table := &actionTokenTable{}
+	useFakeDB(t, table.handlers())
+
+	token, err := CreateActionToken(7, PurposeEmailVerification, EmailVerificationTTL)
+	if err != nil {
+		t.Fatalf("CreateActionToken() failed: %v", err)
+	}
+	if len(table.tokens) != 1 || table.tokens[0].Hash != hashToken(token) || table.tokens[0].Hash == token {
+		t.Errorf("stored tokens = %+v, want the hash of %q", table.tokens, token)
+	}
+}
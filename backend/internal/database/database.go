@@ -2,17 +2,20 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 var DB *sql.DB
 
-// InitDB initializes the database connection
-func InitDB() error {
+// DSN builds the MySQL connection string from the DB_* environment variables.
+// Sessions run in UTC so TIMESTAMP values don't depend on the server's zone;
+// calendar days are computed per user (see services.UserLocation).
+func DSN() string {
 	host := os.Getenv("DB_HOST")
 	port := os.Getenv("DB_PORT")
 	user := os.Getenv("DB_USER")
@@ -26,28 +29,62 @@ func InitDB() error {
 		port = "3306"
 	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=UTC&time_zone=%%27%%2B00%%3A00%%27",
 		user, password, host, port, dbname)
+}
 
-	var err error
-	DB, err = sql.Open("mysql", dsn)
+// Open opens and pings a new connection pool without touching the global DB
+func Open() (*sql.DB, error) {
+	db, err := sql.Open("mysql", DSN())
 	if err != nil {
-		return fmt.Errorf("failed to open database: %v", err)
+		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
 
 	// Test the connection
-	if err = DB.Ping(); err != nil {
-		return fmt.Errorf("failed to ping database: %v", err)
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %v", err)
 	}
 
 	// Set connection pool settings
-	DB.SetMaxOpenConns(25)
-	DB.SetMaxIdleConns(25)
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(25)
+
+	return db, nil
+}
+
+// InitDB initializes the database connection.
+// When DB_AUTO_MIGRATE is "true", pending migrations are applied before returning.
+func InitDB() error {
+	var err error
+	DB, err = Open()
+	if err != nil {
+		return err
+	}
 
 	log.Println("Database connection established successfully")
+
+	if os.Getenv("DB_AUTO_MIGRATE") == "true" {
+		migrator, err := NewMigrator(DB, MigrationsFS())
+		if err != nil {
+			return fmt.Errorf("failed to load migrations: %v", err)
+		}
+		applied, err := migrator.Up()
+		if err != nil {
+			return fmt.Errorf("failed to run migrations: %v", err)
+		}
+		log.Printf("Applied %d pending migration(s)", applied)
+	}
+
 	return nil
 }
 
+// IsDuplicateKey reports whether err is a MySQL unique key violation
+func IsDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
 // CloseDB closes the database connection
 func CloseDB() error {
 	if DB != nil {
@@ -0,0 +1,461 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"habit-tracker-backend/migrations"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// migrationLockName is the MySQL advisory lock held while migrations run so
+// that several containers starting at once don't apply the same files twice
+const migrationLockName = "habit_tracker_schema_migrations"
+
+// migrationLockTimeout is how long (in seconds) to wait for another migrator to finish
+const migrationLockTimeout = 60
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_]+?)(\.down)?\.sql$`)
+
+// MySQL error numbers that mean an "up" statement was already applied by hand
+// (e.g. with one of the old one-off scripts) and can be skipped safely
+var alreadyAppliedErrors = map[uint16]bool{
+	1050: true, // table already exists
+	1060: true, // duplicate column name
+	1061: true, // duplicate key name
+	1826: true, // duplicate foreign key constraint name
+}
+
+// MySQL error numbers that mean a "down" statement has nothing left to undo
+var alreadyRevertedErrors = map[uint16]bool{
+	1051: true, // unknown table
+	1091: true, // can't drop field or key; check that it exists
+}
+
+// Migration is a single versioned schema change loaded from the migrations directory
+type Migration struct {
+	Version  int
+	Name     string
+	UpSQL    string
+	DownSQL  string
+	Checksum string
+}
+
+// MigrationStatus describes whether a migration has been applied
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Modified  bool       `json:"modified"` // file changed after it was applied
+}
+
+// appliedMigration is a row of the schema_migrations tracking table
+type appliedMigration struct {
+	Version   int
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Migrator applies and rolls back migrations against a database
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// MigrationsFS returns the migration files to use: the MIGRATIONS_DIR directory
+// if set, otherwise the files embedded into the binary
+func MigrationsFS() fs.FS {
+	if dir := os.Getenv("MIGRATIONS_DIR"); dir != "" {
+		return os.DirFS(dir)
+	}
+	return migrations.FS
+}
+
+// NewMigrator creates a migrator for the migration files found in fsys
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	loaded, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: loaded}, nil
+}
+
+// loadMigrations reads NNN_name.sql / NNN_name.down.sql pairs sorted by version
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %v", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %v", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %q and %q", version, m.Name, match[2])
+		}
+
+		if match[3] == ".down" {
+			m.DownSQL = string(content)
+		} else {
+			m.UpSQL = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.UpSQL == "" {
+			return nil, fmt.Errorf("migration %03d_%s has a down file but no up file", m.Version, m.Name)
+		}
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	return result, nil
+}
+
+// Up applies all pending migrations and returns how many were applied
+func (m *Migrator) Up() (int, error) {
+	if len(m.migrations) == 0 {
+		return 0, nil
+	}
+	return m.migrateTo(m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down rolls back the most recently applied migration
+func (m *Migrator) Down() error {
+	ctx := context.Background()
+	conn, release, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	applied, err := loadApplied(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if _, ok := applied[m.migrations[i].Version]; ok {
+			return revert(ctx, conn, m.migrations[i])
+		}
+	}
+
+	log.Println("No applied migrations to roll back")
+	return nil
+}
+
+// To migrates up or down until version is the latest applied migration.
+// A version of 0 rolls back every migration.
+func (m *Migrator) To(version int) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+	_, err := m.migrateTo(version)
+	return err
+}
+
+// Status lists every known migration along with its applied state
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %v", err)
+	}
+	defer conn.Close()
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	applied, err := loadApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = row.Checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// migrateTo applies migrations up to and including target and rolls back any above it
+func (m *Migrator) migrateTo(target int) (int, error) {
+	ctx := context.Background()
+	conn, release, err := m.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	applied, err := loadApplied(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	// Refuse to build on top of files that were edited after being applied
+	if err := checkChecksums(m.migrations, applied); err != nil {
+		return 0, err
+	}
+
+	// Roll back everything above the target, newest first
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version <= target {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			if err := revert(ctx, conn, migration); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	// Apply everything pending up to the target, oldest first
+	count := 0
+	for _, migration := range m.migrations {
+		if migration.Version > target {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := apply(ctx, conn, migration); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// checkChecksums returns an error for the first applied migration whose file changed
+// since it was applied
+func checkChecksums(migrations []Migration, applied map[int]appliedMigration) error {
+	for _, migration := range migrations {
+		if row, ok := applied[migration.Version]; ok && row.Checksum != migration.Checksum {
+			return fmt.Errorf("checksum mismatch for applied migration %03d_%s: the file was modified after it was applied",
+				migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) find(version int) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// lock pins a connection and takes the advisory migration lock on it.
+// The returned release func unlocks and returns the connection to the pool.
+func (m *Migrator) lock(ctx context.Context) (*sql.Conn, func(), error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get connection: %v", err)
+	}
+
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLockName, migrationLockTimeout).Scan(&acquired)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to acquire migration lock: %v", err)
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		conn.Close()
+		return nil, nil, fmt.Errorf("timed out after %ds waiting for migration lock %q", migrationLockTimeout, migrationLockName)
+	}
+
+	release := func() {
+		if _, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", migrationLockName); err != nil {
+			log.Printf("Failed to release migration lock: %v", err)
+		}
+		conn.Close()
+	}
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	return conn, release, nil
+}
+
+func ensureMigrationsTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+	return nil
+}
+
+func loadApplied(ctx context.Context, conn *sql.Conn) (map[int]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %v", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var row appliedMigration
+		if err := rows.Scan(&row.Version, &row.Checksum, &row.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations row: %v", err)
+		}
+		applied[row.Version] = row
+	}
+	return applied, rows.Err()
+}
+
+func apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	log.Printf("Applying migration %03d_%s", migration.Version, migration.Name)
+	if err := execStatements(ctx, conn, migration.UpSQL, alreadyAppliedErrors); err != nil {
+		return fmt.Errorf("migration %03d_%s failed: %v", migration.Version, migration.Name, err)
+	}
+
+	_, err := conn.ExecContext(ctx, `
+		INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)
+	`, migration.Version, migration.Name, migration.Checksum)
+	if err != nil {
+		return fmt.Errorf("failed to record migration %03d_%s: %v", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	if strings.TrimSpace(migration.DownSQL) == "" {
+		return fmt.Errorf("migration %03d_%s has no down file", migration.Version, migration.Name)
+	}
+
+	log.Printf("Rolling back migration %03d_%s", migration.Version, migration.Name)
+	if err := execStatements(ctx, conn, migration.DownSQL, alreadyRevertedErrors); err != nil {
+		return fmt.Errorf("rollback of %03d_%s failed: %v", migration.Version, migration.Name, err)
+	}
+
+	_, err := conn.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, migration.Version)
+	if err != nil {
+		return fmt.Errorf("failed to unrecord migration %03d_%s: %v", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// execStatements runs each statement of a migration file in order.
+// MySQL commits DDL implicitly, so there is no surrounding transaction.
+func execStatements(ctx context.Context, conn *sql.Conn, script string, tolerated map[uint16]bool) error {
+	for _, statement := range splitStatements(script) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && tolerated[mysqlErr.Number] {
+				log.Printf("  skipping statement, already in target state: %v", err)
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a SQL script on semicolons, ignoring semicolons inside
+// quotes and dropping -- and /* */ comments
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	var quote rune
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if quote != 0 {
+			current.WriteRune(r)
+			if r == '\\' && quote != '`' && i+1 < len(runes) {
+				i++
+				current.WriteRune(runes[i])
+			} else if r == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+			current.WriteRune(r)
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			current.WriteRune('\n')
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i++
+		case r == ';':
+			if statement := strings.TrimSpace(current.String()); statement != "" {
+				statements = append(statements, statement)
+			}
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if statement := strings.TrimSpace(current.String()); statement != "" {
+		statements = append(statements, statement)
+	}
+
+	return statements
+}
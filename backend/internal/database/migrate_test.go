@@ -0,0 +1,187 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"habit-tracker-backend/migrations"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "statements",
+			script: "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n",
+			want:   []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"},
+		},
+		{
+			name:   "last statement without semicolon",
+			script: "DROP TABLE a;\nDROP TABLE b",
+			want:   []string{"DROP TABLE a", "DROP TABLE b"},
+		},
+		{
+			name:   "empty statements",
+			script: ";; DROP TABLE a;;\n;",
+			want:   []string{"DROP TABLE a"},
+		},
+		{
+			name:   "semicolons in strings and identifiers",
+			script: `INSERT INTO a (name) VALUES ('x;y'), ("z;"); ALTER TABLE ` + "`we;ird`" + ` ADD c INT;`,
+			want:   []string{`INSERT INTO a (name) VALUES ('x;y'), ("z;")`, "ALTER TABLE `we;ird` ADD c INT"},
+		},
+		{
+			name:   "escaped and doubled quotes",
+			script: `INSERT INTO a VALUES ('it\'s; fine'); INSERT INTO a VALUES ('it''s; fine');`,
+			want:   []string{`INSERT INTO a VALUES ('it\'s; fine')`, `INSERT INTO a VALUES ('it''s; fine')`},
+		},
+		{
+			name:   "line comments",
+			script: "-- Migration 001: a; b\nCREATE TABLE a (\n    id INT -- the id; unique\n);",
+			want:   []string{"CREATE TABLE a (\n    id INT \n)"},
+		},
+		{
+			name:   "block comments",
+			script: "/* setup; part 1 */ CREATE TABLE a (id INT);/* trailing */",
+			want:   []string{"CREATE TABLE a (id INT)"},
+		},
+		{
+			name:   "comment markers in strings",
+			script: "INSERT INTO a VALUES ('-- not a comment', '/* nor this */');",
+			want:   []string{"INSERT INTO a VALUES ('-- not a comment', '/* nor this */')"},
+		},
+		{
+			name:   "only comments",
+			script: "-- Rollback 002: nothing to do\n/* really */\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_add_notes.sql":      {Data: []byte("CREATE TABLE notes (id INT);")},
+		"002_add_notes.down.sql": {Data: []byte("DROP TABLE notes;")},
+		"001_init_schema.sql":    {Data: []byte("CREATE TABLE users (id INT);")},
+		"010_add_index.sql":      {Data: []byte("CREATE INDEX i ON notes (id);")},
+		"README.md":              {Data: []byte("not a migration")},
+		"embed.go":               {Data: []byte("package migrations")},
+	}
+	got, err := loadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("loadMigrations() failed: %v", err)
+	}
+	want := []Migration{
+		{Version: 1, Name: "init_schema", UpSQL: "CREATE TABLE users (id INT);", Checksum: checksum("CREATE TABLE users (id INT);")},
+		{Version: 2, Name: "add_notes", UpSQL: "CREATE TABLE notes (id INT);", DownSQL: "DROP TABLE notes;", Checksum: checksum("CREATE TABLE notes (id INT);")},
+		{Version: 10, Name: "add_index", UpSQL: "CREATE INDEX i ON notes (id);", Checksum: checksum("CREATE INDEX i ON notes (id);")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadMigrations() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadMigrationsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "down file without up file",
+			fsys: fstest.MapFS{"003_add_tags.down.sql": {Data: []byte("DROP TABLE tags;")}},
+		},
+		{
+			name: "version used twice",
+			fsys: fstest.MapFS{
+				"003_add_tags.sql":  {Data: []byte("CREATE TABLE tags (id INT);")},
+				"003_add_notes.sql": {Data: []byte("CREATE TABLE notes (id INT);")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := loadMigrations(tt.fsys); err == nil {
+				t.Errorf("loadMigrations() = %+v, want an error", got)
+			}
+		})
+	}
+}
+
+// The migrations shipped with the binary all load and split into statements
+func TestEmbeddedMigrations(t *testing.T) {
+	loaded, err := loadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("loadMigrations() failed: %v", err)
+	}
+	for i, m := range loaded {
+		if m.Version != i+1 {
+			t.Errorf("migration %03d_%s follows version %d", m.Version, m.Name, i)
+		}
+		if len(splitStatements(m.UpSQL)) == 0 {
+			t.Errorf("migration %03d_%s has no statements", m.Version, m.Name)
+		}
+		if strings.TrimSpace(m.DownSQL) == "" {
+			t.Errorf("migration %03d_%s has no down file", m.Version, m.Name)
+		}
+	}
+}
+
+func TestCheckChecksums(t *testing.T) {
+	loaded := []Migration{
+		{Version: 1, Name: "init_schema", Checksum: checksum("one")},
+		{Version: 2, Name: "add_notes", Checksum: checksum("two")},
+	}
+	tests := []struct {
+		name    string
+		applied map[int]appliedMigration
+		wantErr string
+	}{
+		{name: "nothing applied", applied: map[int]appliedMigration{}},
+		{
+			name:    "unchanged",
+			applied: map[int]appliedMigration{1: {Version: 1, Checksum: checksum("one")}, 2: {Version: 2, Checksum: checksum("two")}},
+		},
+		{
+			name:    "pending migrations aren't checked",
+			applied: map[int]appliedMigration{1: {Version: 1, Checksum: checksum("one")}},
+		},
+		{
+			name:    "file modified after it was applied",
+			applied: map[int]appliedMigration{1: {Version: 1, Checksum: checksum("one")}, 2: {Version: 2, Checksum: checksum("two, before")}},
+			wantErr: "checksum mismatch for applied migration 002_add_notes",
+		},
+		{
+			name:    "applied migration whose file is gone",
+			applied: map[int]appliedMigration{1: {Version: 1, Checksum: checksum("one")}, 3: {Version: 3, Checksum: checksum("three")}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkChecksums(loaded, tt.applied)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("checkChecksums() failed: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("checkChecksums() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,16 +2,21 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"habit-tracker-backend/internal/auth"
 	"habit-tracker-backend/internal/database"
@@ -22,7 +27,16 @@ import (
 )
 
 // AuthHandler handles authentication endpoints
-type AuthHandler struct{}
+type AuthHandler struct {
+	mailer services.Mailer
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler() *AuthHandler {
+	return &AuthHandler{
+		mailer: services.NewMailer(),
+	}
+}
 
 // Register handles user registration
 func (h *AuthHandler) Register(c *gin.Context) {
@@ -32,6 +46,17 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	settings := map[string]string{}
+	if req.Timezone != "" {
+		loc, err := services.LoadTimezone(req.Timezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		settings["timezone"] = loc.String()
+	}
+	settingsJSON, _ := json.Marshal(settings)
+
 	// Check if user already exists
 	var existingUser models.User
 	err := database.DB.QueryRow("SELECT id FROM users WHERE email = ?", req.Email).Scan(&existingUser.ID)
@@ -50,8 +75,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	// Create user
 	result, err := database.DB.Exec(`
 		INSERT INTO users (email, password_hash, name, preferences, settings) 
-		VALUES (?, ?, ?, '{}', '{}')
-	`, req.Email, hashedPassword, req.Name)
+		VALUES (?, ?, ?, '{}', ?)
+	`, req.Email, hashedPassword, req.Name, string(settingsJSON))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
@@ -59,9 +84,19 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	userID, _ := result.LastInsertId()
 
-	// Generate token
-	token, err := auth.GenerateToken(int(userID), req.Email)
+	if err := createDefaultCategories(int(userID)); err != nil {
+		log.Printf("Failed to create default habit categories for user %d: %v", userID, err)
+	}
+
+	// A failed verification email must not fail the registration, it can be resent
+	if err := h.sendVerificationEmail(int(userID), req.Email, req.Name); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", userID, err)
+	}
+
+	// Start session
+	tokens, err := auth.IssueSession(int(userID), req.Email, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
+		log.Printf("Failed to issue session for user %d: %v", userID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
@@ -70,13 +105,16 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		ID:        int(userID),
 		Email:     req.Email,
 		Name:      req.Name,
+		Settings:  string(settingsJSON),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
 	c.JSON(http.StatusCreated, models.AuthResponse{
-		Token: token,
-		User:  user,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         user,
 	})
 }
 
@@ -91,11 +129,12 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	// Find user - handle NULL values for JSON fields
 	var user models.User
 	var preferences, settings sql.NullString
+	var emailVerifiedAt sql.NullTime
 	err := database.DB.QueryRow(`
-		SELECT id, email, password_hash, name, created_at, updated_at, preferences, settings 
+		SELECT id, email, password_hash, name, email_verified_at, created_at, updated_at, preferences, settings 
 		FROM users WHERE email = ?
 	`, req.Email).Scan(
-		&user.ID, &user.Email, &user.PasswordHash, &user.Name,
+		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &emailVerifiedAt,
 		&user.CreatedAt, &user.UpdatedAt, &preferences, &settings,
 	)
 	if err == sql.ErrNoRows {
@@ -109,6 +148,10 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+
 	// Handle NULL JSON fields
 	if preferences.Valid {
 		user.Preferences = preferences.String
@@ -121,25 +164,87 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		user.Settings = "{}"
 	}
 
-	// Check password - TEMPORARILY DISABLED: All logins allowed for testing
-	// if !auth.CheckPasswordHash(req.Password, user.PasswordHash) {
-	// 	c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
-	// 	return
-	// }
+	// Check password. It used to be skipped for testing, letting anyone log in as any
+	// user; the login lockout counts these 401s, so it only protects accounts with it.
+	if !auth.CheckPasswordHash(req.Password, user.PasswordHash) {
+		log.Printf("Login attempt failed: wrong password for user %d", user.ID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
 
-	// Generate token
-	token, err := auth.GenerateToken(user.ID, user.Email)
+	// Start session
+	tokens, err := auth.IssueSession(user.ID, user.Email, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
+		log.Printf("Failed to issue session for user %d: %v", user.ID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, models.AuthResponse{
-		Token: token,
-		User:  user,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         user,
+	})
+}
+
+// Refresh exchanges a refresh token for a new access/refresh token pair
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := auth.RotateRefreshToken(req.RefreshToken, c.Request.UserAgent(), c.ClientIP())
+	if err == auth.ErrInvalidRefreshToken || err == auth.ErrRefreshTokenReused {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to rotate refresh token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TokenResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
 	})
 }
 
+// Logout revokes the session the given refresh token belongs to
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := auth.RevokeRefreshToken(req.RefreshToken)
+	if err != nil && err != auth.ErrInvalidRefreshToken {
+		log.Printf("Failed to revoke session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll revokes every session of the authenticated user
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if err := auth.RevokeAllSessions(userID.(int)); err != nil {
+		log.Printf("Failed to revoke sessions for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all sessions"})
+}
+
 // GetMe returns current user information
 func (h *AuthHandler) GetMe(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -150,11 +255,12 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 
 	var user models.User
 	var preferences, settings sql.NullString
+	var emailVerifiedAt sql.NullTime
 	err := database.DB.QueryRow(`
-		SELECT id, email, name, created_at, updated_at, preferences, settings 
+		SELECT id, email, name, email_verified_at, created_at, updated_at, preferences, settings 
 		FROM users WHERE id = ?
 	`, userID).Scan(
-		&user.ID, &user.Email, &user.Name,
+		&user.ID, &user.Email, &user.Name, &emailVerifiedAt,
 		&user.CreatedAt, &user.UpdatedAt, &preferences, &settings,
 	)
 	if err == sql.ErrNoRows {
@@ -167,6 +273,10 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 		return
 	}
 
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+
 	// Handle NULL JSON fields
 	if preferences.Valid {
 		user.Preferences = preferences.String
@@ -182,22 +292,297 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+// UpdateSettings changes the authenticated user's settings and returns all of them
+func (h *AuthHandler) UpdateSettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// JSON_SET paths and their values; booleans go through CAST so they aren't stored as strings
+	paths := []string{}
+	args := []interface{}{}
+
+	if req.Timezone != nil {
+		loc, err := services.LoadTimezone(*req.Timezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		paths = append(paths, "'$.timezone', ?")
+		args = append(args, loc.String())
+	}
+	if req.JournalReminderTime != nil {
+		if *req.JournalReminderTime == "" {
+			paths = append(paths, "'$.journal_reminder_time', NULL")
+		} else {
+			clock, err := services.ParseTimeOfDay(*req.JournalReminderTime)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			paths = append(paths, "'$.journal_reminder_time', ?")
+			args = append(args, clock)
+		}
+	}
+	if req.EmailNotifications != nil {
+		paths = append(paths, "'$.email_notifications', CAST(? AS JSON)")
+		args = append(args, strconv.FormatBool(*req.EmailNotifications))
+	}
+	if req.PushNotifications != nil {
+		paths = append(paths, "'$.push_notifications', CAST(? AS JSON)")
+		args = append(args, strconv.FormatBool(*req.PushNotifications))
+	}
+	if req.WeeklyJournalReview != nil {
+		paths = append(paths, "'$.weekly_journal_review', CAST(? AS JSON)")
+		args = append(args, strconv.FormatBool(*req.WeeklyJournalReview))
+	}
+	if req.MonthlyJournalReview != nil {
+		paths = append(paths, "'$.monthly_journal_review', CAST(? AS JSON)")
+		args = append(args, strconv.FormatBool(*req.MonthlyJournalReview))
+	}
+
+	if len(paths) > 0 {
+		args = append(args, userID)
+		query := fmt.Sprintf("UPDATE users SET settings = JSON_SET(COALESCE(settings, JSON_OBJECT()), %s) WHERE id = ?", strings.Join(paths, ", "))
+		if _, err := database.DB.Exec(query, args...); err != nil {
+			log.Printf("Failed to update settings of user %v: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+			return
+		}
+	}
+
+	var settings sql.NullString
+	if err := database.DB.QueryRow("SELECT settings FROM users WHERE id = ?", userID).Scan(&settings); err != nil {
+		log.Printf("Failed to fetch settings of user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+		return
+	}
+	if !settings.Valid {
+		settings.String = "{}"
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(settings.String))
+}
+
+// ForgotPassword emails a password reset link.
+// The response is the same whether or not the email is registered.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"message": "If an account exists for this email, a reset link has been sent"}
+
+	var userID int
+	var name string
+	err := database.DB.QueryRow("SELECT id, name FROM users WHERE email = ?", req.Email).Scan(&userID, &name)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	if err != nil {
+		log.Printf("Database error in ForgotPassword: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// Failures past this point answer like success too, so the response never tells
+	// whether an account exists for the email
+	token, err := auth.CreateActionToken(userID, auth.PurposePasswordReset, auth.PasswordResetTTL)
+	if err != nil {
+		log.Printf("Failed to create password reset token for user %d: %v", userID, err)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	err = h.mailer.Send(services.Email{
+		To:      req.Email,
+		Subject: "Passwort zurücksetzen",
+		Body: fmt.Sprintf("Hallo %s,\n\nüber diesen Link kannst du ein neues Passwort festlegen:\n\n%s\n\n"+
+			"Der Link ist %d Minuten gültig und kann nur einmal verwendet werden. "+
+			"Falls du das nicht angefordert hast, kannst du diese E-Mail ignorieren.\n",
+			name, appURL("/reset-password?token="+token), int(auth.PasswordResetTTL.Minutes())),
+	})
+	if err != nil {
+		log.Printf("Failed to send password reset email to user %d: %v", userID, err)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ResetPassword sets a new password using a reset token and logs out all sessions
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	userID, err := auth.ConsumeActionToken(req.Token, auth.PurposePasswordReset)
+	if err == auth.ErrInvalidActionToken {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to consume password reset token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	// Receiving the reset email proves ownership of the address as well
+	_, err = database.DB.Exec(`
+		UPDATE users SET password_hash = ?, email_verified_at = COALESCE(email_verified_at, NOW())
+		WHERE id = ?
+	`, hashedPassword, userID)
+	if err != nil {
+		log.Printf("Failed to update password for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	if err := auth.RevokeAllSessions(userID); err != nil {
+		log.Printf("Failed to revoke sessions after password reset for user %d: %v", userID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
+// VerifyEmail marks the user's email address as verified using a verification token
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := auth.ConsumeActionToken(req.Token, auth.PurposeEmailVerification)
+	if err == auth.ErrInvalidActionToken {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to consume email verification token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = ?
+	`, userID)
+	if err != nil {
+		log.Printf("Failed to mark email verified for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerification sends a new verification email to the authenticated user
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var email, name string
+	var emailVerifiedAt sql.NullTime
+	err := database.DB.QueryRow(`
+		SELECT email, name, email_verified_at FROM users WHERE id = ?
+	`, userID).Scan(&email, &name, &emailVerifiedAt)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Database error in ResendVerification for user_id %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if emailVerifiedAt.Valid {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	if err := h.sendVerificationEmail(userID.(int), email, name); err != nil {
+		log.Printf("Failed to send verification email to user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// sendVerificationEmail issues a verification token and emails the link to the user
+func (h *AuthHandler) sendVerificationEmail(userID int, email, name string) error {
+	token, err := auth.CreateActionToken(userID, auth.PurposeEmailVerification, auth.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return h.mailer.Send(services.Email{
+		To:      email,
+		Subject: "Bitte bestätige deine E-Mail-Adresse",
+		Body: fmt.Sprintf("Hallo %s,\n\nbitte bestätige deine E-Mail-Adresse über diesen Link:\n\n%s\n\n"+
+			"Der Link ist %d Stunden gültig.\n",
+			name, appURL("/verify-email?token="+token), int(auth.EmailVerificationTTL.Hours())),
+	})
+}
+
+// appURL builds a link into the frontend configured by APP_URL
+func appURL(path string) string {
+	base := os.Getenv("APP_URL")
+	if base == "" {
+		base = "http://localhost:5173"
+	}
+	return strings.TrimRight(base, "/") + path
+}
+
 // HabitHandler handles habit endpoints
-type HabitHandler struct{}
+type HabitHandler struct {
+	backfillDays    int // how many days back completions may be changed
+	freezesPerMonth int // streak freeze tokens every user gets per calendar month
+}
+
+// NewHabitHandler creates a new habit handler.
+// HABIT_BACKFILL_DAYS sets how far back completions can be changed (default 7),
+// see services.StreakFreezesPerMonth for the streak freezes.
+func NewHabitHandler() *HabitHandler {
+	backfillDays := 7
+	if days, err := strconv.Atoi(os.Getenv("HABIT_BACKFILL_DAYS")); err == nil && days >= 0 {
+		backfillDays = days
+	}
+	return &HabitHandler{backfillDays: backfillDays, freezesPerMonth: services.StreakFreezesPerMonth()}
+}
 
-// GetHabits returns all habits for the authenticated user
+// GetHabits returns all habits for the authenticated user.
+// Archived habits are only returned with ?archived=true.
 func (h *HabitHandler) GetHabits(c *gin.Context) {
 	userID, _ := c.Get("user_id")
+	active := c.Query("archived") != "true"
+	loc := services.UserLocation(userID.(int))
+	today := services.Today(loc)
 
 	rows, err := database.DB.Query(`
-		SELECT h.id, h.user_id, h.name, h.description, h.category, h.icon, h.color, 
-		       h.target_frequency, h.is_active, h.created_at, h.updated_at,
+		SELECT h.id, h.user_id, h.name, h.description, h.category, h.icon, h.color, h.target_frequency,
+		       h.schedule_type, h.schedule_days, h.schedule_count, h.schedule_interval,
+		       h.unit, h.target_value, h.reminder_time, h.is_active, h.archived_at, h.created_at, h.updated_at,
 		       CASE WHEN hc.id IS NOT NULL THEN true ELSE false END as completed_today
 		FROM habits h
-		LEFT JOIN habit_completions hc ON h.id = hc.habit_id AND DATE(hc.completed_at) = CURDATE()
-		WHERE h.user_id = ? AND h.is_active = true
-		ORDER BY h.category, h.created_at
-	`, userID)
+		LEFT JOIN habit_completions hc ON h.id = hc.habit_id AND hc.completed_date = ?
+		LEFT JOIN habit_categories cat ON cat.user_id = h.user_id AND cat.name = h.category
+		WHERE h.user_id = ? AND h.is_active = ?
+		ORDER BY COALESCE(cat.position, 2147483647), h.category, h.created_at
+	`, today.Format(services.DateLayout), userID, active)
 	if err != nil {
 		log.Printf("Failed to query habits for user %v: %v", userID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch habits", "details": err.Error()})
@@ -207,16 +592,27 @@ func (h *HabitHandler) GetHabits(c *gin.Context) {
 
 	type HabitWithCompletion struct {
 		models.Habit
-		CompletedToday bool `json:"completed_today"`
+		CompletedToday bool     `json:"completed_today"`
+		DueToday       bool     `json:"due_today"`
+		TodayValue     *float64 `json:"today_value,omitempty"` // logged total of measurable habits
+		Progress       *float64 `json:"progress,omitempty"`    // today_value / target_value, at most 1
 	}
 
+	periodsStart := today
+
 	var habits []HabitWithCompletion
 	for rows.Next() {
 		var habit HabitWithCompletion
+		var scheduleType string
+		var scheduleDays sql.NullString
+		var scheduleCount, scheduleInterval sql.NullInt64
+		var archivedAt sql.NullTime
+		var reminderTime sql.NullString
 		err := rows.Scan(
 			&habit.ID, &habit.UserID, &habit.Name, &habit.Description,
 			&habit.Category, &habit.Icon, &habit.Color, &habit.TargetFrequency,
-			&habit.IsActive, &habit.CreatedAt, &habit.UpdatedAt,
+			&scheduleType, &scheduleDays, &scheduleCount, &scheduleInterval,
+			&habit.Unit, &habit.TargetValue, &reminderTime, &habit.IsActive, &archivedAt, &habit.CreatedAt, &habit.UpdatedAt,
 			&habit.CompletedToday,
 		)
 		if err != nil {
@@ -224,9 +620,42 @@ func (h *HabitHandler) GetHabits(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan habit", "details": err.Error()})
 			return
 		}
+		habit.Schedule = services.ScheduleFromColumns(scheduleType, scheduleDays, scheduleCount, scheduleInterval)
+		habit.ReminderTime = reminderClock(reminderTime)
+		if archivedAt.Valid {
+			habit.ArchivedAt = &archivedAt.Time
+		}
+		if start := services.PeriodStart(habit.Schedule, services.StartOfDay(habit.CreatedAt, loc), today); start.Before(periodsStart) {
+			periodsStart = start
+		}
 		habits = append(habits, habit)
 	}
 
+	// Quota and interval habits are due until their current period's target is met
+	completed, err := services.CompletionsByHabit(userID.(int), periodsStart)
+	if err != nil {
+		log.Printf("Failed to query completions for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch habits", "details": err.Error()})
+		return
+	}
+	logged, err := loggedValuesByHabit(userID.(int), today, today)
+	if err != nil {
+		log.Printf("Failed to query logged values for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch habits", "details": err.Error()})
+		return
+	}
+
+	for i := range habits {
+		habits[i].DueToday = habits[i].IsActive &&
+			services.IsDue(habits[i].Schedule, services.StartOfDay(habits[i].CreatedAt, loc), today, completed[habits[i].ID])
+		if target := habits[i].TargetValue; target != nil {
+			value := logged[habits[i].ID][today.Format(services.DateLayout)]
+			progress := math.Min(value / *target, 1)
+			habits[i].TodayValue = &value
+			habits[i].Progress = &progress
+		}
+	}
+
 	c.JSON(http.StatusOK, habits)
 }
 
@@ -239,11 +668,41 @@ func (h *HabitHandler) CreateHabit(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	msg, err := checkCategory(userID.(int), req.Category)
+	if err != nil {
+		log.Printf("Failed to check category for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check category"})
+		return
+	}
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	schedule := models.HabitSchedule{Type: services.ScheduleDaily}
+	if req.Schedule != nil {
+		schedule = *req.Schedule
+	}
+	if err := services.NormalizeSchedule(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	days, count, interval := scheduleColumns(schedule)
+	if req.ReminderTime != nil {
+		clock, err := services.ParseTimeOfDay(*req.ReminderTime)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.ReminderTime = &clock
+	}
 
 	result, err := database.DB.Exec(`
-		INSERT INTO habits (user_id, name, description, category, icon, color, target_frequency)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, userID, req.Name, req.Description, req.Category, req.Icon, req.Color, req.TargetFrequency)
+		INSERT INTO habits (user_id, name, description, category, icon, color, target_frequency,
+		                    schedule_type, schedule_days, schedule_count, schedule_interval, unit, target_value, reminder_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, userID, req.Name, req.Description, req.Category, req.Icon, req.Color, req.TargetFrequency,
+		schedule.Type, days, count, interval, req.Unit, req.TargetValue, req.ReminderTime)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create habit"})
 		return
@@ -260,6 +719,10 @@ func (h *HabitHandler) CreateHabit(c *gin.Context) {
 		Icon:            req.Icon,
 		Color:           req.Color,
 		TargetFrequency: req.TargetFrequency,
+		Schedule:        schedule,
+		Unit:            req.Unit,
+		TargetValue:     req.TargetValue,
+		ReminderTime:    req.ReminderTime,
 		IsActive:        true,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
@@ -284,27 +747,42 @@ func (h *HabitHandler) CompleteHabit(c *gin.Context) {
 	// Check if habit belongs to user
 	var habit models.Habit
 	err = database.DB.QueryRow(`
-		SELECT id, user_id FROM habits WHERE id = ? AND user_id = ?
-	`, habitID, userID).Scan(&habit.ID, &habit.UserID)
-	if err != nil {
-		log.Printf("Habit not found or doesn't belong to user: habitID=%d, userID=%v, error: %v", habitID, userID, err)
+		SELECT id, user_id, is_active, target_value FROM habits WHERE id = ? AND user_id = ?
+	`, habitID, userID).Scan(&habit.ID, &habit.UserID, &habit.IsActive, &habit.TargetValue)
+	if err == sql.ErrNoRows {
+		log.Printf("Habit not found or doesn't belong to user: habitID=%d, userID=%v", habitID, userID)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Habit not found"})
 		return
 	}
+	if err != nil {
+		log.Printf("Failed to fetch habit %d: %v", habitID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch habit"})
+		return
+	}
+	if !habit.IsActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Habit is archived"})
+		return
+	}
+	if habit.TargetValue != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Measurable habits are completed by logging values"})
+		return
+	}
+
+	today := services.Today(services.UserLocation(userID.(int))).Format(services.DateLayout)
 
 	// Check if already completed today - if so, toggle it off (delete)
 	var existingCompletion models.HabitCompletion
 	err = database.DB.QueryRow(`
 		SELECT id FROM habit_completions 
-		WHERE habit_id = ? AND DATE(completed_at) = CURDATE()
-	`, habitID).Scan(&existingCompletion.ID)
+		WHERE habit_id = ? AND completed_date = ?
+	`, habitID, today).Scan(&existingCompletion.ID)
 	if err == nil {
 		// Already completed - toggle off by deleting the completion
 		log.Printf("Habit already completed today, deleting completion: habitID=%d", habitID)
 		_, err = database.DB.Exec(`
 			DELETE FROM habit_completions 
-			WHERE habit_id = ? AND DATE(completed_at) = CURDATE()
-		`, habitID)
+			WHERE habit_id = ? AND completed_date = ?
+		`, habitID, today)
 		if err != nil {
 			log.Printf("Failed to delete habit completion: habitID=%d, error: %v", habitID, err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to uncomplete habit", "details": err.Error()})
@@ -323,9 +801,9 @@ func (h *HabitHandler) CompleteHabit(c *gin.Context) {
 	// Not completed - insert completion
 	log.Printf("Inserting new habit completion: habitID=%d, userID=%v", habitID, userID)
 	_, err = database.DB.Exec(`
-		INSERT INTO habit_completions (habit_id, user_id, completed_at, streak_count)
-		VALUES (?, ?, NOW(), 1)
-	`, habitID, userID)
+		INSERT INTO habit_completions (habit_id, user_id, completed_at, completed_date, streak_count)
+		VALUES (?, ?, NOW(), ?, 1)
+	`, habitID, userID, today)
 	if err != nil {
 		log.Printf("Failed to insert habit completion: habitID=%d, userID=%v, error: %v", habitID, userID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete habit", "details": err.Error()})
@@ -336,873 +814,5328 @@ func (h *HabitHandler) CompleteHabit(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Habit completed successfully", "completed": true})
 }
 
-// GetHabitCompletions returns habit completions for a specific week
-func (h *HabitHandler) GetHabitCompletions(c *gin.Context) {
+// UpdateHabit updates an existing habit
+func (h *HabitHandler) UpdateHabit(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	habitIDStr := c.Query("habit_id")
-	
-	// Parse start date from query (defaults to start of current week)
-	startDateStr := c.DefaultQuery("start_date", "")
-	var startDate time.Time
-	var err error
-	
-	if startDateStr != "" {
-		startDate, err = time.Parse("2006-01-02", startDateStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format. Use YYYY-MM-DD"})
-			return
-		}
-	} else {
-		// Get start of current week (Monday)
-		now := time.Now()
-		weekday := int(now.Weekday())
-		if weekday == 0 {
-			weekday = 7 // Sunday is day 7
-		}
-		startDate = now.AddDate(0, 0, -(weekday - 1)).Truncate(24 * time.Hour)
+	habitID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid habit ID"})
+		return
 	}
-	
-	endDate := startDate.AddDate(0, 0, 6) // End of week (Sunday)
-	
-	var rows *sql.Rows
-	if habitIDStr != "" {
-		habitID, err := strconv.Atoi(habitIDStr)
+
+	var req models.UpdateHabitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if habit belongs to user
+	var habit models.Habit
+	err = database.DB.QueryRow(`
+		SELECT id, user_id, is_active FROM habits WHERE id = ? AND user_id = ?
+	`, habitID, userID).Scan(&habit.ID, &habit.UserID, &habit.IsActive)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Habit not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch habit %d: %v", habitID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch habit"})
+		return
+	}
+
+	// Build update query dynamically
+	updateFields := []string{}
+	args := []interface{}{}
+
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+			return
+		}
+		updateFields = append(updateFields, "name = ?")
+		args = append(args, *req.Name)
+	}
+	if req.Description != nil {
+		updateFields = append(updateFields, "description = ?")
+		args = append(args, *req.Description)
+	}
+	if req.Category != nil {
+		msg, err := checkCategory(userID.(int), *req.Category)
+		if err != nil {
+			log.Printf("Failed to check category for user %v: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check category"})
+			return
+		}
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		updateFields = append(updateFields, "category = ?")
+		args = append(args, *req.Category)
+	}
+	if req.Icon != nil {
+		updateFields = append(updateFields, "icon = ?")
+		args = append(args, *req.Icon)
+	}
+	if req.Color != nil {
+		updateFields = append(updateFields, "color = ?")
+		args = append(args, *req.Color)
+	}
+	if req.TargetFrequency != nil {
+		updateFields = append(updateFields, "target_frequency = ?")
+		args = append(args, *req.TargetFrequency)
+	}
+	if req.Schedule != nil {
+		if err := services.NormalizeSchedule(req.Schedule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		days, count, interval := scheduleColumns(*req.Schedule)
+		updateFields = append(updateFields, "schedule_type = ?", "schedule_days = ?", "schedule_count = ?", "schedule_interval = ?")
+		args = append(args, req.Schedule.Type, days, count, interval)
+	}
+	if req.Unit != nil {
+		updateFields = append(updateFields, "unit = ?")
+		args = append(args, *req.Unit)
+	}
+	if req.TargetValue != nil {
+		// Existing completions are kept, a new target only applies from now on
+		updateFields = append(updateFields, "target_value = ?")
+		if *req.TargetValue == 0 {
+			args = append(args, nil)
+		} else {
+			args = append(args, *req.TargetValue)
+		}
+	}
+	if req.ReminderTime != nil {
+		updateFields = append(updateFields, "reminder_time = ?")
+		if *req.ReminderTime == "" {
+			args = append(args, nil)
+		} else {
+			clock, err := services.ParseTimeOfDay(*req.ReminderTime)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			args = append(args, clock)
+		}
+	}
+
+	if len(updateFields) > 0 {
+		args = append(args, habitID)
+		query := fmt.Sprintf("UPDATE habits SET %s WHERE id = ?", strings.Join(updateFields, ", "))
+		if _, err := database.DB.Exec(query, args...); err != nil {
+			log.Printf("Failed to update habit %d: %v", habitID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update habit"})
+			return
+		}
+	}
+
+	// is_active goes through archiving so the archived period is recorded
+	if req.IsActive != nil && *req.IsActive != habit.IsActive {
+		today := services.Today(services.UserLocation(userID.(int)))
+		if err := setHabitArchived(habitID, userID.(int), !*req.IsActive, today); err != nil {
+			log.Printf("Failed to change archive state of habit %d: %v", habitID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update habit"})
+			return
+		}
+	}
+
+	updated, err := fetchHabit(habitID)
+	if err != nil {
+		log.Printf("Failed to fetch updated habit %d: %v", habitID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated habit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteHabit permanently deletes a habit together with its completions
+func (h *HabitHandler) DeleteHabit(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	habitID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid habit ID"})
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM habits WHERE id = ? AND user_id = ?", habitID, userID)
+	if err != nil {
+		log.Printf("Failed to delete habit %d: %v", habitID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete habit"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Habit not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Habit deleted successfully"})
+}
+
+// ArchiveHabit hides a habit and stops counting it towards streaks from today on
+func (h *HabitHandler) ArchiveHabit(c *gin.Context) {
+	h.changeArchiveState(c, true)
+}
+
+// UnarchiveHabit restores an archived habit
+func (h *HabitHandler) UnarchiveHabit(c *gin.Context) {
+	h.changeArchiveState(c, false)
+}
+
+func (h *HabitHandler) changeArchiveState(c *gin.Context, archive bool) {
+	userID, _ := c.Get("user_id")
+	habitID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid habit ID"})
+		return
+	}
+
+	// Check if habit belongs to user
+	var habit models.Habit
+	err = database.DB.QueryRow(`
+		SELECT id, user_id FROM habits WHERE id = ? AND user_id = ?
+	`, habitID, userID).Scan(&habit.ID, &habit.UserID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Habit not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch habit %d: %v", habitID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch habit"})
+		return
+	}
+
+	today := services.Today(services.UserLocation(userID.(int)))
+	if err := setHabitArchived(habitID, userID.(int), archive, today); err != nil {
+		log.Printf("Failed to change archive state of habit %d: %v", habitID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update habit"})
+		return
+	}
+
+	updated, err := fetchHabit(habitID)
+	if err != nil {
+		log.Printf("Failed to fetch updated habit %d: %v", habitID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated habit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// setHabitArchived archives or restores a habit and records the archived period,
+// starting or ending on the user's current day. Changing to the current state is a no-op.
+func setHabitArchived(habitID, userID int, archive bool, today time.Time) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if archive {
+		result, err := tx.Exec(`
+			UPDATE habits SET is_active = false, archived_at = NOW() WHERE id = ? AND is_active = true
+		`, habitID)
+		if err != nil {
+			return fmt.Errorf("failed to archive habit: %v", err)
+		}
+		if affected, _ := result.RowsAffected(); affected > 0 {
+			_, err = tx.Exec(`
+				INSERT INTO habit_archive_periods (habit_id, user_id, archived_on) VALUES (?, ?, ?)
+			`, habitID, userID, today.Format(services.DateLayout))
+			if err != nil {
+				return fmt.Errorf("failed to record archive period: %v", err)
+			}
+		}
+	} else {
+		result, err := tx.Exec(`
+			UPDATE habits SET is_active = true, archived_at = NULL WHERE id = ? AND is_active = false
+		`, habitID)
+		if err != nil {
+			return fmt.Errorf("failed to unarchive habit: %v", err)
+		}
+		if affected, _ := result.RowsAffected(); affected > 0 {
+			_, err = tx.Exec(`
+				UPDATE habit_archive_periods SET restored_on = ? WHERE habit_id = ? AND restored_on IS NULL
+			`, today.Format(services.DateLayout), habitID)
+			if err != nil {
+				return fmt.Errorf("failed to close archive period: %v", err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// habitColumns are the columns scanHabit expects, in order
+const habitColumns = `id, user_id, name, description, category, icon, color, target_frequency,
+		       schedule_type, schedule_days, schedule_count, schedule_interval,
+		       unit, target_value, reminder_time, is_active, archived_at, created_at, updated_at`
+
+// categoryOrder sorts habits selected from the habits table by the position of their category
+const categoryOrder = `COALESCE((SELECT position FROM habit_categories cat
+		WHERE cat.user_id = habits.user_id AND cat.name = habits.category), 2147483647), category`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanHabit reads a habit selected with habitColumns
+func scanHabit(row rowScanner) (models.Habit, error) {
+	var habit models.Habit
+	var description, icon, color, scheduleDays, unit, reminderTime sql.NullString
+	var scheduleType string
+	var scheduleCount, scheduleInterval sql.NullInt64
+	var targetValue sql.NullFloat64
+	var archivedAt sql.NullTime
+	err := row.Scan(
+		&habit.ID, &habit.UserID, &habit.Name, &description,
+		&habit.Category, &icon, &color, &habit.TargetFrequency,
+		&scheduleType, &scheduleDays, &scheduleCount, &scheduleInterval,
+		&unit, &targetValue, &reminderTime, &habit.IsActive, &archivedAt, &habit.CreatedAt, &habit.UpdatedAt,
+	)
+	if err != nil {
+		return habit, err
+	}
+	habit.Schedule = services.ScheduleFromColumns(scheduleType, scheduleDays, scheduleCount, scheduleInterval)
+	if unit.Valid {
+		habit.Unit = &unit.String
+	}
+	if targetValue.Valid {
+		habit.TargetValue = &targetValue.Float64
+	}
+	habit.ReminderTime = reminderClock(reminderTime)
+	habit.Description = description.String
+	habit.Icon = icon.String
+	habit.Color = color.String
+	if archivedAt.Valid {
+		habit.ArchivedAt = &archivedAt.Time
+	}
+	return habit, nil
+}
+
+// reminderClock formats a reminder_time column as "HH:MM"
+func reminderClock(column sql.NullString) *string {
+	if !column.Valid {
+		return nil
+	}
+	clock, err := services.ParseTimeOfDay(column.String)
+	if err != nil {
+		return nil
+	}
+	return &clock
+}
+
+// fetchHabit loads a single habit by ID
+func fetchHabit(habitID int) (models.Habit, error) {
+	return scanHabit(database.DB.QueryRow("SELECT "+habitColumns+" FROM habits WHERE id = ?", habitID))
+}
+
+// fetchUserHabits loads the user's habits, archived ones only when activeOnly is false
+func fetchUserHabits(userID int, activeOnly bool) ([]models.Habit, error) {
+	query := "SELECT " + habitColumns + " FROM habits WHERE user_id = ?"
+	if activeOnly {
+		query += " AND is_active = true"
+	}
+	rows, err := database.DB.Query(query+" ORDER BY "+categoryOrder+", created_at", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var habits []models.Habit
+	for rows.Next() {
+		habit, err := scanHabit(rows)
+		if err != nil {
+			return nil, err
+		}
+		habits = append(habits, habit)
+	}
+	return habits, rows.Err()
+}
+
+// maxCompletionRangeDays caps the range GetHabitCompletions reports on
+const maxCompletionRangeDays = 92
+
+// GetHabitCompletions returns the per-habit, per-day completion status for a date range
+// (start_date to end_date, defaulting to the current week) together with the days on
+// which all due habits were done and the streaks. With habit_id only that habit is reported.
+func (h *HabitHandler) GetHabitCompletions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	habitIDStr := c.Query("habit_id")
+	loc := services.UserLocation(userID.(int))
+	today := services.Today(loc)
+
+	// Rest and frozen days are loaded once for the streaks, the statuses and the response
+	excused, err := services.LoadExcusedDays(userID.(int))
+	if err != nil {
+		log.Printf("Failed to load rest days for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch completions"})
+		return
+	}
+
+	// Parse start date from query (defaults to start of current week)
+	var startDate time.Time
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		startDate, err = time.ParseInLocation("2006-01-02", startDateStr, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format. Use YYYY-MM-DD"})
+			return
+		}
+	} else {
+		// Get start of current week (Monday)
+		weekday := int(today.Weekday())
+		if weekday == 0 {
+			weekday = 7 // Sunday is day 7
+		}
+		startDate = today.AddDate(0, 0, -(weekday - 1))
+	}
+
+	endDate := startDate.AddDate(0, 0, 6) // End of week (Sunday)
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		endDate, err = time.ParseInLocation("2006-01-02", endDateStr, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format. Use YYYY-MM-DD"})
+			return
+		}
+	}
+	if endDate.Before(startDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+	if endDate.After(startDate.AddDate(0, 0, maxCompletionRangeDays-1)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Date range is limited to %d days", maxCompletionRangeDays)})
+		return
+	}
+
+	type CompletionData struct {
+		HabitID        *int   `json:"habit_id,omitempty"`
+		CompletionDate string `json:"completion_date"`
+	}
+
+	var habits []models.Habit
+	var currentStreak, bestStreak int
+	var completionRate *float64
+	var completions []CompletionData
+
+	if habitIDStr != "" {
+		habitID, err := strconv.Atoi(habitIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid habit_id"})
+			return
+		}
+		habit, err := fetchHabit(habitID)
+		if err == sql.ErrNoRows || (err == nil && habit.UserID != userID.(int)) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Habit not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to fetch habit %d: %v", habitID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch habit"})
+			return
+		}
+		habits = []models.Habit{habit}
+
+		periods, err := habitPeriods(habit, today, excused)
+		if err != nil {
+			log.Printf("Failed to evaluate schedule of habit %d: %v", habitID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate streaks"})
+			return
+		}
+		currentStreak, bestStreak = services.Streaks(periods, today)
+		rate := services.CompletionRate(periods, today, today.AddDate(0, 0, -30))
+		completionRate = &rate
+	} else {
+		habits, err = fetchUserHabits(userID.(int), true)
+		if err != nil {
+			log.Printf("Failed to fetch habits for user %v: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch completions"})
+			return
+		}
+
+		// Across all habits, streaks are based on days where ALL habits due that day were completed
+		days, err := allHabitsDays(userID.(int), today, excused)
+		if err != nil {
+			log.Printf("Failed to query habit completions: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch completions"})
+			return
+		}
+		for _, day := range days {
+			if day.Met() && !day.Start.Before(startDate) && !day.Start.After(endDate) {
+				completions = append(completions, CompletionData{CompletionDate: day.Start.Format("2006-01-02")})
+			}
+		}
+		currentStreak, bestStreak = services.Streaks(days, today)
+	}
+
+	statuses, err := habitDayStatuses(userID.(int), habits, startDate, endDate, loc, excused)
+	if err != nil {
+		log.Printf("Failed to build completion status for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch completions"})
+		return
+	}
+
+	// For a single habit every completed day is listed
+	if habitIDStr != "" && len(statuses) == 1 {
+		for _, day := range statuses[0].Days {
+			if day.Completed {
+				completions = append(completions, CompletionData{HabitID: &statuses[0].HabitID, CompletionDate: day.Date})
+			}
+		}
+	}
+
+	// Rest and frozen days don't break streaks, show them alongside the completions
+	restDays, frozenDays := []string{}, []string{}
+	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+		dayStr := day.Format(services.DateLayout)
+		if excused.Rest[dayStr] {
+			restDays = append(restDays, dayStr)
+		}
+		if excused.Frozen[dayStr] {
+			frozenDays = append(frozenDays, dayStr)
+		}
+	}
+
+	response := gin.H{
+		"completions": completions,
+		"habits": statuses,
+		"start_date": startDate.Format("2006-01-02"),
+		"end_date": endDate.Format("2006-01-02"),
+		"current_streak": currentStreak,
+		"best_streak": bestStreak,
+		"rest_days": restDays,
+		"frozen_days": frozenDays,
+	}
+	if completionRate != nil {
+		response["completion_rate"] = *completionRate
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetHabitStats returns completion rates, streaks, the longest gap, success per weekday
+// and a year of heatmap data for one habit
+func (h *HabitHandler) GetHabitStats(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	habitID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid habit ID"})
+		return
+	}
+
+	habit, err := fetchHabit(habitID)
+	if err == sql.ErrNoRows || (err == nil && habit.UserID != userID.(int)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Habit not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch habit %d: %v", habitID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch habit"})
+		return
+	}
+
+	today := services.Today(services.UserLocation(userID.(int)))
+	histories, err := loadHabitHistories(userID.(int), []models.Habit{habit}, today.Location())
+	if err != nil {
+		log.Printf("Failed to load history of habit %d: %v", habitID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, struct {
+		HabitID int `json:"habit_id"`
+		services.HabitStats
+	}{habitID, services.ComputeHabitStats(histories[0], today)})
+}
+
+// GetAllHabitStats returns the statistics of all the user's habits together. Archived
+// habits count for the time they were active; streaks count days on which all
+// scheduled habits were done.
+func (h *HabitHandler) GetAllHabitStats(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	today := services.Today(services.UserLocation(userID.(int)))
+	histories, err := loadHabitHistories(userID.(int), nil, today.Location())
+	if err != nil {
+		log.Printf("Failed to load habit history of user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, services.ComputeOverallStats(histories, today))
+}
+
+// maxRestPeriodDays caps the length of a single rest period
+const maxRestPeriodDays = 366
+
+// GetRestPeriods returns the user's rest periods, latest first
+func (h *HabitHandler) GetRestPeriods(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	rows, err := database.DB.Query(`
+		SELECT id, user_id, start_date, end_date, reason, created_at
+		FROM rest_periods WHERE user_id = ?
+		ORDER BY start_date DESC
+	`, userID)
+	if err != nil {
+		log.Printf("Failed to query rest periods for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rest periods"})
+		return
+	}
+	defer rows.Close()
+
+	periods := []models.RestPeriod{}
+	for rows.Next() {
+		var period models.RestPeriod
+		var start, end time.Time
+		var reason sql.NullString
+		if err := rows.Scan(&period.ID, &period.UserID, &start, &end, &reason, &period.CreatedAt); err != nil {
+			log.Printf("Failed to scan rest period: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rest periods"})
+			return
+		}
+		period.StartDate = start.Format(services.DateLayout)
+		period.EndDate = end.Format(services.DateLayout)
+		period.Reason = reason.String
+		periods = append(periods, period)
+	}
+
+	c.JSON(http.StatusOK, periods)
+}
+
+// CreateRestPeriod declares a range of rest or vacation days, in the past or the future
+func (h *HabitHandler) CreateRestPeriod(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.CreateRestPeriodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	start, err := time.Parse(services.DateLayout, req.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format. Use YYYY-MM-DD"})
+		return
+	}
+	end, err := time.Parse(services.DateLayout, req.EndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format. Use YYYY-MM-DD"})
+		return
+	}
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+	if end.After(start.AddDate(0, 0, maxRestPeriodDays-1)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A rest period can be at most %d days long", maxRestPeriodDays)})
+		return
+	}
+
+	var reason interface{}
+	if req.Reason != "" {
+		reason = req.Reason
+	}
+	result, err := database.DB.Exec(`
+		INSERT INTO rest_periods (user_id, start_date, end_date, reason) VALUES (?, ?, ?, ?)
+	`, userID, req.StartDate, req.EndDate, reason)
+	if err != nil {
+		log.Printf("Failed to create rest period for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rest period"})
+		return
+	}
+
+	periodID, _ := result.LastInsertId()
+	c.JSON(http.StatusCreated, models.RestPeriod{
+		ID:        int(periodID),
+		UserID:    userID.(int),
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Reason:    req.Reason,
+		CreatedAt: time.Now(),
+	})
+}
+
+// DeleteRestPeriod removes a rest period; its days count against streaks again
+func (h *HabitHandler) DeleteRestPeriod(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	periodID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rest period ID"})
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM rest_periods WHERE id = ? AND user_id = ?", periodID, userID)
+	if err != nil {
+		log.Printf("Failed to delete rest period %d: %v", periodID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rest period"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rest period not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rest period deleted successfully"})
+}
+
+// GetStreakFreezes returns how many streak freezes the user has left this month
+// and the days covered by freezes during the last year
+func (h *HabitHandler) GetStreakFreezes(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	today := services.Today(services.UserLocation(userID.(int)))
+
+	excused, err := services.LoadExcusedDays(userID.(int))
+	if err != nil {
+		log.Printf("Failed to load streak freezes for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch streak freezes"})
+		return
+	}
+
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location()).Format(services.DateLayout)
+	yearAgo := today.AddDate(-1, 0, 0).Format(services.DateLayout)
+	used := 0
+	frozenDays := []string{}
+	for dayStr := range excused.Frozen {
+		if dayStr >= monthStart {
+			used++
+		}
+		if dayStr > yearAgo {
+			frozenDays = append(frozenDays, dayStr)
+		}
+	}
+	sort.Strings(frozenDays)
+
+	c.JSON(http.StatusOK, gin.H{
+		"per_month":       h.freezesPerMonth,
+		"used_this_month": used,
+		"available":       max(h.freezesPerMonth-used, 0),
+		"frozen_days":     frozenDays,
+	})
+}
+
+// defaultCategories are the habit categories every user starts with
+var defaultCategories = []string{"morning", "afternoon", "evening"}
+
+// createDefaultCategories gives a new user the default habit categories
+func createDefaultCategories(userID int) error {
+	for i, name := range defaultCategories {
+		_, err := database.DB.Exec(`
+			INSERT INTO habit_categories (user_id, name, position) VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE id = id
+		`, userID, name, i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCategory returns why a habit can't be put into the category, or "" if it can.
+// The error is set when the category couldn't be checked.
+func checkCategory(userID int, name string) (string, error) {
+	if strings.TrimSpace(name) == "" {
+		return "Category cannot be empty", nil
+	}
+	var id int
+	err := database.DB.QueryRow("SELECT id FROM habit_categories WHERE user_id = ? AND name = ?", userID, name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return fmt.Sprintf("Unknown category %q", name), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up category %q: %v", name, err)
+	}
+	return "", nil
+}
+
+// fetchCategory loads one of the user's habit categories
+func fetchCategory(categoryID, userID int) (models.HabitCategory, error) {
+	var category models.HabitCategory
+	var icon, color sql.NullString
+	err := database.DB.QueryRow(`
+		SELECT id, user_id, name, icon, color, position, created_at
+		FROM habit_categories WHERE id = ? AND user_id = ?
+	`, categoryID, userID).Scan(&category.ID, &category.UserID, &category.Name, &icon, &color, &category.Position, &category.CreatedAt)
+	category.Icon = icon.String
+	category.Color = color.String
+	return category, err
+}
+
+// GetCategories returns the user's habit categories in display order
+func (h *HabitHandler) GetCategories(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	rows, err := database.DB.Query(`
+		SELECT id, user_id, name, icon, color, position, created_at
+		FROM habit_categories WHERE user_id = ?
+		ORDER BY position, name
+	`, userID)
+	if err != nil {
+		log.Printf("Failed to query categories for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+	defer rows.Close()
+
+	categories := []models.HabitCategory{}
+	for rows.Next() {
+		var category models.HabitCategory
+		var icon, color sql.NullString
+		if err := rows.Scan(&category.ID, &category.UserID, &category.Name, &icon, &color, &category.Position, &category.CreatedAt); err != nil {
+			log.Printf("Failed to scan category: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+			return
+		}
+		category.Icon = icon.String
+		category.Color = color.String
+		categories = append(categories, category)
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+// CreateCategory adds a habit category after the user's existing ones
+func (h *HabitHandler) CreateCategory(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.CreateHabitCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO habit_categories (user_id, name, icon, color, position)
+		SELECT ?, ?, ?, ?, COALESCE(MAX(position) + 1, 0) FROM habit_categories WHERE user_id = ?
+	`, userID, req.Name, req.Icon, req.Color, userID)
+	if database.IsDuplicateKey(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A category with this name already exists"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create category for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
+		return
+	}
+
+	categoryID, _ := result.LastInsertId()
+	category, err := fetchCategory(int(categoryID), userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch created category"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory renames, restyles or moves a habit category.
+// Habits of a renamed category are moved along.
+func (h *HabitHandler) UpdateCategory(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var req models.UpdateHabitCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := fetchCategory(categoryID, userID.(int))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch category %d: %v", categoryID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category"})
+		return
+	}
+
+	updateFields := []string{}
+	args := []interface{}{}
+	renamed := false
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+			return
+		}
+		renamed = name != category.Name
+		updateFields = append(updateFields, "name = ?")
+		args = append(args, name)
+	}
+	if req.Icon != nil {
+		updateFields = append(updateFields, "icon = ?")
+		args = append(args, *req.Icon)
+	}
+	if req.Color != nil {
+		updateFields = append(updateFields, "color = ?")
+		args = append(args, *req.Color)
+	}
+	if req.Position != nil {
+		updateFields = append(updateFields, "position = ?")
+		args = append(args, *req.Position)
+	}
+
+	if len(updateFields) > 0 {
+		tx, err := database.DB.Begin()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+			return
+		}
+		defer tx.Rollback()
+
+		args = append(args, categoryID)
+		query := fmt.Sprintf("UPDATE habit_categories SET %s WHERE id = ?", strings.Join(updateFields, ", "))
+		_, err = tx.Exec(query, args...)
+		if database.IsDuplicateKey(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A category with this name already exists"})
+			return
+		}
+		if err == nil && renamed {
+			_, err = tx.Exec(`
+				UPDATE habits SET category = ? WHERE user_id = ? AND category = ?
+			`, strings.TrimSpace(*req.Name), userID, category.Name)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to update category %d: %v", categoryID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+			return
+		}
+	}
+
+	updated, err := fetchCategory(categoryID, userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteCategory deletes a habit category that no habit uses anymore
+func (h *HabitHandler) DeleteCategory(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	category, err := fetchCategory(categoryID, userID.(int))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch category %d: %v", categoryID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category"})
+		return
+	}
+
+	var habitCount int
+	err = database.DB.QueryRow(`
+		SELECT COUNT(*) FROM habits WHERE user_id = ? AND category = ?
+	`, userID, category.Name).Scan(&habitCount)
+	if err != nil {
+		log.Printf("Failed to count habits of category %d: %v", categoryID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+	if habitCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Category is still used by %d habit(s)", habitCount)})
+		return
+	}
+
+	if _, err := database.DB.Exec("DELETE FROM habit_categories WHERE id = ?", categoryID); err != nil {
+		log.Printf("Failed to delete category %d: %v", categoryID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+}
+
+// SetHabitCompletion sets or clears the completion of a habit for a past day or today,
+// within the backfill window
+func (h *HabitHandler) SetHabitCompletion(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	habitID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid habit ID"})
+		return
+	}
+
+	loc := services.UserLocation(userID.(int))
+	date, err := time.ParseInLocation("2006-01-02", c.Param("date"), loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return
+	}
+
+	var req models.SetHabitCompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	habit, err := fetchHabit(habitID)
+	if err == sql.ErrNoRows || (err == nil && habit.UserID != userID.(int)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Habit not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch habit %d: %v", habitID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch habit"})
+		return
+	}
+	if !habit.IsActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Habit is archived"})
+		return
+	}
+	if habit.TargetValue != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Measurable habits are completed by logging values"})
+		return
+	}
+	if msg := h.checkBackfillDate(habit, date, loc); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	dateStr := date.Format("2006-01-02")
+	if err := setCompletion(habitID, userID.(int), date, *req.Completed); err != nil {
+		log.Printf("Failed to set completion of habit %d on %s: %v", habitID, dateStr, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update completion"})
+		return
+	}
+	h.settleStreakFreeze(userID.(int), date, loc)
+
+	c.JSON(http.StatusOK, gin.H{"habit_id": habitID, "date": dateStr, "completed": *req.Completed})
+}
+
+// settleStreakFreeze settles the freeze of a day before today whose completions were just
+// changed, as the streak freeze job only settles each day once it is over
+func (h *HabitHandler) settleStreakFreeze(userID int, day time.Time, loc *time.Location) {
+	if !day.Before(services.Today(loc)) {
+		return
+	}
+	if err := services.SettleStreakFreeze(userID, day, h.freezesPerMonth); err != nil {
+		log.Printf("Failed to settle streak freeze of user %d on %s: %v", userID, day.Format(services.DateLayout), err)
+	}
+}
+
+// setCompletion records or removes the completion of a yes/no habit on a day
+func setCompletion(habitID, userID int, day time.Time, completed bool) error {
+	var err error
+	if completed {
+		_, err = database.DB.Exec(`
+			INSERT INTO habit_completions (habit_id, user_id, completed_at, completed_date, streak_count)
+			VALUES (?, ?, ?, ?, 1)
+			ON DUPLICATE KEY UPDATE id = id
+		`, habitID, userID, backfillTime(day), day.Format(services.DateLayout))
+	} else {
+		_, err = database.DB.Exec(`
+			DELETE FROM habit_completions WHERE habit_id = ? AND completed_date = ?
+		`, habitID, day.Format(services.DateLayout))
+	}
+	return err
+}
+
+// LogHabit adds a value to a measurable habit's total for a day. The day counts as
+// completed once the total reaches the habit's target.
+func (h *HabitHandler) LogHabit(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	habitID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid habit ID"})
+		return
+	}
+
+	var req models.LogHabitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc := services.UserLocation(userID.(int))
+	date := services.Today(loc)
+	if req.Date != "" {
+		date, err = time.ParseInLocation("2006-01-02", req.Date, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+	}
+
+	habit, err := fetchHabit(habitID)
+	if err == sql.ErrNoRows || (err == nil && habit.UserID != userID.(int)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Habit not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch habit %d: %v", habitID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch habit"})
+		return
+	}
+	if !habit.IsActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Habit is archived"})
+		return
+	}
+	if habit.TargetValue == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Habit has no target value, complete it instead"})
+		return
+	}
+	if msg := h.checkBackfillDate(habit, date, loc); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	total, completed, err := logHabitValue(habit, date, req.Value)
+	dateStr := date.Format("2006-01-02")
+	if err == errNegativeTotal {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The day's total cannot drop below zero"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to log value for habit %d on %s: %v", habitID, dateStr, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log value"})
+		return
+	}
+	h.settleStreakFreeze(userID.(int), date, loc)
+
+	c.JSON(http.StatusOK, gin.H{
+		"habit_id":     habitID,
+		"date":         dateStr,
+		"value":        total,
+		"target_value": *habit.TargetValue,
+		"unit":         habit.Unit,
+		"progress":     math.Min(total / *habit.TargetValue, 1),
+		"completed":    completed,
+	})
+}
+
+var errNegativeTotal = errors.New("total below zero")
+
+// logHabitValue records a value for a measurable habit and adds or removes the day's
+// completion depending on whether the new total reaches the target
+func logHabitValue(habit models.Habit, day time.Time, value float64) (total float64, completed bool, err error) {
+	date := day.Format(services.DateLayout)
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the habit row so concurrent logs for it are applied one after another
+	var lockedID int
+	if err := tx.QueryRow("SELECT id FROM habits WHERE id = ? FOR UPDATE", habit.ID).Scan(&lockedID); err != nil {
+		return 0, false, fmt.Errorf("failed to lock habit: %v", err)
+	}
+
+	err = tx.QueryRow(`
+		SELECT COALESCE(SUM(value), 0) FROM habit_logs WHERE habit_id = ? AND log_date = ?
+	`, habit.ID, date).Scan(&total)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to sum logged values: %v", err)
+	}
+
+	total += value
+	if total < 0 {
+		return 0, false, errNegativeTotal
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO habit_logs (habit_id, user_id, value, log_date) VALUES (?, ?, ?, ?)
+	`, habit.ID, habit.UserID, value, date)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to insert log: %v", err)
+	}
+
+	completed = total >= *habit.TargetValue
+	if completed {
+		_, err = tx.Exec(`
+			INSERT INTO habit_completions (habit_id, user_id, completed_at, completed_date, streak_count)
+			VALUES (?, ?, ?, ?, 1)
+			ON DUPLICATE KEY UPDATE id = id
+		`, habit.ID, habit.UserID, backfillTime(day), date)
+	} else {
+		_, err = tx.Exec(`
+			DELETE FROM habit_completions WHERE habit_id = ? AND completed_date = ?
+		`, habit.ID, date)
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to update completion: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("failed to commit log: %v", err)
+	}
+	return total, completed, nil
+}
+
+// checkBackfillDate returns why completions can't be changed on date, or "" if they can.
+// Days are those of the user's time zone loc.
+func (h *HabitHandler) checkBackfillDate(habit models.Habit, date time.Time, loc *time.Location) string {
+	today := services.Today(loc)
+	if date.After(today) {
+		return "Cannot complete a habit in the future"
+	}
+	if date.Before(today.AddDate(0, 0, -h.backfillDays)) {
+		return fmt.Sprintf("Completions can only be changed for the last %d days", h.backfillDays)
+	}
+	if date.Before(services.StartOfDay(habit.CreatedAt, loc)) {
+		return "Date is before the habit was created"
+	}
+	return ""
+}
+
+// HabitDayStatus is whether a habit was due and completed on one day
+type HabitDayStatus struct {
+	Date      string   `json:"date"`
+	Due       bool     `json:"due"`
+	Completed bool     `json:"completed"`
+	Value     *float64 `json:"value,omitempty"`  // logged total of measurable habits
+	Rest      bool     `json:"rest,omitempty"`   // inside one of the user's rest periods
+	Frozen    bool     `json:"frozen,omitempty"` // a missed day covered by a streak freeze
+}
+
+// HabitStatusRange is the day-by-day status of one habit
+type HabitStatusRange struct {
+	HabitID int              `json:"habit_id"`
+	Name    string           `json:"name"`
+	Days    []HabitDayStatus `json:"days"`
+}
+
+// habitDayStatuses reports for every habit and day from start to end whether it was due and completed.
+// start and end are days in the user's time zone loc.
+func habitDayStatuses(userID int, habits []models.Habit, start, end time.Time, loc *time.Location, excused services.ExcusedDays) ([]HabitStatusRange, error) {
+	if len(habits) == 0 {
+		return []HabitStatusRange{}, nil
+	}
+
+	// Quota schedules need the completions since the start of the first period in range
+	since := start
+	for _, habit := range habits {
+		if periodStart := services.PeriodStart(habit.Schedule, services.StartOfDay(habit.CreatedAt, loc), start); periodStart.Before(since) {
+			since = periodStart
+		}
+	}
+	completed, err := services.CompletionsByHabit(userID, since)
+	if err != nil {
+		return nil, err
+	}
+	logged, err := loggedValuesByHabit(userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	archived, err := services.LoadArchiveRanges(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]HabitStatusRange, 0, len(habits))
+	for _, habit := range habits {
+		anchor := services.StartOfDay(habit.CreatedAt, loc)
+		status := HabitStatusRange{HabitID: habit.ID, Name: habit.Name, Days: []HabitDayStatus{}}
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			dayStr := day.Format(services.DateLayout)
+			active := !day.Before(anchor) && !archived.Contains(habit.ID, dayStr)
+			dayStatus := HabitDayStatus{
+				Date:      dayStr,
+				Due:       active && !excused.Rest[dayStr] && services.IsDue(habit.Schedule, anchor, day, completed[habit.ID]),
+				Completed: completed[habit.ID][dayStr] > 0,
+				Rest:      excused.Rest[dayStr],
+				Frozen:    excused.Frozen[dayStr],
+			}
+			if habit.TargetValue != nil {
+				value := logged[habit.ID][dayStr]
+				dayStatus.Value = &value
+			}
+			status.Days = append(status.Days, dayStatus)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// loggedValuesByHabit sums a user's logged habit values per habit and day from start to end
+func loggedValuesByHabit(userID int, start, end time.Time) (map[int]map[string]float64, error) {
+	rows, err := database.DB.Query(`
+		SELECT habit_id, log_date, SUM(value)
+		FROM habit_logs
+		WHERE user_id = ? AND log_date BETWEEN ? AND ?
+		GROUP BY habit_id, log_date
+	`, userID, start.Format(services.DateLayout), end.Format(services.DateLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logged := make(map[int]map[string]float64)
+	for rows.Next() {
+		var habitID int
+		var date time.Time
+		var total float64
+		if err := rows.Scan(&habitID, &date, &total); err != nil {
+			return nil, err
+		}
+		if logged[habitID] == nil {
+			logged[habitID] = make(map[string]float64)
+		}
+		logged[habitID][date.Format(services.DateLayout)] = total
+	}
+	return logged, nil
+}
+
+// habitPeriods evaluates a habit's schedule from its creation until today, the
+// user's current day. Periods that start while the habit was archived are left out.
+func habitPeriods(habit models.Habit, today time.Time, excused services.ExcusedDays) ([]services.PeriodResult, error) {
+	histories, err := services.LoadHabitHistories(habit.UserID, []models.Habit{habit}, today.Location(), excused)
+	if err != nil {
+		return nil, err
+	}
+	return histories[0].Periods(today), nil
+}
+
+// allHabitsDays returns the days on which the user's habits were scheduled and how many
+// of them were completed, up to today in the user's time zone (see services.DailyResults)
+func allHabitsDays(userID int, today time.Time, excused services.ExcusedDays) ([]services.PeriodResult, error) {
+	histories, err := services.LoadHabitHistories(userID, nil, today.Location(), excused)
+	if err != nil {
+		return nil, err
+	}
+	return services.DailyResults(histories, today), nil
+}
+
+// loadHabitHistories loads the histories of the given habits, or of all the user's habits
+// when habits is nil, with days in loc (see services.LoadHabitHistories)
+func loadHabitHistories(userID int, habits []models.Habit, loc *time.Location) ([]services.HabitHistory, error) {
+	excused, err := services.LoadExcusedDays(userID)
+	if err != nil {
+		return nil, err
+	}
+	return services.LoadHabitHistories(userID, habits, loc, excused)
+}
+
+// scheduleColumns returns the schedule_days, schedule_count and schedule_interval values of a schedule
+func scheduleColumns(schedule models.HabitSchedule) (days, count, interval interface{}) {
+	if len(schedule.Days) > 0 {
+		days = services.FormatDays(schedule.Days)
+	}
+	if schedule.Count > 0 {
+		count = schedule.Count
+	}
+	if schedule.IntervalDays > 0 {
+		interval = schedule.IntervalDays
+	}
+	return days, count, interval
+}
+
+// backfillTime is the completed_at stored for a completion set for a whole day:
+// noon of that day, or now when the day is today
+func backfillTime(day time.Time) time.Time {
+	if now := time.Now().In(day.Location()); services.StartOfDay(now, day.Location()).Equal(day) {
+		return now
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, day.Location())
+}
+
+// RoutineHandler handles routine endpoints
+type RoutineHandler struct{}
+
+// NewRoutineHandler creates a new routine handler
+func NewRoutineHandler() *RoutineHandler {
+	return &RoutineHandler{}
+}
+
+// RoutineStep is one habit of a routine and its status today
+type RoutineStep struct {
+	HabitID    int    `json:"habit_id"`
+	Name       string `json:"name"`
+	Icon       string `json:"icon"`
+	Position   int    `json:"position"`
+	Measurable bool   `json:"measurable"` // completed by logging values, not through the routine
+	Due        bool   `json:"due"`
+	Completed  bool   `json:"completed"`
+}
+
+// RoutineStatus is a routine with today's progress and its streaks. A routine day
+// counts when every habit of the routine scheduled that day was done.
+type RoutineStatus struct {
+	models.Routine
+	Steps          []RoutineStep `json:"steps"`
+	CompletedToday bool          `json:"completed_today"` // all steps due today are done
+	NextHabitID    *int          `json:"next_habit_id"`   // first step still open today
+	CurrentStreak  int           `json:"current_streak"`
+	BestStreak     int           `json:"best_streak"`
+}
+
+// GetRoutines returns the user's routines with today's progress
+func (h *RoutineHandler) GetRoutines(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	routines, err := fetchRoutines(userID.(int), 0)
+	if err != nil {
+		log.Printf("Failed to fetch routines for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch routines"})
+		return
+	}
+	statuses, err := routineStatuses(userID.(int), routines)
+	if err != nil {
+		log.Printf("Failed to evaluate routines for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch routines"})
+		return
+	}
+
+	c.JSON(http.StatusOK, statuses)
+}
+
+// GetRoutine returns a single routine with today's progress
+func (h *RoutineHandler) GetRoutine(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	routineID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid routine ID"})
+		return
+	}
+
+	h.respondWithRoutine(c, userID.(int), routineID, http.StatusOK)
+}
+
+// CreateRoutine creates a routine from an ordered list of habits
+func (h *RoutineHandler) CreateRoutine(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.CreateRoutineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+		return
+	}
+	msg, err := checkRoutineHabits(userID.(int), req.HabitIDs)
+	if err != nil {
+		log.Printf("Failed to check routine habits for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check habits"})
+		return
+	}
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create routine"})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO routines (user_id, name, description, icon, color) VALUES (?, ?, ?, ?, ?)
+	`, userID, req.Name, req.Description, req.Icon, req.Color)
+	if err != nil {
+		log.Printf("Failed to create routine for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create routine"})
+		return
+	}
+	routineID, _ := result.LastInsertId()
+	if err := setRoutineHabits(tx, int(routineID), req.HabitIDs); err != nil {
+		log.Printf("Failed to add habits to routine %d: %v", routineID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create routine"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create routine"})
+		return
+	}
+
+	h.respondWithRoutine(c, userID.(int), int(routineID), http.StatusCreated)
+}
+
+// UpdateRoutine updates a routine; habit_ids replaces its steps and their order
+func (h *RoutineHandler) UpdateRoutine(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	routineID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid routine ID"})
+		return
+	}
+
+	var req models.UpdateRoutineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var exists int
+	err = database.DB.QueryRow("SELECT id FROM routines WHERE id = ? AND user_id = ?", routineID, userID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Routine not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch routine %d: %v", routineID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch routine"})
+		return
+	}
+
+	updateFields := []string{}
+	args := []interface{}{}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+			return
+		}
+		updateFields = append(updateFields, "name = ?")
+		args = append(args, *req.Name)
+	}
+	if req.Description != nil {
+		updateFields = append(updateFields, "description = ?")
+		args = append(args, *req.Description)
+	}
+	if req.Icon != nil {
+		updateFields = append(updateFields, "icon = ?")
+		args = append(args, *req.Icon)
+	}
+	if req.Color != nil {
+		updateFields = append(updateFields, "color = ?")
+		args = append(args, *req.Color)
+	}
+	if req.HabitIDs != nil {
+		msg, err := checkRoutineHabits(userID.(int), *req.HabitIDs)
+		if err != nil {
+			log.Printf("Failed to check routine habits for user %v: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check habits"})
+			return
+		}
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update routine"})
+		return
+	}
+	defer tx.Rollback()
+
+	if len(updateFields) > 0 {
+		args = append(args, routineID)
+		query := fmt.Sprintf("UPDATE routines SET %s WHERE id = ?", strings.Join(updateFields, ", "))
+		if _, err := tx.Exec(query, args...); err != nil {
+			log.Printf("Failed to update routine %d: %v", routineID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update routine"})
+			return
+		}
+	}
+	if req.HabitIDs != nil {
+		if err := setRoutineHabits(tx, routineID, *req.HabitIDs); err != nil {
+			log.Printf("Failed to update habits of routine %d: %v", routineID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update routine"})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update routine"})
+		return
+	}
+
+	h.respondWithRoutine(c, userID.(int), routineID, http.StatusOK)
+}
+
+// DeleteRoutine deletes a routine; its habits are kept
+func (h *RoutineHandler) DeleteRoutine(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	routineID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid routine ID"})
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM routines WHERE id = ? AND user_id = ?", routineID, userID)
+	if err != nil {
+		log.Printf("Failed to delete routine %d: %v", routineID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete routine"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Routine not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Routine deleted successfully"})
+}
+
+// CompleteRoutine completes every step of the routine that is still open today.
+// Measurable habits are left alone, they are completed by logging values.
+func (h *RoutineHandler) CompleteRoutine(c *gin.Context) {
+	h.advanceRoutine(c, false)
+}
+
+// StepRoutine completes the next open step of the routine
+func (h *RoutineHandler) StepRoutine(c *gin.Context) {
+	h.advanceRoutine(c, true)
+}
+
+// advanceRoutine completes the open steps of a routine for today, only the first one if single
+func (h *RoutineHandler) advanceRoutine(c *gin.Context, single bool) {
+	userID, _ := c.Get("user_id")
+	routineID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid routine ID"})
+		return
+	}
+
+	status, err := fetchRoutineStatus(userID.(int), routineID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Routine not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch routine %d: %v", routineID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch routine"})
+		return
+	}
+	if status.NextHabitID == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Routine is already complete for today"})
+		return
+	}
+
+	today := services.Today(services.UserLocation(userID.(int)))
+	for _, step := range status.Steps {
+		if !step.Due || step.Completed {
+			continue
+		}
+		if step.Measurable {
+			if single {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Log a value to complete this step", "habit_id": step.HabitID})
+				return
+			}
+			continue
+		}
+		if err := setCompletion(step.HabitID, userID.(int), today, true); err != nil {
+			log.Printf("Failed to complete habit %d of routine %d: %v", step.HabitID, routineID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete routine"})
+			return
+		}
+		if single {
+			break
+		}
+	}
+
+	h.respondWithRoutine(c, userID.(int), routineID, http.StatusOK)
+}
+
+// respondWithRoutine writes the current status of a routine
+func (h *RoutineHandler) respondWithRoutine(c *gin.Context, userID, routineID, status int) {
+	routine, err := fetchRoutineStatus(userID, routineID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Routine not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch routine %d: %v", routineID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch routine"})
+		return
+	}
+	c.JSON(status, routine)
+}
+
+// fetchRoutineStatus loads one routine of the user with today's progress
+func fetchRoutineStatus(userID, routineID int) (RoutineStatus, error) {
+	routines, err := fetchRoutines(userID, routineID)
+	if err != nil {
+		return RoutineStatus{}, err
+	}
+	if len(routines) == 0 {
+		return RoutineStatus{}, sql.ErrNoRows
+	}
+	statuses, err := routineStatuses(userID, routines)
+	if err != nil {
+		return RoutineStatus{}, err
+	}
+	return statuses[0], nil
+}
+
+// fetchRoutines loads the user's routines with their habits in order, or only the
+// routine with routineID when it isn't 0
+func fetchRoutines(userID, routineID int) ([]models.Routine, error) {
+	query := "SELECT id, user_id, name, description, icon, color, created_at, updated_at FROM routines WHERE user_id = ?"
+	args := []interface{}{userID}
+	if routineID != 0 {
+		query += " AND id = ?"
+		args = append(args, routineID)
+	}
+	rows, err := database.DB.Query(query+" ORDER BY created_at", args...)
+	if err != nil {
+		return nil, err
+	}
+	routines := []models.Routine{}
+	index := make(map[int]int)
+	for rows.Next() {
+		var routine models.Routine
+		var description, icon, color sql.NullString
+		if err := rows.Scan(&routine.ID, &routine.UserID, &routine.Name, &description, &icon, &color, &routine.CreatedAt, &routine.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		routine.Description = description.String
+		routine.Icon = icon.String
+		routine.Color = color.String
+		routine.HabitIDs = []int{}
+		index[routine.ID] = len(routines)
+		routines = append(routines, routine)
+	}
+	rows.Close()
+
+	rows, err = database.DB.Query(`
+		SELECT rh.routine_id, rh.habit_id
+		FROM routine_habits rh
+		INNER JOIN routines r ON r.id = rh.routine_id
+		WHERE r.user_id = ?
+		ORDER BY rh.routine_id, rh.position
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var routineID, habitID int
+		if err := rows.Scan(&routineID, &habitID); err != nil {
+			return nil, err
+		}
+		if i, ok := index[routineID]; ok {
+			routines[i].HabitIDs = append(routines[i].HabitIDs, habitID)
+		}
+	}
+	return routines, rows.Err()
+}
+
+// routineStatuses adds today's progress and the streaks to routines. Archived habits
+// are not listed as steps but count for routine streaks while they were active.
+func routineStatuses(userID int, routines []models.Routine) ([]RoutineStatus, error) {
+	today := services.Today(services.UserLocation(userID))
+
+	habits, err := fetchUserHabits(userID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch habits: %v", err)
+	}
+	histories, err := loadHabitHistories(userID, habits, today.Location())
+	if err != nil {
+		return nil, err
+	}
+	habitsByID := make(map[int]models.Habit, len(habits))
+	historiesByID := make(map[int]services.HabitHistory, len(histories))
+	for i, habit := range habits {
+		habitsByID[habit.ID] = habit
+		historiesByID[habit.ID] = histories[i]
+	}
+
+	todayStr := today.Format(services.DateLayout)
+	statuses := make([]RoutineStatus, 0, len(routines))
+	for _, routine := range routines {
+		status := RoutineStatus{Routine: routine, Steps: []RoutineStep{}, CompletedToday: true}
+		var routineHistories []services.HabitHistory
+		for position, habitID := range routine.HabitIDs {
+			habit, ok := habitsByID[habitID]
+			if !ok {
+				continue
+			}
+			history := historiesByID[habitID]
+			routineHistories = append(routineHistories, history)
+			if !habit.IsActive {
+				continue
+			}
+
+			step := RoutineStep{
+				HabitID:    habit.ID,
+				Name:       habit.Name,
+				Icon:       habit.Icon,
+				Position:   position,
+				Measurable: habit.TargetValue != nil,
+				Due:        services.IsDue(habit.Schedule, history.Anchor, today, history.Completed),
+				Completed:  history.Completed[todayStr] > 0,
+			}
+			if step.Due && !step.Completed {
+				status.CompletedToday = false
+				if status.NextHabitID == nil {
+					status.NextHabitID = &step.HabitID
+				}
+			}
+			status.Steps = append(status.Steps, step)
+		}
+		status.CurrentStreak, status.BestStreak = services.Streaks(services.DailyResults(routineHistories, today), today)
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// checkRoutineHabits returns why habitIDs can't be the steps of a routine, or "" if they
+// can. The error is set when the habits couldn't be checked.
+func checkRoutineHabits(userID int, habitIDs []int) (string, error) {
+	seen := make(map[int]bool)
+	for _, habitID := range habitIDs {
+		if seen[habitID] {
+			return fmt.Sprintf("Habit %d is listed twice", habitID), nil
+		}
+		seen[habitID] = true
+
+		var ownerID int
+		err := database.DB.QueryRow("SELECT user_id FROM habits WHERE id = ?", habitID).Scan(&ownerID)
+		if err == sql.ErrNoRows || (err == nil && ownerID != userID) {
+			return fmt.Sprintf("Habit %d not found", habitID), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to look up habit %d: %v", habitID, err)
+		}
+	}
+	return "", nil
+}
+
+// setRoutineHabits replaces the steps of a routine with habitIDs in that order
+func setRoutineHabits(tx *sql.Tx, routineID int, habitIDs []int) error {
+	if _, err := tx.Exec("DELETE FROM routine_habits WHERE routine_id = ?", routineID); err != nil {
+		return err
+	}
+	for position, habitID := range habitIDs {
+		_, err := tx.Exec(`
+			INSERT INTO routine_habits (routine_id, habit_id, position) VALUES (?, ?, ?)
+		`, routineID, habitID, position)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TaskHandler handles task endpoints
+type TaskHandler struct{}
+
+// taskColumns are the columns scanTask reads, in order
+const taskColumns = `id, user_id, title, description, priority, due_date,
+		       completed_at, parent_task_id, parent_id, position, is_recurring_template,
+		       recurrence_interval_weeks, recurrence_end_date, recurrence_rule, occurrence_date,
+		       project_id, note_id, checklist_item_id, created_at, updated_at`
+
+// scanTask reads a task selected with taskColumns
+func scanTask(row rowScanner) (models.Task, error) {
+	var task models.Task
+	var occurrenceDate sql.NullTime
+	err := row.Scan(
+		&task.ID, &task.UserID, &task.Title, &task.Description,
+		&task.Priority, &task.DueDate, &task.CompletedAt,
+		&task.ParentTaskID, &task.ParentID, &task.Position, &task.IsRecurringTemplate,
+		&task.RecurrenceIntervalWeeks, &task.RecurrenceEndDate, &task.RecurrenceRule, &occurrenceDate,
+		&task.ProjectID, &task.NoteID, &task.ChecklistItemID, &task.CreatedAt, &task.UpdatedAt,
+	)
+	if occurrenceDate.Valid {
+		day := occurrenceDate.Time.Format(services.DateLayout)
+		task.OccurrenceDate = &day
+	}
+	return task, err
+}
+
+// fetchTask loads a task of the user
+func fetchTask(taskID, userID int) (models.Task, error) {
+	return scanTask(database.DB.QueryRow(
+		"SELECT "+taskColumns+" FROM tasks WHERE id = ? AND user_id = ?", taskID, userID))
+}
+
+// taskSort is an order of the task list. Rows are ordered by keys, all ascending and
+// ending with a unique one, so a page can continue after the key values of its last task.
+type taskSort struct {
+	keys   []string
+	values func(task models.Task) []interface{}
+}
+
+const (
+	taskCompletedKey = "IF(completed_at IS NULL, 0, 1)"
+	taskPriorityKey  = "CASE priority WHEN 'high' THEN 1 WHEN 'medium' THEN 2 WHEN 'low' THEN 3 ELSE 4 END"
+)
+
+// fetchTaskWithLabels loads a task of the user with its labels
+func fetchTaskWithLabels(taskID, userID int) (models.Task, error) {
+	task, err := fetchTask(taskID, userID)
+	if err != nil {
+		return task, err
+	}
+	tasks := []models.Task{task}
+	err = loadTaskLabels(tasks)
+	return tasks[0], err
+}
+
+// Task list orders: by priority and due date (default), in manual order, by due date or by title
+var taskSorts = map[string]taskSort{
+	"priority": {
+		keys: []string{taskCompletedKey, taskPriorityKey, taskDueKey(""), "position", "id"},
+		values: func(t models.Task) []interface{} {
+			return []interface{}{taskCompletedRank(t), taskPriorityRank(t.Priority), taskDueValue(t, ""), t.Position, t.ID}
+		},
+	},
+	"manual": {
+		keys: []string{"position", "id"},
+		values: func(t models.Task) []interface{} {
+			return []interface{}{t.Position, t.ID}
+		},
+	},
+	"due": {
+		keys: []string{taskCompletedKey, taskDueKey(noDueDateKey), taskPriorityKey, "id"},
+		values: func(t models.Task) []interface{} {
+			return []interface{}{taskCompletedRank(t), taskDueValue(t, noDueDateKey), taskPriorityRank(t.Priority), t.ID}
+		},
+	},
+	"title": {
+		keys: []string{"title", "id"},
+		values: func(t models.Task) []interface{} {
+			return []interface{}{t.Title, t.ID}
+		},
+	},
+}
+
+// noDueDateKey sorts tasks without due date after all others
+const noDueDateKey = "9999-12-31 23:59:59"
+
+// taskDueKey is the due date as a sortable string, none for tasks without one
+func taskDueKey(none string) string {
+	return fmt.Sprintf("IFNULL(DATE_FORMAT(due_date, '%%Y-%%m-%%d %%H:%%i:%%s'), '%s')", none)
+}
+
+// taskDueValue is the value of taskDueKey for a task
+func taskDueValue(t models.Task, none string) string {
+	if t.DueDate == nil {
+		return none
+	}
+	return t.DueDate.UTC().Format("2006-01-02 15:04:05")
+}
+
+func taskCompletedRank(t models.Task) int {
+	if t.CompletedAt == nil {
+		return 0
+	}
+	return 1
+}
+
+func taskPriorityRank(priority string) int {
+	switch priority {
+	case "high":
+		return 1
+	case "medium":
+		return 2
+	case "low":
+		return 3
+	}
+	return 4
+}
+
+// Page sizes of the task list
+const (
+	defaultTaskPageSize = 50
+	maxTaskPageSize     = 200
+)
+
+// taskCursor is where the next page of a task list starts, handed out as an opaque string
+type taskCursor struct {
+	Sort string        `json:"s"`
+	Keys []interface{} `json:"k"`
+}
+
+func encodeTaskCursor(sortBy string, task models.Task) string {
+	data, _ := json.Marshal(taskCursor{Sort: sortBy, Keys: taskSorts[sortBy].values(task)})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTaskCursor(raw, sortBy string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var cursor taskCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || len(cursor.Keys) != len(taskSorts[sortBy].keys) {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if cursor.Sort != sortBy {
+		return nil, fmt.Errorf("cursor belongs to a different sort order")
+	}
+	return cursor.Keys, nil
+}
+
+// TaskView is a predefined task list: the filters it applies by default
+type TaskView struct {
+	Name    string            `json:"name"`
+	Title   string            `json:"title"`
+	Filters map[string]string `json:"filters"`
+}
+
+// taskViews are the task lists available as ?view=
+var taskViews = []TaskView{
+	{Name: "today", Title: "Today", Filters: map[string]string{"status": "open", "due": "until_today", "sort": "priority"}},
+	{Name: "upcoming", Title: "Upcoming", Filters: map[string]string{"status": "open", "due": "upcoming", "sort": "due"}},
+	{Name: "someday", Title: "Someday", Filters: map[string]string{"status": "open", "due": "none", "sort": "manual"}},
+}
+
+// taskFilterParams are the query parameters of the task list; they override those of a view
+var taskFilterParams = []string{"status", "priority", "due", "due_from", "due_to", "q", "project", "label", "sort", "instances"}
+
+// taskFilter is a parsed task list query
+type taskFilter struct {
+	status     string // open, completed or empty for all
+	priorities []string
+	due        string // a due range, see dueRange
+	dueFrom    *time.Time
+	dueTo      *time.Time // exclusive
+	query      string
+	project    string // a project ID or "none"
+	labels     []int  // tasks with any of these labels
+	sort       string
+	instances  string // next or all open instances of recurring tasks
+}
+
+// parseTaskFilter reads the filters of a task list from the query, starting from those
+// of ?view=. Days are those of loc.
+func parseTaskFilter(c *gin.Context, loc *time.Location) (taskFilter, error) {
+	params := map[string]string{"sort": "priority", "instances": "next"}
+	if name := c.Query("view"); name != "" {
+		found := false
+		for _, view := range taskViews {
+			if view.Name == name {
+				for key, value := range view.Filters {
+					params[key] = value
+				}
+				found = true
+			}
+		}
+		if !found {
+			return taskFilter{}, fmt.Errorf("unknown view %q", name)
+		}
+	}
+	for _, key := range taskFilterParams {
+		if value := strings.TrimSpace(c.Query(key)); value != "" {
+			params[key] = value
+		}
+	}
+
+	filter := taskFilter{sort: params["sort"], query: params["q"], due: params["due"], project: params["project"], instances: params["instances"]}
+	if _, ok := taskSorts[filter.sort]; !ok {
+		return filter, fmt.Errorf("sort must be priority, manual, due or title")
+	}
+	if filter.instances != "next" && filter.instances != "all" {
+		return filter, fmt.Errorf("instances must be next or all")
+	}
+	switch params["status"] {
+	case "", "all":
+	case "open", "completed":
+		filter.status = params["status"]
+	default:
+		return filter, fmt.Errorf("status must be open, completed or all")
+	}
+	if raw := params["priority"]; raw != "" {
+		for _, priority := range strings.Split(raw, ",") {
+			priority = strings.TrimSpace(priority)
+			if taskPriorityRank(priority) > 3 {
+				return filter, fmt.Errorf("invalid priority %q", priority)
+			}
+			filter.priorities = append(filter.priorities, priority)
+		}
+	}
+	if filter.due != "" {
+		if _, _, err := dueRange(filter.due, loc); err != nil {
+			return filter, err
+		}
+	}
+	if filter.project != "" && filter.project != "none" {
+		if _, err := strconv.Atoi(filter.project); err != nil {
+			return filter, fmt.Errorf("project must be a project ID or none")
+		}
+	}
+	if raw := params["label"]; raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			labelID, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return filter, fmt.Errorf("label must be a comma separated list of label IDs")
+			}
+			filter.labels = append(filter.labels, labelID)
+		}
+	}
+	for key, bound := range map[string]**time.Time{"due_from": &filter.dueFrom, "due_to": &filter.dueTo} {
+		if raw := params[key]; raw != "" {
+			day, err := time.ParseInLocation(services.DateLayout, raw, loc)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be a date (YYYY-MM-DD)", key)
+			}
+			if key == "due_to" {
+				day = day.AddDate(0, 0, 1)
+			}
+			*bound = &day
+		}
+	}
+	return filter, nil
+}
+
+// dueRange returns the due dates matching a named range as [from, to); either may be
+// nil for an open end. "none" matches tasks without due date and has neither.
+func dueRange(name string, loc *time.Location) (from, to *time.Time, err error) {
+	today := services.Today(loc)
+	tomorrow := today.AddDate(0, 0, 1)
+	monday := today.AddDate(0, 0, -((int(today.Weekday())+6)%7))
+	now := time.Now()
+	switch name {
+	case "overdue":
+		return nil, &now, nil
+	case "today":
+		return &today, &tomorrow, nil
+	case "until_today":
+		return nil, &tomorrow, nil
+	case "tomorrow":
+		dayAfter := tomorrow.AddDate(0, 0, 1)
+		return &tomorrow, &dayAfter, nil
+	case "this_week":
+		nextMonday := monday.AddDate(0, 0, 7)
+		return &monday, &nextMonday, nil
+	case "next_week":
+		nextMonday, mondayAfter := monday.AddDate(0, 0, 7), monday.AddDate(0, 0, 14)
+		return &nextMonday, &mondayAfter, nil
+	case "upcoming":
+		return &tomorrow, nil, nil
+	case "none":
+		return nil, nil, nil
+	}
+	return nil, nil, fmt.Errorf("due must be overdue, today, until_today, tomorrow, this_week, next_week, upcoming or none")
+}
+
+// where returns the SQL conditions of the filter and their arguments
+func (f taskFilter) where(loc *time.Location) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	switch f.status {
+	case "open":
+		conditions = append(conditions, "completed_at IS NULL")
+	case "completed":
+		conditions = append(conditions, "completed_at IS NOT NULL")
+	}
+	if len(f.priorities) > 0 {
+		placeholders := strings.Repeat("?,", len(f.priorities))
+		conditions = append(conditions, fmt.Sprintf("priority IN (%s)", placeholders[:len(placeholders)-1]))
+		for _, priority := range f.priorities {
+			args = append(args, priority)
+		}
+	}
+	if f.due == "none" {
+		conditions = append(conditions, "due_date IS NULL")
+	} else if f.due != "" {
+		from, to, _ := dueRange(f.due, loc)
+		if f.due == "overdue" {
+			conditions = append(conditions, "completed_at IS NULL")
+		}
+		if from != nil {
+			conditions = append(conditions, "due_date >= ?")
+			args = append(args, *from)
+		}
+		if to != nil {
+			conditions = append(conditions, "due_date < ?")
+			args = append(args, *to)
+		}
+	}
+	if f.dueFrom != nil {
+		conditions = append(conditions, "due_date >= ?")
+		args = append(args, *f.dueFrom)
+	}
+	if f.dueTo != nil {
+		conditions = append(conditions, "due_date < ?")
+		args = append(args, *f.dueTo)
+	}
+	if f.query != "" {
+		pattern := "%" + escapeLike(f.query) + "%"
+		conditions = append(conditions, "(title LIKE ? OR description LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+	if f.project == "none" {
+		conditions = append(conditions, "project_id IS NULL")
+	} else if f.project != "" {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, f.project)
+	}
+	if len(f.labels) > 0 {
+		placeholders := strings.Repeat("?,", len(f.labels))
+		conditions = append(conditions, fmt.Sprintf("id IN (SELECT task_id FROM task_labels WHERE label_id IN (%s))", placeholders[:len(placeholders)-1]))
+		args = append(args, convertIntsToInterface(f.labels)...)
+	}
+	if f.instances == "next" {
+		conditions = append(conditions, services.NextOpenInstance("tasks"))
+	}
+	return conditions, args
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// GetTaskViews returns the predefined task lists
+func (h *TaskHandler) GetTaskViews(c *gin.Context) {
+	c.JSON(http.StatusOK, taskViews)
+}
+
+// GetTasks returns the tasks of the authenticated user, filtered by ?view= and the
+// parameters in taskFilterParams. Subtasks are listed alongside their parents and
+// reference them by parent_id. Of the open instances of a recurring task only the next
+// one is listed; ?instances=all lists all of them up to the recurrence horizon, such as
+// for a calendar. With ?limit= or ?cursor= the list is paginated and the cursor of the
+// next page is sent in the X-Next-Cursor header.
+func (h *TaskHandler) GetTasks(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	loc := services.UserLocation(userID.(int))
+
+	filter, err := parseTaskFilter(c, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	order := taskSorts[filter.sort]
+
+	conditions, args := filter.where(loc)
+	conditions = append([]string{"user_id = ?", "is_recurring_template = FALSE"}, conditions...)
+	args = append([]interface{}{userID}, args...)
+
+	limit := 0
+	if raw, cursor := c.Query("limit"), c.Query("cursor"); raw != "" || cursor != "" {
+		limit = defaultTaskPageSize
+		if raw != "" {
+			if limit, err = strconv.Atoi(raw); err != nil || limit < 1 || limit > maxTaskPageSize {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxTaskPageSize)})
+				return
+			}
+		}
+		if cursor != "" {
+			keys, err := decodeTaskCursor(cursor, filter.sort)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			placeholders := strings.Repeat("?,", len(keys))
+			conditions = append(conditions, fmt.Sprintf("(%s) > (%s)", strings.Join(order.keys, ", "), placeholders[:len(placeholders)-1]))
+			args = append(args, keys...)
+		}
+	}
+
+	if err := services.MaterializeRecurringTasks(userID.(int)); err != nil {
+		log.Printf("Failed to create recurring task instances for user %v: %v", userID, err)
+	}
+
+	query := "SELECT " + taskColumns + " FROM tasks WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY " + strings.Join(order.keys, ", ")
+	if limit > 0 {
+		// One more than requested tells whether there is a next page
+		query += fmt.Sprintf(" LIMIT %d", limit+1)
+	}
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Failed to query tasks for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+		return
+	}
+	defer rows.Close()
+
+	var tasks []models.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			log.Printf("Failed to scan task for user %v: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan task"})
+			return
+		}
+		tasks = append(tasks, task)
+	}
+
+	if limit > 0 && len(tasks) > limit {
+		tasks = tasks[:limit]
+		c.Header("X-Next-Cursor", encodeTaskCursor(filter.sort, tasks[limit-1]))
+	}
+	if err := loadTaskLabels(tasks); err != nil {
+		log.Printf("Failed to load task labels for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// GetRecurringTasks returns the recurring task templates of the user
+func (h *TaskHandler) GetRecurringTasks(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	rows, err := database.DB.Query(`
+		SELECT `+taskColumns+`
+		FROM tasks WHERE user_id = ? AND is_recurring_template = TRUE
+		ORDER BY created_at`, userID)
+	if err != nil {
+		log.Printf("Failed to query recurring tasks for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recurring tasks"})
+		return
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			log.Printf("Failed to scan recurring task for user %v: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recurring tasks"})
+			return
+		}
+		tasks = append(tasks, task)
+	}
+	if err := loadTaskLabels(tasks); err != nil {
+		log.Printf("Failed to load task labels for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recurring tasks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// CreateTask creates a new task. A recurring task is stored as a template whose
+// instances are created up to the recurrence horizon.
+func (h *TaskHandler) CreateTask(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.CreateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.ParentID != nil {
+		msg, err := checkTaskParent(userID.(int), 0, *req.ParentID)
+		if err != nil {
+			log.Printf("Failed to check parent task for user %v: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check parent task"})
+			return
+		}
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+	if req.ProjectID != nil {
+		msg, err := checkTaskProject(userID.(int), *req.ProjectID)
+		if err != nil {
+			log.Printf("Failed to check project for user %v: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check project"})
+			return
+		}
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+	msg, err := checkLabels(userID.(int), req.LabelIDs)
+	if err != nil {
+		log.Printf("Failed to check labels for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check labels"})
+		return
+	}
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	rule, err := taskRecurrenceRule(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if rule != nil && req.DueDate == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "due_date is required for recurring tasks"})
+		return
+	}
+	position, err := nextTaskPosition(userID.(int), req.ParentID)
+	if err != nil {
+		log.Printf("Failed to determine task position for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		return
+	}
+
+	if rule != nil {
+		result, err := database.DB.Exec(`
+			INSERT INTO tasks (user_id, title, description, priority, due_date, parent_id, position, project_id, is_recurring_template, recurrence_interval_weeks, recurrence_end_date, recurrence_rule)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, TRUE, ?, ?, ?)
+		`, userID, req.Title, req.Description, req.Priority, req.DueDate, req.ParentID, position, req.ProjectID, req.RecurrenceIntervalWeeks, req.RecurrenceEndDate, rule.String())
+		if err != nil {
+			log.Printf("Failed to create recurring task: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recurring task"})
+			return
+		}
+		templateID, _ := result.LastInsertId()
+		// Instances get the labels of the template
+		if err := setTaskLabels(database.DB, int(templateID), req.LabelIDs); err != nil {
+			log.Printf("Failed to set labels of task %d: %v", templateID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recurring task"})
+			return
+		}
+
+		if err := services.MaterializeRecurringTasks(userID.(int)); err != nil {
+			log.Printf("Failed to create recurring task instances for user %v: %v", userID, err)
+		}
+		template, err := fetchTaskWithLabels(int(templateID), userID.(int))
+		if err != nil {
+			log.Printf("Failed to fetch task %d: %v", templateID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recurring task"})
+			return
+		}
+		instances, err := fetchSeriesInstances(int(templateID), userID.(int))
+		if err != nil {
+			log.Printf("Failed to fetch instances of task %d: %v", templateID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recurring task"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":  "Recurring task created",
+			"template": template,
+			"count":    len(instances),
+			"tasks":    instances,
+		})
+		return
+	}
+
+	// Non-recurring task
+	result, err := database.DB.Exec(`
+		INSERT INTO tasks (user_id, title, description, priority, due_date, parent_id, position, project_id, is_recurring_template, recurrence_interval_weeks, recurrence_end_date)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, userID, req.Title, req.Description, req.Priority, req.DueDate, req.ParentID, position, req.ProjectID, false, nil, nil)
+	if err != nil {
+		log.Printf("Failed to create task: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		return
+	}
+
+	taskID, _ := result.LastInsertId()
+	if err := setTaskLabels(database.DB, int(taskID), req.LabelIDs); err != nil {
+		log.Printf("Failed to set labels of task %d: %v", taskID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		return
+	}
+
+	task, err := fetchTaskWithLabels(int(taskID), userID.(int))
+	if err != nil {
+		log.Printf("Failed to fetch task %d: %v", taskID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, task)
+}
+
+// QuickAddTask creates a task from one line of text. Dates, times, priority markers
+// ("!hoch") and tags ("#gesundheit") are read by rules; with use_ai, text the rules find
+// ambiguous is read by the AI instead. Tags become labels, missing ones are created.
+// With preview, nothing is saved and only the parse is returned.
+func (h *TaskHandler) QuickAddTask(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.QuickAddTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now().In(services.UserLocation(userID.(int)))
+	parsed := services.ParseQuickTask(req.Text, now)
+	if parsed.Ambiguous && req.UseAI {
+		if aiParsed, err := services.NewOpenAIService().ParseQuickTask(req.Text, now); err != nil {
+			log.Printf("AI quick add parse failed for user %v: %v", userID, err)
+		} else {
+			parsed = aiParsed
+		}
+	}
+	if parsed.Priority == "" {
+		parsed.Priority = "medium"
+	}
+	if parsed.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No task title found in the text"})
+		return
+	}
+	if req.ProjectID != nil {
+		msg, err := checkTaskProject(userID.(int), *req.ProjectID)
+		if err != nil {
+			log.Printf("Failed to check project for user %v: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check project"})
+			return
+		}
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
+	for _, tag := range parsed.Tags {
+		if len([]rune(tag)) > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Tag %q is longer than 50 characters", tag)})
+			return
+		}
+	}
+
+	labels, missing, err := labelsForTags(userID.(int), parsed.Tags)
+	if err != nil {
+		log.Printf("Failed to fetch labels for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch labels"})
+		return
+	}
+	if req.Preview {
+		c.JSON(http.StatusOK, gin.H{"parsed": parsed, "labels": labels, "new_labels": missing})
+		return
+	}
+
+	labelIDs := make([]int, 0, len(parsed.Tags))
+	for _, label := range labels {
+		labelIDs = append(labelIDs, label.ID)
+	}
+	for _, name := range missing {
+		labelID, err := createTagLabel(userID.(int), name)
+		if err != nil {
+			log.Printf("Failed to create label %q for user %v: %v", name, userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create label"})
+			return
+		}
+		labelIDs = append(labelIDs, labelID)
+	}
+
+	position, err := nextTaskPosition(userID.(int), nil)
+	if err != nil {
+		log.Printf("Failed to determine task position for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		return
+	}
+	result, err := database.DB.Exec(`
+		INSERT INTO tasks (user_id, title, description, priority, due_date, position, project_id)
+		VALUES (?, ?, '', ?, ?, ?, ?)
+	`, userID, parsed.Title, parsed.Priority, parsed.DueDate, position, req.ProjectID)
+	if err != nil {
+		log.Printf("Failed to create task: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		return
+	}
+	taskID, _ := result.LastInsertId()
+	if err := setTaskLabels(database.DB, int(taskID), labelIDs); err != nil {
+		log.Printf("Failed to set labels of task %d: %v", taskID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		return
+	}
+
+	task, err := fetchTaskWithLabels(int(taskID), userID.(int))
+	if err != nil {
+		log.Printf("Failed to fetch task %d: %v", taskID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"task": task, "parsed": parsed})
+}
+
+// labelsForTags returns the labels of the user named like tags, ignoring case, and the
+// tags no label is named like
+func labelsForTags(userID int, tags []string) ([]models.Label, []string, error) {
+	labels := []models.Label{}
+	missing := []string{}
+	if len(tags) == 0 {
+		return labels, missing, nil
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, user_id, name, color, created_at FROM labels WHERE user_id = ?
+	`, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var all []models.Label
+	for rows.Next() {
+		label, err := scanLabel(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		all = append(all, label)
+	}
+
+	for _, tag := range tags {
+		found := false
+		for _, label := range all {
+			if strings.EqualFold(label.Name, tag) {
+				labels = append(labels, label)
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, tag)
+		}
+	}
+	return labels, missing, nil
+}
+
+// createTagLabel creates a label for a tag, or returns the existing one if another
+// request created it first
+func createTagLabel(userID int, name string) (int, error) {
+	result, err := database.DB.Exec("INSERT INTO labels (user_id, name) VALUES (?, ?)", userID, name)
+	if database.IsDuplicateKey(err) {
+		var labelID int
+		err = database.DB.QueryRow("SELECT id FROM labels WHERE user_id = ? AND name = ?", userID, name).Scan(&labelID)
+		return labelID, err
+	}
+	if err != nil {
+		return 0, err
+	}
+	labelID, _ := result.LastInsertId()
+	return int(labelID), nil
+}
+
+// UpdateTask updates a task; setting parent_id moves it with its subtasks.
+// For an instance of a recurring task, ?scope=following or ?scope=all edits the series
+// from that instance on or as a whole; editing a template always edits the whole series.
+func (h *TaskHandler) UpdateTask(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	var req models.UpdateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := fetchTask(taskID, userID.(int))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch task %d: %v", taskID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task"})
+		return
+	}
+
+	scope, msg := taskScope(c, task)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if scope != scopeThis {
+		h.updateSeries(c, userID.(int), task, scope, req)
+		return
+	}
+	if req.RecurrenceRule != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "recurrence_rule can only be changed for following or all occurrences"})
+		return
+	}
+
+	updateFields := []string{}
+	args := []interface{}{}
+
+	if req.Title != nil {
+		if strings.TrimSpace(*req.Title) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Title cannot be empty"})
+			return
+		}
+		updateFields = append(updateFields, "title = ?")
+		args = append(args, *req.Title)
+	}
+	if req.Description != nil {
+		updateFields = append(updateFields, "description = ?")
+		args = append(args, *req.Description)
+	}
+	if req.Priority != nil {
+		updateFields = append(updateFields, "priority = ?")
+		args = append(args, *req.Priority)
+	}
+	if req.ClearDueDate {
+		updateFields = append(updateFields, "due_date = NULL")
+	} else if req.DueDate != nil {
+		updateFields = append(updateFields, "due_date = ?")
+		args = append(args, *req.DueDate)
+	}
+	if req.ParentID != nil {
+		var parentID *int
+		if *req.ParentID != 0 {
+			parentID = req.ParentID
+			msg, err := checkTaskParent(userID.(int), taskID, *parentID)
+			if err != nil {
+				log.Printf("Failed to check parent task for user %v: %v", userID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check parent task"})
+				return
+			}
+			if msg != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+				return
+			}
+		}
+		if !sameTaskParent(task.ParentID, parentID) {
+			position, err := nextTaskPosition(userID.(int), parentID)
+			if err != nil {
+				log.Printf("Failed to determine task position for user %v: %v", userID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+				return
+			}
+			updateFields = append(updateFields, "parent_id = ?", "position = ?")
+			args = append(args, parentID, position)
+		}
+	}
+	projectFields, projectArgs, msg, err := taskProjectUpdate(userID.(int), req)
+	if err != nil {
+		log.Printf("Failed to check project and labels for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	updateFields = append(updateFields, projectFields...)
+	args = append(args, projectArgs...)
+
+	if len(updateFields) > 0 {
+		args = append(args, taskID)
+		query := fmt.Sprintf("UPDATE tasks SET %s WHERE id = ?", strings.Join(updateFields, ", "))
+		if _, err := database.DB.Exec(query, args...); err != nil {
+			log.Printf("Failed to update task %d: %v", taskID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+			return
+		}
+	}
+	if req.LabelIDs != nil {
+		if err := setTaskLabels(database.DB, taskID, *req.LabelIDs); err != nil {
+			log.Printf("Failed to set labels of task %d: %v", taskID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+			return
+		}
+	}
+
+	task, err = fetchTaskWithLabels(taskID, userID.(int))
+	if err != nil {
+		log.Printf("Failed to fetch task %d: %v", taskID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task"})
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// DeleteTask deletes a task together with its subtasks. For an instance of a recurring
+// task, ?scope=following ends the series before it and ?scope=all deletes the series;
+// deleting a template deletes its series. Completed instances the series no longer
+// covers are kept as plain tasks.
+func (h *TaskHandler) DeleteTask(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	task, err := fetchTask(taskID, userID.(int))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch task %d: %v", taskID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task"})
+		return
+	}
+
+	scope, msg := taskScope(c, task)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if scope == scopeThis {
+		// generated_until of the template is past this occurrence, so it isn't created again
+		if _, err := database.DB.Exec("DELETE FROM tasks WHERE id = ?", taskID); err != nil {
+			log.Printf("Failed to delete task %d: %v", taskID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
+		return
+	}
+
+	series, err := loadTaskSeries(task, userID.(int))
+	if err != nil {
+		log.Printf("Failed to load series of task %d: %v", taskID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
+		return
+	}
+	defer tx.Rollback()
+
+	split, err := series.splitDay(task, scope)
+	if err != nil {
+		log.Printf("Invalid occurrence of task %d: %v", taskID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
+		return
+	}
+	if split != nil {
+		_, err = tx.Exec(`
+			UPDATE tasks SET recurrence_rule = ? WHERE id = ?
+		`, series.endBefore(*split).String(), series.template.ID)
+		if err == nil {
+			_, err = tx.Exec(`
+				DELETE FROM tasks
+				WHERE parent_task_id = ? AND occurrence_date >= ? AND (completed_at IS NULL OR id = ?)
+			`, series.template.ID, split.Format(services.DateLayout), taskID)
+		}
+		if err == nil {
+			// Completed instances after the new end are no longer part of the series
+			_, err = tx.Exec(`
+				UPDATE tasks SET parent_task_id = NULL, updated_at = updated_at
+				WHERE parent_task_id = ? AND occurrence_date >= ? AND completed_at IS NOT NULL
+			`, series.template.ID, split.Format(services.DateLayout))
+		}
+	} else {
+		_, err = tx.Exec(`
+			UPDATE tasks SET parent_task_id = NULL, updated_at = updated_at
+			WHERE parent_task_id = ? AND completed_at IS NOT NULL
+		`, series.template.ID)
+		if err == nil {
+			// Open instances go with the template (ON DELETE CASCADE)
+			_, err = tx.Exec("DELETE FROM tasks WHERE id = ?", series.template.ID)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to delete series of task %d: %v", taskID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Recurring task deleted successfully"})
+}
+
+// Scopes of edits and deletions of recurring task instances
+const (
+	scopeThis      = "this"
+	scopeFollowing = "following"
+	scopeAll       = "all"
+)
+
+// taskScope reads ?scope for a task, or returns why it is invalid. Tasks that aren't
+// part of a series always have the scope "this", templates always "all".
+func taskScope(c *gin.Context, task models.Task) (string, string) {
+	scope := c.DefaultQuery("scope", scopeThis)
+	if scope != scopeThis && scope != scopeFollowing && scope != scopeAll {
+		return "", "scope must be this, following or all"
+	}
+	if task.IsRecurringTemplate {
+		return scopeAll, ""
+	}
+	if task.ParentTaskID == nil || task.OccurrenceDate == nil {
+		return scopeThis, ""
+	}
+	return scope, ""
+}
+
+// updateSeries applies an edit to the whole series of a recurring task, or splits the
+// series at task and applies it to the new series that starts there. A due_date moves the
+// series by as much as it moves task. Open instances are updated, those from today on are
+// created again when the schedule changes; completed instances are left alone.
+func (h *TaskHandler) updateSeries(c *gin.Context, userID int, task models.Task, scope string, req models.UpdateTaskRequest) {
+	if req.ParentID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "parent_id can only be changed for a single task"})
+		return
+	}
+	if req.ClearDueDate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A recurring task needs a due date"})
+		return
+	}
+	if req.Title != nil && strings.TrimSpace(*req.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title cannot be empty"})
+		return
+	}
+
+	series, err := loadTaskSeries(task, userID)
+	if err != nil {
+		log.Printf("Failed to load series of task %d: %v", task.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+	rule := series.rule
+	if req.RecurrenceRule != nil {
+		if rule, err = services.ParseRecurrenceRule(*req.RecurrenceRule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	split, err := series.splitDay(task, scope)
+	if err != nil {
+		log.Printf("Invalid occurrence of task %d: %v", task.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+
+	start := series.start
+	if split != nil {
+		start = series.occurrence(*split)
+		if req.RecurrenceRule == nil {
+			rule = series.continueAt(*split)
+		}
+	}
+	if req.DueDate != nil && task.DueDate != nil {
+		start = start.Add(req.DueDate.Sub(*task.DueDate))
+	}
+	rescheduled := req.DueDate != nil || req.RecurrenceRule != nil
+
+	// Fields shared by the template and its open instances
+	updateFields := []string{}
+	args := []interface{}{}
+	if req.Title != nil {
+		updateFields = append(updateFields, "title = ?")
+		args = append(args, *req.Title)
+	}
+	if req.Description != nil {
+		updateFields = append(updateFields, "description = ?")
+		args = append(args, *req.Description)
+	}
+	if req.Priority != nil {
+		updateFields = append(updateFields, "priority = ?")
+		args = append(args, *req.Priority)
+	}
+	projectFields, projectArgs, msg, err := taskProjectUpdate(userID, req)
+	if err != nil {
+		log.Printf("Failed to check project and labels for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	updateFields = append(updateFields, projectFields...)
+	args = append(args, projectArgs...)
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+	defer tx.Rollback()
+
+	templateID := series.template.ID
+	if split != nil {
+		templateID, err = splitSeries(tx, series, *split, start, rule, req)
+	} else {
+		err = rescheduleSeries(tx, series, start, rule, rescheduled, updateFields, args)
+	}
+	if err == nil && len(updateFields) > 0 {
+		query := fmt.Sprintf("UPDATE tasks SET %s WHERE parent_task_id = ? AND completed_at IS NULL", strings.Join(updateFields, ", "))
+		_, err = tx.Exec(query, append(args, templateID)...)
+	}
+	if err == nil && req.LabelIDs != nil {
+		err = setSeriesLabels(tx, templateID, *req.LabelIDs)
+	}
+	if err != nil {
+		log.Printf("Failed to update series of task %d: %v", task.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+
+	if err := services.MaterializeRecurringTasks(userID); err != nil {
+		log.Printf("Failed to create recurring task instances for user %d: %v", userID, err)
+	}
+	template, err := fetchTaskWithLabels(templateID, userID)
+	if err != nil {
+		log.Printf("Failed to fetch task %d: %v", templateID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Recurring task updated successfully", "template": template})
+}
+
+// rescheduleSeries updates the template of a series. When its schedule changed, the open
+// instances from today on are removed so they are created again on the new schedule.
+func rescheduleSeries(tx *sql.Tx, series taskSeries, start time.Time, rule services.RecurrenceRule, rescheduled bool, updateFields []string, args []interface{}) error {
+	fields := append([]string{"due_date = ?", "recurrence_rule = ?"}, updateFields...)
+	values := append([]interface{}{start, rule.String()}, args...)
+	if rescheduled {
+		today := services.Today(series.start.Location())
+		_, err := tx.Exec(`
+			DELETE FROM tasks WHERE parent_task_id = ? AND completed_at IS NULL AND occurrence_date >= ?
+		`, series.template.ID, today.Format(services.DateLayout))
+		if err != nil {
+			return err
+		}
+		fields = append(fields, "generated_until = ?")
+		values = append(values, today.AddDate(0, 0, -1).Format(services.DateLayout))
+	}
+	query := fmt.Sprintf("UPDATE tasks SET %s WHERE id = ?", strings.Join(fields, ", "))
+	_, err := tx.Exec(query, append(values, series.template.ID)...)
+	return err
+}
+
+// splitSeries ends a series before split and starts a new one there with the edited
+// fields. Completed instances from split on move to the new series, open ones are
+// created again by it. It returns the ID of the new template.
+func splitSeries(tx *sql.Tx, series taskSeries, split, start time.Time, rule services.RecurrenceRule, req models.UpdateTaskRequest) (int, error) {
+	t := series.template
+	title, description, priority := t.Title, t.Description, t.Priority
+	if req.Title != nil {
+		title = *req.Title
+	}
+	if req.Description != nil {
+		description = *req.Description
+	}
+	if req.Priority != nil {
+		priority = *req.Priority
+	}
+	projectID := t.ProjectID
+	if req.ProjectID != nil {
+		if projectID = req.ProjectID; *projectID == 0 {
+			projectID = nil
+		}
+	}
+
+	_, err := tx.Exec("UPDATE tasks SET recurrence_rule = ? WHERE id = ?", series.endBefore(split).String(), t.ID)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(`
+		INSERT INTO tasks (user_id, title, description, priority, due_date, parent_id, position, project_id, is_recurring_template, recurrence_rule)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, TRUE, ?)
+	`, t.UserID, title, description, priority, start, t.ParentID, t.Position, projectID, rule.String())
+	if err != nil {
+		return 0, err
+	}
+	newID, _ := result.LastInsertId()
+	if req.LabelIDs == nil {
+		_, err = tx.Exec(`
+			INSERT INTO task_labels (task_id, label_id)
+			SELECT ?, label_id FROM task_labels WHERE task_id = ?
+		`, newID, t.ID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	day := split.Format(services.DateLayout)
+	_, err = tx.Exec(`
+		UPDATE tasks SET parent_task_id = ?, updated_at = updated_at
+		WHERE parent_task_id = ? AND occurrence_date >= ? AND completed_at IS NOT NULL
+	`, newID, t.ID, day)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(`
+		DELETE FROM tasks WHERE parent_task_id = ? AND occurrence_date >= ? AND completed_at IS NULL
+	`, t.ID, day)
+	return int(newID), err
+}
+
+// taskSeries is a recurring task template with its parsed rule
+type taskSeries struct {
+	template models.Task
+	rule     services.RecurrenceRule
+	start    time.Time // first occurrence, in the user's time zone
+}
+
+// loadTaskSeries loads the series a template or one of its instances belongs to
+func loadTaskSeries(task models.Task, userID int) (taskSeries, error) {
+	template := task
+	if !task.IsRecurringTemplate {
+		if task.ParentTaskID == nil {
+			return taskSeries{}, fmt.Errorf("task %d is not part of a series", task.ID)
+		}
+		var err error
+		if template, err = fetchTask(*task.ParentTaskID, userID); err != nil {
+			return taskSeries{}, err
+		}
+	}
+	if template.RecurrenceRule == nil || template.DueDate == nil {
+		return taskSeries{}, fmt.Errorf("task %d has no recurrence rule", template.ID)
+	}
+	rule, err := services.ParseRecurrenceRule(*template.RecurrenceRule)
+	if err != nil {
+		return taskSeries{}, err
+	}
+	return taskSeries{template: template, rule: rule, start: template.DueDate.In(services.UserLocation(userID))}, nil
+}
+
+// splitDay returns the day a "following" edit of task splits the series at, or nil when
+// the whole series is affected: for scope "all", or when task is its first occurrence
+func (s taskSeries) splitDay(task models.Task, scope string) (*time.Time, error) {
+	if scope != scopeFollowing || task.OccurrenceDate == nil {
+		return nil, nil
+	}
+	day, err := time.ParseInLocation(services.DateLayout, *task.OccurrenceDate, s.start.Location())
+	if err != nil {
+		return nil, err
+	}
+	if s.occurrencesBefore(day) == 0 {
+		return nil, nil
+	}
+	return &day, nil
+}
+
+// occurrence returns the due date the series gives to its occurrence on day
+func (s taskSeries) occurrence(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), s.start.Hour(), s.start.Minute(), s.start.Second(), 0, s.start.Location())
+}
+
+// occurrencesBefore counts the occurrences of the series before day
+func (s taskSeries) occurrencesBefore(day time.Time) int {
+	first := services.StartOfDay(s.start, s.start.Location())
+	return len(s.rule.Occurrences(s.start, first, day.AddDate(0, 0, -1)))
+}
+
+// continueAt returns the rule of a series split off at day; it continues the count of
+// this one, so both together have as many occurrences as before
+func (s taskSeries) continueAt(day time.Time) services.RecurrenceRule {
+	rule := s.rule
+	if rule.Count > 0 {
+		if rule.Count -= s.occurrencesBefore(day); rule.Count < 1 {
+			rule.Count = 1
+		}
+	}
+	return rule
+}
+
+// endBefore returns the rule of the series ending with the last occurrence before day
+func (s taskSeries) endBefore(day time.Time) services.RecurrenceRule {
+	rule := s.rule
+	until := day.AddDate(0, 0, -1)
+	rule.Count = 0
+	rule.Until = &until
+	return rule
+}
+
+// taskRecurrenceRule returns the rule of a new task, nil if it doesn't recur: its
+// recurrence_rule, or the weekly interval and optional end date of is_recurring
+func taskRecurrenceRule(req models.CreateTaskRequest) (*services.RecurrenceRule, error) {
+	if req.RecurrenceRule != "" {
+		rule, err := services.ParseRecurrenceRule(req.RecurrenceRule)
+		if err != nil {
+			return nil, err
+		}
+		return &rule, nil
+	}
+	if !req.IsRecurring {
+		return nil, nil
+	}
+	if req.RecurrenceIntervalWeeks == nil || *req.RecurrenceIntervalWeeks < 1 || *req.RecurrenceIntervalWeeks > 999 {
+		return nil, fmt.Errorf("recurring tasks need a recurrence_rule or recurrence_interval_weeks between 1 and 999")
+	}
+	rule := services.RecurrenceRule{Freq: services.FreqWeekly, Interval: *req.RecurrenceIntervalWeeks}
+	if req.RecurrenceEndDate != nil {
+		until := *req.RecurrenceEndDate
+		rule.Until = &until
+	}
+	return &rule, nil
+}
+
+// fetchSeriesInstances loads the instances of a recurring task, oldest first
+func fetchSeriesInstances(templateID, userID int) ([]models.Task, error) {
+	rows, err := database.DB.Query(`
+		SELECT `+taskColumns+`
+		FROM tasks WHERE parent_task_id = ? AND user_id = ?
+		ORDER BY occurrence_date`, templateID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tasks, loadTaskLabels(tasks)
+}
+
+// ReorderTasks sets the manual order of the tasks under one parent. The listed tasks
+// come first in the given order, siblings that weren't listed keep their order after them.
+func (h *TaskHandler) ReorderTasks(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.ReorderTasksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := "SELECT id FROM tasks WHERE user_id = ? AND parent_id IS NULL AND is_recurring_template = FALSE ORDER BY position, id"
+	args := []interface{}{userID}
+	if req.ParentID != nil {
+		query = "SELECT id FROM tasks WHERE user_id = ? AND parent_id = ? AND is_recurring_template = FALSE ORDER BY position, id"
+		args = append(args, *req.ParentID)
+	}
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Failed to fetch sibling tasks for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder tasks"})
+		return
+	}
+	var siblings []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder tasks"})
+			return
+		}
+		siblings = append(siblings, id)
+	}
+	rows.Close()
+
+	isSibling := make(map[int]bool, len(siblings))
+	for _, id := range siblings {
+		isSibling[id] = true
+	}
+	listed := make(map[int]bool, len(req.TaskIDs))
+	for _, id := range req.TaskIDs {
+		if !isSibling[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Task %d is not a child of the given parent", id)})
+			return
+		}
+		if listed[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Task %d is listed twice", id)})
+			return
+		}
+		listed[id] = true
+	}
+	order := append([]int{}, req.TaskIDs...)
+	for _, id := range siblings {
+		if !listed[id] {
+			order = append(order, id)
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder tasks"})
+		return
+	}
+	defer tx.Rollback()
+	for position, id := range order {
+		if _, err := tx.Exec("UPDATE tasks SET position = ?, updated_at = updated_at WHERE id = ?", position, id); err != nil {
+			log.Printf("Failed to reorder task %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder tasks"})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder tasks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tasks reordered successfully", "task_ids": order})
+}
+
+// nextTaskPosition returns the position after the last child of parentID (nil for top level tasks)
+func nextTaskPosition(userID int, parentID *int) (int, error) {
+	query := "SELECT COALESCE(MAX(position) + 1, 0) FROM tasks WHERE user_id = ? AND parent_id IS NULL"
+	args := []interface{}{userID}
+	if parentID != nil {
+		query = "SELECT COALESCE(MAX(position) + 1, 0) FROM tasks WHERE user_id = ? AND parent_id = ?"
+		args = append(args, *parentID)
+	}
+	var position int
+	err := database.DB.QueryRow(query, args...).Scan(&position)
+	return position, err
+}
+
+// sameTaskParent reports whether two optional parent IDs are the same
+func sameTaskParent(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// checkTaskParent returns why taskID can't be moved under parentID, or "" if it can.
+// The error is set when the parent couldn't be checked.
+func checkTaskParent(userID, taskID, parentID int) (string, error) {
+	// Walk up from the new parent; reaching the task itself would create a cycle
+	for id := &parentID; id != nil; {
+		if *id == taskID {
+			return "A task can't be moved under itself or one of its subtasks", nil
+		}
+		parent, err := fetchTask(*id, userID)
+		if err == sql.ErrNoRows {
+			return "Parent task not found", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch task %d: %v", *id, err)
+		}
+		if parent.IsRecurringTemplate {
+			return "Subtasks belong to an occurrence of a recurring task, not to the series", nil
+		}
+		id = parent.ParentID
+	}
+	return "", nil
+}
+
+// taskDescendants returns the IDs of all subtasks below taskID, at any depth
+func taskDescendants(userID, taskID int) ([]int, error) {
+	rows, err := database.DB.Query("SELECT id, parent_id FROM tasks WHERE user_id = ? AND parent_id IS NOT NULL", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	children := make(map[int][]int)
+	for rows.Next() {
+		var id, parentID int
+		if err := rows.Scan(&id, &parentID); err != nil {
+			return nil, err
+		}
+		children[parentID] = append(children[parentID], id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var descendants []int
+	queue := []int{taskID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		descendants = append(descendants, children[id]...)
+		queue = append(queue, children[id]...)
+	}
+	return descendants, nil
+}
+
+// CompleteTask toggles the completion of a task. With ?cascade=true, completing a
+// task also completes its open subtasks.
+func (h *TaskHandler) CompleteTask(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	taskIDStr := c.Param("id")
+	taskID, err := strconv.Atoi(taskIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	// Check if task belongs to user
+	var task models.Task
+	err = database.DB.QueryRow(`
+		SELECT id, user_id, completed_at, is_recurring_template FROM tasks WHERE id = ? AND user_id = ?
+	`, taskID, userID).Scan(&task.ID, &task.UserID, &task.CompletedAt, &task.IsRecurringTemplate)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if task.IsRecurringTemplate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Complete an occurrence of a recurring task instead"})
+		return
+	}
+
+	// Toggle completion status
+	var completedAt *time.Time
+	if task.CompletedAt == nil {
+		now := time.Now()
+		completedAt = &now
+	}
+
+	ids := []int{taskID}
+	if completedAt != nil && c.Query("cascade") == "true" {
+		descendants, err := taskDescendants(userID.(int), taskID)
+		if err != nil {
+			log.Printf("Failed to fetch subtasks of task %d: %v", taskID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+			return
+		}
+		ids = append(ids, descendants...)
+	}
+
+	placeholders := strings.Repeat("?,", len(ids))
+	placeholders = placeholders[:len(placeholders)-1]
+	args := append([]interface{}{completedAt, taskID}, convertIntsToInterface(ids)...)
+	// Subtasks completed earlier keep their completion time
+	_, err = database.DB.Exec(fmt.Sprintf(`
+		UPDATE tasks SET completed_at = ?, updated_at = NOW()
+		WHERE (id = ? OR completed_at IS NULL) AND id IN (%s)
+	`, placeholders), args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+	if err := syncChecklistItems(ids); err != nil {
+		log.Printf("Failed to sync checklist items of task %d: %v", taskID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task updated successfully", "completed": completedAt != nil})
+}
+
+// syncChecklistItems checks or unchecks the checklist items linked to tasks as the
+// tasks are completed
+func syncChecklistItems(taskIDs []int) error {
+	placeholders := strings.Repeat("?,", len(taskIDs))
+	placeholders = placeholders[:len(placeholders)-1]
+	_, err := database.DB.Exec(fmt.Sprintf(`
+		UPDATE checklist_items ci
+		INNER JOIN tasks t ON t.checklist_item_id = ci.id
+		SET ci.is_checked = (t.completed_at IS NOT NULL), ci.updated_at = NOW()
+		WHERE t.id IN (%s) AND ci.is_checked != (t.completed_at IS NOT NULL)
+	`, placeholders), convertIntsToInterface(taskIDs)...)
+	return err
+}
+
+// ProjectHandler handles project endpoints
+type ProjectHandler struct{}
+
+// NewProjectHandler creates a new project handler
+func NewProjectHandler() *ProjectHandler {
+	return &ProjectHandler{}
+}
+
+// projectQuery selects projects with their task counts; conditions on p follow
+var projectQuery = `
+	SELECT p.id, p.user_id, p.name, p.description, p.color, p.archived_at, p.created_at, p.updated_at,
+	       COUNT(t.id), COALESCE(SUM(t.completed_at IS NOT NULL), 0)
+	FROM projects p
+	LEFT JOIN tasks t ON t.project_id = p.id AND t.is_recurring_template = FALSE AND ` + services.NextOpenInstance("t") + `
+	WHERE p.user_id = ?`
+
+// scanProject reads a project selected with projectQuery and computes its progress
+func scanProject(row rowScanner) (models.Project, error) {
+	var project models.Project
+	var description, color sql.NullString
+	err := row.Scan(&project.ID, &project.UserID, &project.Name, &description, &color, &project.ArchivedAt,
+		&project.CreatedAt, &project.UpdatedAt, &project.TaskCount, &project.CompletedCount)
+	project.Description = description.String
+	project.Color = color.String
+	project.IsArchived = project.ArchivedAt != nil
+	if project.TaskCount > 0 {
+		project.Progress = math.Round(float64(project.CompletedCount)/float64(project.TaskCount)*1000) / 10
+	}
+	return project, err
+}
+
+// fetchProject loads a project of the user with its progress
+func fetchProject(projectID, userID int) (models.Project, error) {
+	return scanProject(database.DB.QueryRow(projectQuery+" AND p.id = ? GROUP BY p.id", userID, projectID))
+}
+
+// GetProjects returns the user's active projects, or the archived ones with ?archived=true
+func (h *ProjectHandler) GetProjects(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	condition := " AND p.archived_at IS NULL"
+	if c.Query("archived") == "true" {
+		condition = " AND p.archived_at IS NOT NULL"
+	}
+	rows, err := database.DB.Query(projectQuery+condition+" GROUP BY p.id ORDER BY p.name", userID)
+	if err != nil {
+		log.Printf("Failed to query projects for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch projects"})
+		return
+	}
+	defer rows.Close()
+
+	projects := []models.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			log.Printf("Failed to scan project: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch projects"})
+			return
+		}
+		projects = append(projects, project)
+	}
+
+	c.JSON(http.StatusOK, projects)
+}
+
+// GetProject returns a single project with its progress
+func (h *ProjectHandler) GetProject(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	respondWithProject(c, projectID, userID.(int), http.StatusOK)
+}
+
+// CreateProject creates a new project
+func (h *ProjectHandler) CreateProject(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.CreateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO projects (user_id, name, description, color) VALUES (?, ?, ?, ?)
+	`, userID, req.Name, req.Description, req.Color)
+	if err != nil {
+		log.Printf("Failed to create project for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
+		return
+	}
+	projectID, _ := result.LastInsertId()
+
+	respondWithProject(c, int(projectID), userID.(int), http.StatusCreated)
+}
+
+// UpdateProject updates a project
+func (h *ProjectHandler) UpdateProject(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var req models.UpdateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updateFields := []string{}
+	args := []interface{}{}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+			return
+		}
+		updateFields = append(updateFields, "name = ?")
+		args = append(args, *req.Name)
+	}
+	if req.Description != nil {
+		updateFields = append(updateFields, "description = ?")
+		args = append(args, *req.Description)
+	}
+	if req.Color != nil {
+		updateFields = append(updateFields, "color = ?")
+		args = append(args, *req.Color)
+	}
+
+	if len(updateFields) > 0 {
+		args = append(args, projectID, userID)
+		query := fmt.Sprintf("UPDATE projects SET %s WHERE id = ? AND user_id = ?", strings.Join(updateFields, ", "))
+		if _, err := database.DB.Exec(query, args...); err != nil {
+			log.Printf("Failed to update project %d: %v", projectID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+			return
+		}
+	}
+
+	respondWithProject(c, projectID, userID.(int), http.StatusOK)
+}
+
+// DeleteProject deletes a project; its tasks are kept without project
+func (h *ProjectHandler) DeleteProject(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM projects WHERE id = ? AND user_id = ?", projectID, userID)
+	if err != nil {
+		log.Printf("Failed to delete project %d: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
+}
+
+// ArchiveProject hides a project from the project list; its tasks stay unchanged
+func (h *ProjectHandler) ArchiveProject(c *gin.Context) {
+	h.changeArchiveState(c, true)
+}
+
+// UnarchiveProject restores an archived project
+func (h *ProjectHandler) UnarchiveProject(c *gin.Context) {
+	h.changeArchiveState(c, false)
+}
+
+func (h *ProjectHandler) changeArchiveState(c *gin.Context, archive bool) {
+	userID, _ := c.Get("user_id")
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	query := "UPDATE projects SET archived_at = NULL WHERE id = ? AND user_id = ?"
+	if archive {
+		query = "UPDATE projects SET archived_at = COALESCE(archived_at, NOW()) WHERE id = ? AND user_id = ?"
+	}
+	if _, err := database.DB.Exec(query, projectID, userID); err != nil {
+		log.Printf("Failed to change archive state of project %d: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+		return
+	}
+
+	respondWithProject(c, projectID, userID.(int), http.StatusOK)
+}
+
+// respondWithProject writes a project of the user with its progress
+func respondWithProject(c *gin.Context, projectID, userID, status int) {
+	project, err := fetchProject(projectID, userID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch project %d: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch project"})
+		return
+	}
+	c.JSON(status, project)
+}
+
+// taskProjectUpdate returns the update of project_id requested by an edit, after
+// checking the new project and labels; msg tells why they can't be used and err that
+// they couldn't be checked. A project ID of 0 removes the task from its project.
+func taskProjectUpdate(userID int, req models.UpdateTaskRequest) (fields []string, args []interface{}, msg string, err error) {
+	if req.LabelIDs != nil {
+		if msg, err := checkLabels(userID, *req.LabelIDs); msg != "" || err != nil {
+			return nil, nil, msg, err
+		}
+	}
+	if req.ProjectID == nil {
+		return nil, nil, "", nil
+	}
+	if *req.ProjectID == 0 {
+		return []string{"project_id = NULL"}, nil, "", nil
+	}
+	if msg, err := checkTaskProject(userID, *req.ProjectID); msg != "" || err != nil {
+		return nil, nil, msg, err
+	}
+	return []string{"project_id = ?"}, []interface{}{*req.ProjectID}, "", nil
+}
+
+// checkTaskProject returns why a task can't be put into the project, or "" if it can.
+// The error is set when the project couldn't be checked.
+func checkTaskProject(userID, projectID int) (string, error) {
+	var archivedAt sql.NullTime
+	err := database.DB.QueryRow(`
+		SELECT archived_at FROM projects WHERE id = ? AND user_id = ?
+	`, projectID, userID).Scan(&archivedAt)
+	if err == sql.ErrNoRows {
+		return "Project not found", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch project %d: %v", projectID, err)
+	}
+	if archivedAt.Valid {
+		return "Project is archived", nil
+	}
+	return "", nil
+}
+
+// LabelHandler handles label endpoints
+type LabelHandler struct{}
+
+// NewLabelHandler creates a new label handler
+func NewLabelHandler() *LabelHandler {
+	return &LabelHandler{}
+}
+
+// GetLabels returns the user's labels
+func (h *LabelHandler) GetLabels(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	rows, err := database.DB.Query(`
+		SELECT id, user_id, name, color, created_at FROM labels WHERE user_id = ? ORDER BY name
+	`, userID)
+	if err != nil {
+		log.Printf("Failed to query labels for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch labels"})
+		return
+	}
+	defer rows.Close()
+
+	labels := []models.Label{}
+	for rows.Next() {
+		label, err := scanLabel(rows)
+		if err != nil {
+			log.Printf("Failed to scan label: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch labels"})
+			return
+		}
+		labels = append(labels, label)
+	}
+
+	c.JSON(http.StatusOK, labels)
+}
+
+// CreateLabel creates a new label
+func (h *LabelHandler) CreateLabel(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.CreateLabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO labels (user_id, name, color) VALUES (?, ?, ?)
+	`, userID, name, req.Color)
+	if database.IsDuplicateKey(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A label with this name already exists"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create label for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create label"})
+		return
+	}
+	labelID, _ := result.LastInsertId()
+
+	label, err := fetchLabel(int(labelID), userID.(int))
+	if err != nil {
+		log.Printf("Failed to fetch label %d: %v", labelID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch label"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, label)
+}
+
+// UpdateLabel renames or recolors a label
+func (h *LabelHandler) UpdateLabel(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	labelID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid label ID"})
+		return
+	}
+
+	var req models.UpdateLabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updateFields := []string{}
+	args := []interface{}{}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+			return
+		}
+		updateFields = append(updateFields, "name = ?")
+		args = append(args, name)
+	}
+	if req.Color != nil {
+		updateFields = append(updateFields, "color = ?")
+		args = append(args, *req.Color)
+	}
+
+	if len(updateFields) > 0 {
+		args = append(args, labelID, userID)
+		query := fmt.Sprintf("UPDATE labels SET %s WHERE id = ? AND user_id = ?", strings.Join(updateFields, ", "))
+		_, err := database.DB.Exec(query, args...)
+		if database.IsDuplicateKey(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A label with this name already exists"})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to update label %d: %v", labelID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update label"})
+			return
+		}
+	}
+
+	label, err := fetchLabel(labelID, userID.(int))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Label not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch label %d: %v", labelID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch label"})
+		return
+	}
+
+	c.JSON(http.StatusOK, label)
+}
+
+// DeleteLabel deletes a label and removes it from all tasks
+func (h *LabelHandler) DeleteLabel(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	labelID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid label ID"})
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM labels WHERE id = ? AND user_id = ?", labelID, userID)
+	if err != nil {
+		log.Printf("Failed to delete label %d: %v", labelID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete label"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Label not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Label deleted successfully"})
+}
+
+func scanLabel(row rowScanner) (models.Label, error) {
+	var label models.Label
+	var color sql.NullString
+	err := row.Scan(&label.ID, &label.UserID, &label.Name, &color, &label.CreatedAt)
+	label.Color = color.String
+	return label, err
+}
+
+func fetchLabel(labelID, userID int) (models.Label, error) {
+	return scanLabel(database.DB.QueryRow(`
+		SELECT id, user_id, name, color, created_at FROM labels WHERE id = ? AND user_id = ?
+	`, labelID, userID))
+}
+
+// checkLabels returns why labelIDs can't be put on a task, or "" if they can. The error
+// is set when the labels couldn't be checked.
+func checkLabels(userID int, labelIDs []int) (string, error) {
+	seen := make(map[int]bool)
+	for _, labelID := range labelIDs {
+		if seen[labelID] {
+			return fmt.Sprintf("Label %d is listed twice", labelID), nil
+		}
+		seen[labelID] = true
+		if _, err := fetchLabel(labelID, userID); err == sql.ErrNoRows {
+			return fmt.Sprintf("Label %d not found", labelID), nil
+		} else if err != nil {
+			return "", fmt.Errorf("failed to fetch label %d: %v", labelID, err)
+		}
+	}
+	return "", nil
+}
+
+// NotificationHandler handles the in-app inbox and Web Push subscriptions
+type NotificationHandler struct {
+	dispatcher *services.NotificationDispatcher
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(dispatcher *services.NotificationDispatcher) *NotificationHandler {
+	return &NotificationHandler{dispatcher: dispatcher}
+}
+
+// GetNotifications returns the user's notifications, newest first, and how many are
+// unread. ?unread=true returns only unread ones, ?limit caps the list (50, at most 200).
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+		limit = n
+	}
+	condition := ""
+	if c.Query("unread") == "true" {
+		condition = " AND read_at IS NULL"
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, kind, title, body, url, read_at, created_at
+		FROM notifications
+		WHERE user_id = ?`+condition+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		log.Printf("Failed to query notifications for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		var body, link sql.NullString
+		var readAt sql.NullTime
+		if err := rows.Scan(&n.ID, &n.Kind, &n.Title, &body, &link, &readAt, &n.CreatedAt); err != nil {
+			log.Printf("Failed to scan notification: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+			return
+		}
+		n.Body = body.String
+		n.URL = link.String
+		if readAt.Valid {
+			n.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, n)
+	}
+
+	var unread int
+	err = database.DB.QueryRow(`
+		SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL
+	`, userID).Scan(&unread)
+	if err != nil {
+		log.Printf("Failed to count unread notifications for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"unread_count":  unread,
+	})
+}
+
+// MarkNotificationRead marks one notification as read
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	notificationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	var exists bool
+	err = database.DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM notifications WHERE id = ? AND user_id = ?)
+	`, notificationID, userID).Scan(&exists)
+	if err != nil {
+		log.Printf("Failed to fetch notification %d: %v", notificationID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE notifications SET read_at = NOW() WHERE id = ? AND read_at IS NULL
+	`, notificationID)
+	if err != nil {
+		log.Printf("Failed to mark notification %d as read: %v", notificationID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
+
+// MarkAllNotificationsRead marks all of the user's notifications as read
+func (h *NotificationHandler) MarkAllNotificationsRead(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	result, err := database.DB.Exec(`
+		UPDATE notifications SET read_at = NOW() WHERE user_id = ? AND read_at IS NULL
+	`, userID)
+	if err != nil {
+		log.Printf("Failed to mark notifications of user %v as read: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+	marked, _ := result.RowsAffected()
+
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
+
+// GetPushKey returns the VAPID public key the frontend passes to pushManager.subscribe
+func (h *NotificationHandler) GetPushKey(c *gin.Context) {
+	key := h.dispatcher.PushPublicKey()
+	if key == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Web Push is not configured"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"public_key": key})
+}
+
+// CreatePushSubscription registers a browser for Web Push. An endpoint registered
+// before, possibly by another account on the same browser, moves to the user.
+func (h *NotificationHandler) CreatePushSubscription(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.PushSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if h.dispatcher.PushPublicKey() == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Web Push is not configured"})
+		return
+	}
+	if err := services.ValidatePushKeys(req.Keys.P256dh, req.Keys.Auth); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := services.ValidatePushEndpoint(req.Endpoint); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err := database.DB.Exec(`
+		INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE user_id = VALUES(user_id), p256dh = VALUES(p256dh), auth = VALUES(auth)
+	`, userID, req.Endpoint, req.Keys.P256dh, req.Keys.Auth)
+	if err != nil {
+		log.Printf("Failed to save push subscription of user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save push subscription"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Push subscription saved"})
+}
+
+// DeletePushSubscription unregisters a browser from Web Push
+func (h *NotificationHandler) DeletePushSubscription(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.DeletePushSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		DELETE FROM push_subscriptions WHERE endpoint = ? AND user_id = ?
+	`, req.Endpoint, userID)
+	if err != nil {
+		log.Printf("Failed to delete push subscription of user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete push subscription"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Push subscription not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Push subscription deleted"})
+}
+
+// sqlExecer is a *sql.DB or *sql.Tx
+type sqlExecer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// setTaskLabels replaces the labels of a task
+func setTaskLabels(db sqlExecer, taskID int, labelIDs []int) error {
+	if _, err := db.Exec("DELETE FROM task_labels WHERE task_id = ?", taskID); err != nil {
+		return err
+	}
+	for _, labelID := range labelIDs {
+		if _, err := db.Exec("INSERT INTO task_labels (task_id, label_id) VALUES (?, ?)", taskID, labelID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setSeriesLabels replaces the labels of a recurring task and its open instances
+func setSeriesLabels(tx *sql.Tx, templateID int, labelIDs []int) error {
+	if err := setTaskLabels(tx, templateID, labelIDs); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`
+		DELETE tl FROM task_labels tl
+		INNER JOIN tasks t ON t.id = tl.task_id
+		WHERE t.parent_task_id = ? AND t.completed_at IS NULL
+	`, templateID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO task_labels (task_id, label_id)
+		SELECT t.id, tl.label_id FROM tasks t
+		INNER JOIN task_labels tl ON tl.task_id = ?
+		WHERE t.parent_task_id = ? AND t.completed_at IS NULL
+	`, templateID, templateID)
+	return err
+}
+
+// loadTaskLabels fills in the labels of tasks
+func loadTaskLabels(tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	index := make(map[int]int, len(tasks))
+	ids := make([]int, len(tasks))
+	for i := range tasks {
+		tasks[i].Labels = []models.Label{}
+		index[tasks[i].ID] = i
+		ids[i] = tasks[i].ID
+	}
+
+	placeholders := strings.Repeat("?,", len(ids))
+	placeholders = placeholders[:len(placeholders)-1]
+	rows, err := database.DB.Query(fmt.Sprintf(`
+		SELECT tl.task_id, l.id, l.user_id, l.name, l.color, l.created_at
+		FROM task_labels tl
+		INNER JOIN labels l ON l.id = tl.label_id
+		WHERE tl.task_id IN (%s)
+		ORDER BY l.name
+	`, placeholders), convertIntsToInterface(ids)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var taskID int
+		var label models.Label
+		var color sql.NullString
+		if err := rows.Scan(&taskID, &label.ID, &label.UserID, &label.Name, &color, &label.CreatedAt); err != nil {
+			return err
+		}
+		label.Color = color.String
+		tasks[index[taskID]].Labels = append(tasks[index[taskID]].Labels, label)
+	}
+	return rows.Err()
+}
+
+// JournalHandler handles journal entry endpoints
+type JournalHandler struct {
+	summarizer *services.JournalSummarizer
+}
+
+// NewJournalHandler creates a new journal handler
+func NewJournalHandler(summarizer *services.JournalSummarizer) *JournalHandler {
+	return &JournalHandler{summarizer: summarizer}
+}
+
+// GetJournalEntries returns all journal entries for the authenticated user
+func (h *JournalHandler) GetJournalEntries(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	rows, err := database.DB.Query(`
+		SELECT `+journalEntryColumns+`
+		FROM journal_entries WHERE user_id = ?
+		ORDER BY entry_date DESC
+	`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch journal entries"})
+		return
+	}
+	defer rows.Close()
+
+	var entries []models.JournalEntry
+	for rows.Next() {
+		entry, err := scanJournalEntry(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan journal entry"})
+			return
+		}
+		entries = append(entries, entry)
+	}
+	if err := loadJournalTags(entries); err != nil {
+		log.Printf("Failed to load journal tags for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch journal entries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// GetJournalEntryByDate returns a journal entry for a specific date.
+// "today" is the current day in the user's time zone.
+func (h *JournalHandler) GetJournalEntryByDate(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	date := c.Param("date")
+	if date == "today" {
+		date = services.Today(services.UserLocation(userID.(int))).Format(services.DateLayout)
+	} else if _, err := time.Parse(services.DateLayout, date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return
+	}
+
+	entry, err := scanJournalEntry(database.DB.QueryRow(`
+		SELECT `+journalEntryColumns+`
+		FROM journal_entries WHERE user_id = ? AND DATE(entry_date) = ?
+	`, userID, date))
+
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Journal entry not found"})
+		return
+	}
+	if err == nil {
+		entries := []models.JournalEntry{entry}
+		err = loadJournalTags(entries)
+		entry = entries[0]
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch journal entry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// CreateOrUpdateJournalEntry creates or updates a journal entry
+func (h *JournalHandler) CreateOrUpdateJournalEntry(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.CreateJournalEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Received journal entry request: EntryDate=%v, Mood=%s, Content length=%d, Tags=%s", req.EntryDate, req.Mood, len(req.Content), req.Tags)
+
+	// The entry is for the calendar day the client sent, whatever offset it was sent with
+	entryDate := req.EntryDate.Format(services.DateLayout)
+
+	// Answers are validated against the entry's template; without one there are none
+	var templateID, fields, fieldsText interface{}
+	if req.TemplateID != nil {
+		template, err := fetchJournalTemplate(userID.(int), *req.TemplateID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Journal template not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to fetch journal template %d: %v", *req.TemplateID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save journal entry"})
+			return
+		}
+		answers, text, err := journalFieldColumns(template, req.Fields)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		templateID, fields, fieldsText = template.ID, answers, text
+	} else if len(req.Fields) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fields need a template_id"})
+		return
+	}
+
+	// Check if entry already exists for this date
+	var existingID int
+	err := database.DB.QueryRow(`
+		SELECT id FROM journal_entries WHERE user_id = ? AND entry_date = ?
+	`, userID, entryDate).Scan(&existingID)
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing entry"})
+		return
+	}
+	created := err == sql.ErrNoRows
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save journal entry"})
+		return
+	}
+	defer tx.Rollback()
+
+	entryID := existingID
+	if created {
+		log.Printf("Creating new entry for user %d", userID)
+		result, err := tx.Exec(`
+			INSERT INTO journal_entries (user_id, entry_date, mood, content, template_id, fields, fields_text)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, userID, entryDate, req.Mood, req.Content, templateID, fields, fieldsText)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid habit_id"})
+			log.Printf("Failed to create journal entry: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create journal entry: %v", err)})
 			return
 		}
-		// Check if habit belongs to user
-		var habit models.Habit
-		err = database.DB.QueryRow(`
-			SELECT id FROM habits WHERE id = ? AND user_id = ?
-		`, habitID, userID).Scan(&habit.ID)
+		id, _ := result.LastInsertId()
+		entryID = int(id)
+	} else {
+		log.Printf("Updating existing entry %d for user %d", existingID, userID)
+		_, err := tx.Exec(`
+			UPDATE journal_entries 
+			SET mood = ?, content = ?, template_id = ?, fields = ?, fields_text = ?, updated_at = NOW()
+			WHERE id = ?
+		`, req.Mood, req.Content, templateID, fields, fieldsText, existingID)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Habit not found"})
+			log.Printf("Failed to update journal entry: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update journal entry: %v", err)})
 			return
 		}
-		
-		rows, err = database.DB.Query(`
-			SELECT DATE(completed_at) as completion_date, COUNT(*) as count
-			FROM habit_completions
-			WHERE habit_id = ? AND user_id = ? 
-			AND DATE(completed_at) >= ? AND DATE(completed_at) <= ?
-			GROUP BY DATE(completed_at)
-		`, habitID, userID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
-	} else {
-		// Get completions for all habits of the user
-		rows, err = database.DB.Query(`
-			SELECT hc.habit_id, DATE(hc.completed_at) as completion_date
-			FROM habit_completions hc
-			INNER JOIN habits h ON hc.habit_id = h.id
-			WHERE hc.user_id = ? 
-			AND h.user_id = ?
-			AND DATE(hc.completed_at) >= ? AND DATE(hc.completed_at) <= ?
-			ORDER BY hc.completed_at
-		`, userID, userID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 	}
-	
+
+	if err := setJournalTags(tx, userID.(int), entryID, parseJournalTags(req.Tags)); err != nil {
+		log.Printf("Failed to set tags of journal entry %d: %v", entryID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save journal entry"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save journal entry"})
+		return
+	}
+
+	entry, err := fetchJournalEntry(entryID)
 	if err != nil {
-		log.Printf("Failed to query habit completions: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch completions"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated entry"})
 		return
 	}
-	defer rows.Close()
-	
-	type CompletionData struct {
-		HabitID        *int   `json:"habit_id,omitempty"`
-		CompletionDate string `json:"completion_date"`
+
+	if created {
+		c.JSON(http.StatusCreated, entry)
+	} else {
+		c.JSON(http.StatusOK, entry)
 	}
-	
-	// Group completions by date and habit_id
-	type DateHabitMap map[string]map[int]bool // date -> habit_id -> true
-	dateHabitMap := make(DateHabitMap)
-	
-	for rows.Next() {
-		var date time.Time
-		var habitID int
-		err := rows.Scan(&date, &habitID)
+}
+
+// SummarizeJournalEntries generates an AI summary of the user's stored journal entries,
+// either of the week or month containing a date, which is kept until its entries change,
+// or of the last days (7 by default)
+func (h *JournalHandler) SummarizeJournalEntries(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.SummarizeJournalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	today := services.Today(services.UserLocation(userID.(int)))
+
+	if req.Period == "" {
+		days := req.Days
+		if days == 0 {
+			days = 7
+		}
+		from := today.AddDate(0, 0, 1-days)
+		summary, count, err := h.summarizer.SummarizeRange(userID.(int), from, today)
+		if err == services.ErrNoJournalEntries {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No journal entries in this period"})
+			return
+		}
 		if err != nil {
-			log.Printf("Failed to scan completion: %v", err)
-			continue
+			log.Printf("Failed to summarize journal of user %v: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate summary"})
+			return
 		}
-		dateStr := date.Format("2006-01-02")
-		if dateHabitMap[dateStr] == nil {
-			dateHabitMap[dateStr] = make(map[int]bool)
+		c.JSON(http.StatusOK, gin.H{
+			"summary":     summary,
+			"from":        from.Format(services.DateLayout),
+			"to":          today.Format(services.DateLayout),
+			"entry_count": count,
+		})
+		return
+	}
+
+	day := today
+	if req.Date != "" {
+		var err error
+		if day, err = time.ParseInLocation(services.DateLayout, req.Date, today.Location()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
 		}
-		dateHabitMap[dateStr][habitID] = true
 	}
-	
-	// Get total count of active habits for the user
-	var totalActiveHabits int
-	err = database.DB.QueryRow(`
-		SELECT COUNT(*) FROM habits WHERE user_id = ? AND is_active = true
-	`, userID).Scan(&totalActiveHabits)
+	summary, _, err := h.summarizer.SummarizePeriod(userID.(int), req.Period, day)
+	if err == services.ErrNoJournalEntries {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No journal entries in this period"})
+		return
+	}
 	if err != nil {
-		log.Printf("Failed to count active habits: %v", err)
-		totalActiveHabits = 0
+		log.Printf("Failed to summarize journal of user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate summary"})
+		return
 	}
-	
-	// Only include dates where ALL active habits were completed
-	var completions []CompletionData
-	for dateStr, habitIDs := range dateHabitMap {
-		if len(habitIDs) >= totalActiveHabits && totalActiveHabits > 0 {
-			completions = append(completions, CompletionData{
-				CompletionDate: dateStr,
-			})
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetJournalSummaries returns the user's generated week and month summaries, newest first.
+// ?period limits them to "week" or "month".
+func (h *JournalHandler) GetJournalSummaries(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	condition := ""
+	args := []interface{}{userID}
+	if period := c.Query("period"); period != "" {
+		if period != services.JournalPeriodWeek && period != services.JournalPeriodMonth {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "period must be week or month"})
+			return
 		}
+		condition = " AND period = ?"
+		args = append(args, period)
+	}
+	limit := 20
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+		limit = n
 	}
-	
-	// Calculate streaks based on days where ALL habits were completed
-	var currentStreak, bestStreak int
-	if habitIDStr != "" {
-		habitID, _ := strconv.Atoi(habitIDStr)
-		currentStreak, bestStreak = calculateStreaks(habitID, userID.(int))
-	} else {
-		// Calculate streaks based on days where ALL habits were completed
-		currentStreak, bestStreak = calculateOverallStreaksAllHabits(userID.(int))
-	}
-	
-	c.JSON(http.StatusOK, gin.H{
-		"completions": completions,
-		"start_date": startDate.Format("2006-01-02"),
-		"end_date": endDate.Format("2006-01-02"),
-		"current_streak": currentStreak,
-		"best_streak": bestStreak,
-	})
-}
+	args = append(args, limit)
 
-// calculateStreaks calculates current and best streak for a specific habit
-func calculateStreaks(habitID, userID int) (currentStreak, bestStreak int) {
 	rows, err := database.DB.Query(`
-		SELECT DATE(completed_at) as completion_date
-		FROM habit_completions
-		WHERE habit_id = ? AND user_id = ?
-		ORDER BY completion_date DESC
-	`, habitID, userID)
+		SELECT id, user_id, period, period_start, period_end, summary, entry_count, created_at, updated_at
+		FROM journal_summaries
+		WHERE user_id = ?`+condition+`
+		ORDER BY period_start DESC, period
+		LIMIT ?
+	`, args...)
 	if err != nil {
-		log.Printf("Failed to calculate streaks: %v", err)
-		return 0, 0
+		log.Printf("Failed to query journal summaries of user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch journal summaries"})
+		return
 	}
 	defer rows.Close()
-	
-	var dates []time.Time
+
+	summaries := []models.JournalSummary{}
 	for rows.Next() {
-		var date time.Time
-		if err := rows.Scan(&date); err != nil {
-			continue
+		var summary models.JournalSummary
+		err := rows.Scan(&summary.ID, &summary.UserID, &summary.Period, &summary.PeriodStart, &summary.PeriodEnd,
+			&summary.Summary, &summary.EntryCount, &summary.CreatedAt, &summary.UpdatedAt)
+		if err != nil {
+			log.Printf("Failed to scan journal summary: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch journal summaries"})
+			return
 		}
-		dates = append(dates, date.Truncate(24 * time.Hour))
-	}
-	
-	if len(dates) == 0 {
-		return 0, 0
+		summaries = append(summaries, summary)
 	}
-	
-	// Calculate current streak (from today backwards)
-	today := time.Now().Truncate(24 * time.Hour)
-	currentStreak = 0
-	expectedDate := today
-	
-	for i := 0; i < len(dates); i++ {
-		date := dates[i]
-		if date.Equal(expectedDate) {
-			currentStreak++
-			expectedDate = expectedDate.AddDate(0, 0, -1)
-		} else if date.Before(expectedDate) {
-			break
-		}
+	c.JSON(http.StatusOK, summaries)
+}
+
+// UpdateJournalEntry updates a specific journal entry
+func (h *JournalHandler) UpdateJournalEntry(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	entryIDStr := c.Param("id")
+	entryID, err := strconv.Atoi(entryIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entry ID"})
+		return
 	}
-	
-	// Calculate best streak
-	bestStreak = 1
-	streak := 1
-	for i := 1; i < len(dates); i++ {
-		daysDiff := int(dates[i-1].Sub(dates[i]).Hours() / 24)
-		if daysDiff == 1 {
-			streak++
-			if streak > bestStreak {
-				bestStreak = streak
-			}
-		} else {
-			streak = 1
-		}
+
+	var req models.UpdateJournalEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	
-	return currentStreak, bestStreak
-}
 
-// calculateOverallStreaks calculates overall streaks across all habits
-func calculateOverallStreaks(userID int) (currentStreak, bestStreak int) {
-	rows, err := database.DB.Query(`
-		SELECT DATE(completed_at) as completion_date
-		FROM habit_completions
-		WHERE user_id = ?
-		GROUP BY DATE(completed_at)
-		ORDER BY completion_date DESC
-	`, userID)
+	// Verify entry belongs to user
+	var currentTemplate sql.NullInt64
+	var currentFields []byte
+	err = database.DB.QueryRow(`
+		SELECT template_id, fields FROM journal_entries WHERE id = ? AND user_id = ?
+	`, entryID, userID).Scan(&currentTemplate, &currentFields)
 	if err != nil {
-		log.Printf("Failed to calculate overall streaks: %v", err)
-		return 0, 0
+		c.JSON(http.StatusNotFound, gin.H{"error": "Journal entry not found"})
+		return
 	}
-	defer rows.Close()
-	
-	var dates []time.Time
-	for rows.Next() {
-		var date time.Time
-		if err := rows.Scan(&date); err != nil {
-			continue
-		}
-		dates = append(dates, date.Truncate(24 * time.Hour))
+
+	// Build update query dynamically
+	updateFields := []string{}
+	args := []interface{}{}
+
+	if req.Mood != nil {
+		updateFields = append(updateFields, "mood = ?")
+		args = append(args, *req.Mood)
 	}
-	
-	if len(dates) == 0 {
-		return 0, 0
+	if req.Content != nil {
+		updateFields = append(updateFields, "content = ?")
+		args = append(args, *req.Content)
 	}
-	
-	// Calculate current streak (from today backwards)
-	today := time.Now().Truncate(24 * time.Hour)
-	currentStreak = 0
-	expectedDate := today
-	
-	for i := 0; i < len(dates); i++ {
-		date := dates[i]
-		if date.Equal(expectedDate) {
-			currentStreak++
-			expectedDate = expectedDate.AddDate(0, 0, -1)
-		} else if date.Before(expectedDate) {
-			break
+	// A new template or new answers are validated together; kept answers are checked
+	// against a new template
+	if req.TemplateID != nil || req.Fields != nil {
+		templateID := int(currentTemplate.Int64)
+		if req.TemplateID != nil {
+			templateID = *req.TemplateID
 		}
-	}
-	
-	// Calculate best streak
-	bestStreak = 1
-	streak := 1
-	for i := 1; i < len(dates); i++ {
-		daysDiff := int(dates[i-1].Sub(dates[i]).Hours() / 24)
-		if daysDiff == 1 {
-			streak++
-			if streak > bestStreak {
-				bestStreak = streak
+		answers := req.Fields
+		if answers == nil && len(currentFields) > 0 {
+			if err := json.Unmarshal(currentFields, &answers); err != nil {
+				log.Printf("Failed to read fields of journal entry %d: %v", entryID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update journal entry"})
+				return
+			}
+		}
+
+		if templateID == 0 {
+			if len(req.Fields) > 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Fields need a template_id"})
+				return
 			}
+			updateFields = append(updateFields, "template_id = NULL", "fields = NULL", "fields_text = NULL")
 		} else {
-			streak = 1
+			template, err := fetchJournalTemplate(userID.(int), templateID)
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Journal template not found"})
+				return
+			}
+			if err != nil {
+				log.Printf("Failed to fetch journal template %d: %v", templateID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update journal entry"})
+				return
+			}
+			fields, fieldsText, err := journalFieldColumns(template, answers)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			updateFields = append(updateFields, "template_id = ?", "fields = ?", "fields_text = ?")
+			args = append(args, templateID, fields, fieldsText)
 		}
 	}
-	
-	return currentStreak, bestStreak
+
+	if len(updateFields) == 0 && req.Tags == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+		return
+	}
+
+	updateFields = append(updateFields, "updated_at = NOW()")
+	args = append(args, entryID)
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update journal entry"})
+		return
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf("UPDATE journal_entries SET %s WHERE id = ?", strings.Join(updateFields, ", "))
+	_, err = tx.Exec(query, args...)
+	if err == nil && req.Tags != nil {
+		err = setJournalTags(tx, userID.(int), entryID, parseJournalTags(*req.Tags))
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Failed to update journal entry %d: %v", entryID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update journal entry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Journal entry updated successfully"})
 }
 
-// calculateOverallStreaksAllHabits calculates streaks based on days where ALL active habits were completed
-func calculateOverallStreaksAllHabits(userID int) (currentStreak, bestStreak int) {
-	// Get total count of active habits
-	var totalActiveHabits int
-	err := database.DB.QueryRow(`
-		SELECT COUNT(*) FROM habits WHERE user_id = ? AND is_active = true
-	`, userID).Scan(&totalActiveHabits)
+// DeleteJournalEntry deletes a journal entry
+func (h *JournalHandler) DeleteJournalEntry(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	entryIDStr := c.Param("id")
+	entryID, err := strconv.Atoi(entryIDStr)
 	if err != nil {
-		log.Printf("Failed to count active habits: %v", err)
-		return 0, 0
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entry ID"})
+		return
 	}
-	
-	if totalActiveHabits == 0 {
-		return 0, 0
+
+	// Verify entry belongs to user
+	var entry models.JournalEntry
+	err = database.DB.QueryRow(`
+		SELECT id, user_id FROM journal_entries WHERE id = ? AND user_id = ?
+	`, entryID, userID).Scan(&entry.ID, &entry.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Journal entry not found"})
+		return
 	}
-	
-	// Get all completions grouped by date with habit counts
-	rows, err := database.DB.Query(`
-		SELECT DATE(completed_at) as completion_date, COUNT(DISTINCT habit_id) as habit_count
-		FROM habit_completions hc
-		INNER JOIN habits h ON hc.habit_id = h.id
-		WHERE hc.user_id = ? AND h.user_id = ? AND h.is_active = true
-		GROUP BY DATE(completed_at)
-		HAVING habit_count >= ?
-		ORDER BY completion_date DESC
-	`, userID, userID, totalActiveHabits)
-	if err != nil {
-		log.Printf("Failed to calculate overall streaks (all habits): %v", err)
-		return 0, 0
+
+	_, err = database.DB.Exec(`DELETE FROM journal_entries WHERE id = ?`, entryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete journal entry"})
+		return
 	}
-	defer rows.Close()
-	
-	var dates []time.Time
-	for rows.Next() {
-		var date time.Time
-		var habitCount int
-		if err := rows.Scan(&date, &habitCount); err != nil {
-			continue
+	if err := pruneJournalTags(database.DB, userID.(int)); err != nil {
+		log.Printf("Failed to prune journal tags of user %v: %v", userID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Journal entry deleted successfully"})
+}
+
+// SearchJournalEntries finds the user's journal entries by text, tags, mood, template and
+// date range. q is matched against the content and template answers with the full-text
+// index, words as prefixes ("lauf" finds "laufen"), and results are ordered by relevance;
+// without q they are ordered newest first. tags is comma-separated and matches entries
+// having all of them. With template_id, fields[key]=value filters on its fields.
+func (h *JournalHandler) SearchJournalEntries(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	order := "entry_date DESC"
+	var orderArgs []interface{}
+	conditions := []string{"user_id = ?"}
+	args := []interface{}{userID}
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		match := fulltextQuery(q)
+		if match == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Search query contains no words"})
+			return
 		}
-		// Only include dates where all habits were completed
-		if habitCount >= totalActiveHabits {
-			dates = append(dates, date.Truncate(24 * time.Hour))
+		order = "MATCH(content, fields_text) AGAINST(? IN BOOLEAN MODE) DESC, entry_date DESC"
+		orderArgs = append(orderArgs, match)
+		conditions = append(conditions, "MATCH(content, fields_text) AGAINST(? IN BOOLEAN MODE)")
+		args = append(args, match)
+	}
+	if tags := parseJournalTags(c.Query("tags")); len(tags) > 0 {
+		placeholders := strings.Repeat("?,", len(tags))
+		placeholders = placeholders[:len(placeholders)-1]
+		conditions = append(conditions, fmt.Sprintf(`id IN (
+			SELECT et.entry_id FROM journal_entry_tags et
+			INNER JOIN journal_tags t ON t.id = et.tag_id
+			WHERE t.user_id = ? AND t.name IN (%s)
+			GROUP BY et.entry_id
+			HAVING COUNT(*) = ?)`, placeholders))
+		args = append(args, userID)
+		for _, tag := range tags {
+			args = append(args, tag)
 		}
+		args = append(args, len(tags))
 	}
-	
-	if len(dates) == 0 {
-		return 0, 0
+	if mood := c.Query("mood"); mood != "" {
+		conditions = append(conditions, "mood = ?")
+		args = append(args, mood)
 	}
-	
-	// Calculate current streak (from today backwards)
-	today := time.Now().Truncate(24 * time.Hour)
-	currentStreak = 0
-	expectedDate := today
-	
-	for i := 0; i < len(dates); i++ {
-		date := dates[i]
-		if date.Equal(expectedDate) {
-			currentStreak++
-			expectedDate = expectedDate.AddDate(0, 0, -1)
-		} else if date.Before(expectedDate) {
-			break
+	filters := c.QueryMap("fields")
+	if raw := c.Query("template_id"); raw != "" {
+		templateID, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+			return
+		}
+		conditions = append(conditions, "template_id = ?")
+		args = append(args, templateID)
+
+		if len(filters) > 0 {
+			template, err := fetchJournalTemplate(userID.(int), templateID)
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Journal template not found"})
+				return
+			}
+			if err != nil {
+				log.Printf("Failed to fetch journal template %d: %v", templateID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search journal entries"})
+				return
+			}
+			for key, value := range filters {
+				condition, values, err := journalFieldCondition(template, key, value)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				conditions = append(conditions, condition)
+				args = append(args, values...)
+			}
+		}
+	} else if len(filters) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Filtering by fields needs a template_id"})
+		return
+	}
+	for _, bound := range []struct{ param, condition string }{{"from", "entry_date >= ?"}, {"to", "entry_date <= ?"}} {
+		raw := c.Query(bound.param)
+		if raw == "" {
+			continue
+		}
+		if _, err := time.Parse(services.DateLayout, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s date. Use YYYY-MM-DD", bound.param)})
+			return
+		}
+		conditions = append(conditions, bound.condition)
+		args = append(args, raw)
+	}
+
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
 		}
+		limit = n
+	}
+	args = append(append(args, orderArgs...), limit)
+
+	rows, err := database.DB.Query(fmt.Sprintf(`
+		SELECT %s
+		FROM journal_entries
+		WHERE %s
+		ORDER BY %s
+		LIMIT ?
+	`, journalEntryColumns, strings.Join(conditions, " AND "), order), args...)
+	if err != nil {
+		log.Printf("Failed to search journal entries of user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search journal entries"})
+		return
 	}
-	
-	// Calculate best streak
-	bestStreak = 1
-	streak := 1
-	for i := 1; i < len(dates); i++ {
-		daysDiff := int(dates[i-1].Sub(dates[i]).Hours() / 24)
-		if daysDiff == 1 {
-			streak++
-			if streak > bestStreak {
-				bestStreak = streak
-			}
-		} else {
-			streak = 1
+	defer rows.Close()
+
+	entries := []models.JournalEntry{}
+	for rows.Next() {
+		entry, err := scanJournalEntry(rows)
+		if err != nil {
+			log.Printf("Failed to scan journal entry: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search journal entries"})
+			return
 		}
+		entries = append(entries, entry)
+	}
+	if err := loadJournalTags(entries); err != nil {
+		log.Printf("Failed to load journal tags for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search journal entries"})
+		return
 	}
-	
-	return currentStreak, bestStreak
-}
 
-// TaskHandler handles task endpoints
-type TaskHandler struct{}
+	c.JSON(http.StatusOK, entries)
+}
 
-// GetTasks returns all tasks for the authenticated user
-func (h *TaskHandler) GetTasks(c *gin.Context) {
+// GetJournalTags returns the user's journal tags with how many entries have each,
+// most used first
+func (h *JournalHandler) GetJournalTags(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
 	rows, err := database.DB.Query(`
-		SELECT id, user_id, title, description, priority, due_date, 
-		       completed_at, parent_task_id, is_recurring_template, 
-		       recurrence_interval_weeks, recurrence_end_date, created_at, updated_at
-		FROM tasks WHERE user_id = ?
-		ORDER BY 
-			CASE WHEN completed_at IS NULL THEN 0 ELSE 1 END,
-			CASE priority 
-				WHEN 'high' THEN 1 
-				WHEN 'medium' THEN 2 
-				WHEN 'low' THEN 3 
-			END,
-			due_date ASC
+		SELECT t.id, t.name, COUNT(*), t.created_at
+		FROM journal_tags t
+		INNER JOIN journal_entry_tags et ON et.tag_id = t.id
+		WHERE t.user_id = ?
+		GROUP BY t.id
+		ORDER BY COUNT(*) DESC, t.name
 	`, userID)
 	if err != nil {
-		log.Printf("Failed to query tasks for user %v: %v", userID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+		log.Printf("Failed to query journal tags of user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch journal tags"})
 		return
 	}
 	defer rows.Close()
 
-	var tasks []models.Task
+	tags := []models.JournalTag{}
 	for rows.Next() {
-		var task models.Task
-		err := rows.Scan(
-			&task.ID, &task.UserID, &task.Title, &task.Description,
-			&task.Priority, &task.DueDate, &task.CompletedAt,
-			&task.ParentTaskID, &task.IsRecurringTemplate,
-			&task.RecurrenceIntervalWeeks, &task.RecurrenceEndDate,
-			&task.CreatedAt, &task.UpdatedAt,
-		)
-		if err != nil {
-			log.Printf("Failed to scan task for user %v: %v", userID, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan task"})
+		var tag models.JournalTag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.EntryCount, &tag.CreatedAt); err != nil {
+			log.Printf("Failed to scan journal tag: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch journal tags"})
 			return
 		}
-		tasks = append(tasks, task)
+		tags = append(tags, tag)
 	}
 
-	c.JSON(http.StatusOK, tasks)
+	c.JSON(http.StatusOK, tags)
 }
 
-// CreateTask creates a new task
-func (h *TaskHandler) CreateTask(c *gin.Context) {
+// RenameJournalTag renames a tag on all entries. If another tag already has the new
+// name (ignoring case), the two are merged into that one, which is returned.
+func (h *JournalHandler) RenameJournalTag(c *gin.Context) {
 	userID, _ := c.Get("user_id")
+	tagID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
 
-	var req models.CreateTaskRequest
+	var req models.RenameJournalTagRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	// If it's a recurring task, generate all instances
-	if req.IsRecurring && req.DueDate != nil && req.RecurrenceIntervalWeeks != nil && req.RecurrenceEndDate != nil {
-		var createdTasks []models.Task
-		startDate := *req.DueDate
-		endDate := *req.RecurrenceEndDate
-		intervalWeeks := *req.RecurrenceIntervalWeeks
-
-		// Generate tasks for each occurrence
-		currentDate := startDate
-		for !currentDate.After(endDate) {
-			task := models.Task{
-				UserID:                userID.(int),
-				Title:                 req.Title,
-				Description:           req.Description,
-				Priority:              req.Priority,
-				DueDate:               &currentDate,
-				IsRecurringTemplate:   false,
-				RecurrenceIntervalWeeks: nil,
-				RecurrenceEndDate:     nil,
-				CreatedAt:             time.Now(),
-				UpdatedAt:             time.Now(),
-			}
-
-			result, err := database.DB.Exec(`
-				INSERT INTO tasks (user_id, title, description, priority, due_date, is_recurring_template, recurrence_interval_weeks, recurrence_end_date)
-				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-			`, task.UserID, task.Title, task.Description, task.Priority, task.DueDate, task.IsRecurringTemplate, task.RecurrenceIntervalWeeks, task.RecurrenceEndDate)
-			if err != nil {
-				log.Printf("Failed to create recurring task instance: %v", err)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recurring task instance"})
-				return
-			}
-
-			taskID, _ := result.LastInsertId()
-			task.ID = int(taskID)
-			createdTasks = append(createdTasks, task)
-
-			// Move to next occurrence
-			currentDate = currentDate.AddDate(0, 0, intervalWeeks*7)
-		}
-
-		c.JSON(http.StatusCreated, gin.H{
-			"message": "Recurring tasks created",
-			"count":   len(createdTasks),
-			"tasks":   createdTasks,
-		})
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
 		return
 	}
 
-	// Non-recurring task - create as before
-	result, err := database.DB.Exec(`
-		INSERT INTO tasks (user_id, title, description, priority, due_date, is_recurring_template, recurrence_interval_weeks, recurrence_end_date)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-	`, userID, req.Title, req.Description, req.Priority, req.DueDate, false, nil, nil)
+	tx, err := database.DB.Begin()
 	if err != nil {
-		log.Printf("Failed to create task: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename tag"})
 		return
 	}
+	defer tx.Rollback()
 
-	taskID, _ := result.LastInsertId()
-
-	task := models.Task{
-		ID:          int(taskID),
-		UserID:      userID.(int),
-		Title:       req.Title,
-		Description: req.Description,
-		Priority:    req.Priority,
-		DueDate:     req.DueDate,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+	var exists bool
+	err = tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM journal_tags WHERE id = ? AND user_id = ? FOR UPDATE)
+	`, tagID, userID).Scan(&exists)
+	if err != nil {
+		log.Printf("Failed to fetch journal tag %d: %v", tagID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename tag"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+
+	resultID := tagID
+	var targetID int
+	err = tx.QueryRow(`
+		SELECT id FROM journal_tags WHERE user_id = ? AND name = ? AND id <> ?
+	`, userID, name, tagID).Scan(&targetID)
+	switch {
+	case err == sql.ErrNoRows:
+		_, err = tx.Exec("UPDATE journal_tags SET name = ? WHERE id = ?", name, tagID)
+	case err == nil:
+		// Entries having both tags keep the target's position
+		_, err = tx.Exec(`
+			INSERT IGNORE INTO journal_entry_tags (entry_id, tag_id, position)
+			SELECT entry_id, ?, position FROM journal_entry_tags WHERE tag_id = ?
+		`, targetID, tagID)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM journal_tags WHERE id = ?", tagID)
+		}
+		resultID = targetID
+	}
+	if err == nil {
+		err = tx.Commit()
 	}
-
-	c.JSON(http.StatusCreated, task)
-}
-
-// CompleteTask marks a task as completed
-func (h *TaskHandler) CompleteTask(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	taskIDStr := c.Param("id")
-	taskID, err := strconv.Atoi(taskIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		log.Printf("Failed to rename journal tag %d: %v", tagID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename tag"})
 		return
 	}
 
-	// Check if task belongs to user
-	var task models.Task
+	var tag models.JournalTag
 	err = database.DB.QueryRow(`
-		SELECT id, user_id, completed_at FROM tasks WHERE id = ? AND user_id = ?
-	`, taskID, userID).Scan(&task.ID, &task.UserID, &task.CompletedAt)
+		SELECT t.id, t.name, COUNT(et.entry_id), t.created_at
+		FROM journal_tags t
+		LEFT JOIN journal_entry_tags et ON et.tag_id = t.id
+		WHERE t.id = ?
+		GROUP BY t.id
+	`, resultID).Scan(&tag.ID, &tag.Name, &tag.EntryCount, &tag.CreatedAt)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		log.Printf("Failed to fetch journal tag %d: %v", resultID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tag"})
 		return
 	}
 
-	// Toggle completion status
-	var completedAt *time.Time
-	if task.CompletedAt == nil {
-		now := time.Now()
-		completedAt = &now
-	}
+	c.JSON(http.StatusOK, tag)
+}
 
-	_, err = database.DB.Exec(`
-		UPDATE tasks SET completed_at = ?, updated_at = NOW() WHERE id = ?
-	`, completedAt, taskID)
+// DeleteJournalTag removes a tag from all entries
+func (h *JournalHandler) DeleteJournalTag(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	tagID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Task updated successfully"})
-}
-
-// JournalHandler handles journal entry endpoints
-type JournalHandler struct{}
+	result, err := database.DB.Exec("DELETE FROM journal_tags WHERE id = ? AND user_id = ?", tagID, userID)
+	if err != nil {
+		log.Printf("Failed to delete journal tag %d: %v", tagID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
 
-// NewJournalHandler creates a new journal handler
-func NewJournalHandler() *JournalHandler {
-	return &JournalHandler{}
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
 }
 
-// GetJournalEntries returns all journal entries for the authenticated user
-func (h *JournalHandler) GetJournalEntries(c *gin.Context) {
+// GetJournalTemplates returns the user's journal templates by name
+func (h *JournalHandler) GetJournalTemplates(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
 	rows, err := database.DB.Query(`
-		SELECT id, user_id, entry_date, mood, content, tags, created_at, updated_at
-		FROM journal_entries WHERE user_id = ?
-		ORDER BY entry_date DESC
+		SELECT `+journalTemplateColumns+`
+		FROM journal_templates WHERE user_id = ?
+		ORDER BY name
 	`, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch journal entries"})
+		log.Printf("Failed to query journal templates of user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch journal templates"})
 		return
 	}
 	defer rows.Close()
 
-	var entries []models.JournalEntry
+	templates := []models.JournalTemplate{}
 	for rows.Next() {
-		var entry models.JournalEntry
-		err := rows.Scan(&entry.ID, &entry.UserID, &entry.EntryDate, &entry.Mood, &entry.Content, &entry.Tags, &entry.CreatedAt, &entry.UpdatedAt)
+		template, err := scanJournalTemplate(rows)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan journal entry"})
+			log.Printf("Failed to scan journal template: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch journal templates"})
 			return
 		}
-		entries = append(entries, entry)
+		templates = append(templates, template)
 	}
 
-	c.JSON(http.StatusOK, entries)
+	c.JSON(http.StatusOK, templates)
 }
 
-// GetJournalEntryByDate returns a journal entry for a specific date
-func (h *JournalHandler) GetJournalEntryByDate(c *gin.Context) {
+// CreateJournalTemplate creates a journal template with typed fields
+func (h *JournalHandler) CreateJournalTemplate(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	date := c.Param("date")
 
-	var entry models.JournalEntry
-	err := database.DB.QueryRow(`
-		SELECT id, user_id, entry_date, mood, content, tags, created_at, updated_at
-		FROM journal_entries WHERE user_id = ? AND DATE(entry_date) = ?
-	`, userID, date).Scan(&entry.ID, &entry.UserID, &entry.EntryDate, &entry.Mood, &entry.Content, &entry.Tags, &entry.CreatedAt, &entry.UpdatedAt)
+	var req models.JournalTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name, fields, err := parseJournalTemplate(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Journal entry not found"})
+	result, err := database.DB.Exec(`
+		INSERT INTO journal_templates (user_id, name, description, fields) VALUES (?, ?, ?, ?)
+	`, userID, name, req.Description, fields)
+	if database.IsDuplicateKey(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A journal template with this name already exists"})
 		return
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch journal entry"})
+		log.Printf("Failed to create journal template for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create journal template"})
 		return
 	}
+	templateID, _ := result.LastInsertId()
 
-	c.JSON(http.StatusOK, entry)
+	template, err := fetchJournalTemplate(userID.(int), int(templateID))
+	if err != nil {
+		log.Printf("Failed to fetch journal template %d: %v", templateID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch journal template"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
 }
 
-// CreateOrUpdateJournalEntry creates or updates a journal entry
-func (h *JournalHandler) CreateOrUpdateJournalEntry(c *gin.Context) {
+// UpdateJournalTemplate replaces the name, description and fields of a journal template.
+// Entries already written with it keep their answers; they are checked against the new
+// fields when they are next saved.
+func (h *JournalHandler) UpdateJournalTemplate(c *gin.Context) {
 	userID, _ := c.Get("user_id")
+	templateID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
 
-	var req models.CreateJournalEntryRequest
+	var req models.JournalTemplateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Printf("Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name, fields, err := parseJournalTemplate(req)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	log.Printf("Received journal entry request: EntryDate=%v, Mood=%s, Content length=%d, Tags=%s", req.EntryDate, req.Mood, len(req.Content), req.Tags)
-
-	// Check if entry already exists for this date
-	var existingID int
-	err := database.DB.QueryRow(`
-		SELECT id FROM journal_entries WHERE user_id = ? AND DATE(entry_date) = DATE(?)
-	`, userID, req.EntryDate).Scan(&existingID)
+	_, err = database.DB.Exec(`
+		UPDATE journal_templates SET name = ?, description = ?, fields = ? WHERE id = ? AND user_id = ?
+	`, name, req.Description, fields, templateID, userID)
+	if database.IsDuplicateKey(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A journal template with this name already exists"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to update journal template %d: %v", templateID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update journal template"})
+		return
+	}
 
+	template, err := fetchJournalTemplate(userID.(int), templateID)
 	if err == sql.ErrNoRows {
-		// Create new entry
-		log.Printf("Creating new entry for user %d", userID)
-		// Ensure tags is valid JSON - if it's already a JSON string, use it; otherwise wrap it
-		tagsJSON := req.Tags
-		if req.Tags != "" && !strings.HasPrefix(strings.TrimSpace(req.Tags), "[") {
-			// If tags is not a JSON array, try to parse it as comma-separated and convert to JSON
-			tagsList := strings.Split(req.Tags, ",")
-			var cleanTags []string
-			for _, tag := range tagsList {
-				trimmed := strings.TrimSpace(tag)
-				if trimmed != "" {
-					cleanTags = append(cleanTags, trimmed)
-				}
-			}
-			if len(cleanTags) > 0 {
-				tagsJSONBytes, _ := json.Marshal(cleanTags)
-				tagsJSON = string(tagsJSONBytes)
-			} else {
-				tagsJSON = "[]"
-			}
-		}
-		if tagsJSON == "" {
-			tagsJSON = "[]"
-		}
-		
-		result, err := database.DB.Exec(`
-			INSERT INTO journal_entries (user_id, entry_date, mood, content, tags)
-			VALUES (?, ?, ?, ?, ?)
-		`, userID, req.EntryDate, req.Mood, req.Content, tagsJSON)
-		if err != nil {
-			log.Printf("Failed to create journal entry: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create journal entry: %v", err)})
-			return
-		}
-
-		entryID, _ := result.LastInsertId()
-		entry := models.JournalEntry{
-			ID:        int(entryID),
-			UserID:    userID.(int),
-			EntryDate: req.EntryDate,
-			Mood:      req.Mood,
-			Content:   req.Content,
-			Tags:      req.Tags,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		}
-
-		c.JSON(http.StatusCreated, entry)
-	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing entry"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Journal template not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch journal template %d: %v", templateID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch journal template"})
 		return
-	} else {
-		// Update existing entry
-		log.Printf("Updating existing entry %d for user %d", existingID, userID)
-		// Ensure tags is valid JSON
-		tagsJSON := req.Tags
-		if req.Tags != "" && !strings.HasPrefix(strings.TrimSpace(req.Tags), "[") {
-			// If tags is not a JSON array, try to parse it as comma-separated and convert to JSON
-			tagsList := strings.Split(req.Tags, ",")
-			var cleanTags []string
-			for _, tag := range tagsList {
-				trimmed := strings.TrimSpace(tag)
-				if trimmed != "" {
-					cleanTags = append(cleanTags, trimmed)
-				}
-			}
-			if len(cleanTags) > 0 {
-				tagsJSONBytes, _ := json.Marshal(cleanTags)
-				tagsJSON = string(tagsJSONBytes)
-			} else {
-				tagsJSON = "[]"
-			}
-		}
-		if tagsJSON == "" {
-			tagsJSON = "[]"
-		}
-		
-		_, err := database.DB.Exec(`
-			UPDATE journal_entries 
-			SET mood = ?, content = ?, tags = ?, updated_at = NOW()
-			WHERE id = ?
-		`, req.Mood, req.Content, tagsJSON, existingID)
-		if err != nil {
-			log.Printf("Failed to update journal entry: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update journal entry: %v", err)})
-			return
-		}
-
-		// Fetch updated entry
-		var entry models.JournalEntry
-		err = database.DB.QueryRow(`
-			SELECT id, user_id, entry_date, mood, content, tags, created_at, updated_at
-			FROM journal_entries WHERE id = ?
-		`, existingID).Scan(&entry.ID, &entry.UserID, &entry.EntryDate, &entry.Mood, &entry.Content, &entry.Tags, &entry.CreatedAt, &entry.UpdatedAt)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated entry"})
-			return
-		}
-
-		c.JSON(http.StatusOK, entry)
 	}
-}
 
-// SummarizeJournalEntries generates an AI summary of journal entries
-func (h *JournalHandler) SummarizeJournalEntries(c *gin.Context) {
-	_, _ = c.Get("user_id") // User is authenticated via middleware
+	c.JSON(http.StatusOK, template)
+}
 
-	var req struct {
-		Entries []models.JournalEntry `json:"entries"`
-		Days    int                    `json:"days"`
+// DeleteJournalTemplate deletes a journal template. Its entries keep their answers.
+func (h *JournalHandler) DeleteJournalTemplate(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	templateID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	result, err := database.DB.Exec("DELETE FROM journal_templates WHERE id = ? AND user_id = ?", templateID, userID)
+	if err != nil {
+		log.Printf("Failed to delete journal template %d: %v", templateID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete journal template"})
 		return
 	}
-
-	// Build context string from entries
-	context := fmt.Sprintf("Hier sind die Journal-Einträge der letzten %d Tage:\n\n", req.Days)
-	
-	for i, entry := range req.Entries {
-		entryDate, _ := time.Parse("2006-01-02", entry.EntryDate.Format("2006-01-02"))
-		context += fmt.Sprintf("=== Eintrag %d: %s ===\n", i+1, entryDate.Format("02.01.2006"))
-		
-		if entry.Mood != "" {
-			moodLabels := map[string]string{
-				"excellent": "Ausgezeichnet",
-				"good":     "Gut",
-				"okay":     "Okay",
-				"bad":      "Schlecht",
-				"terrible": "Schrecklich",
-			}
-			if moodLabel, ok := moodLabels[entry.Mood]; ok {
-				context += fmt.Sprintf("Stimmung: %s\n", moodLabel)
-			}
-		}
-		
-		if entry.Content != "" {
-			context += fmt.Sprintf("Inhalt:\n%s\n\n", entry.Content)
-		}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Journal template not found"})
+		return
 	}
 
-	// Use OpenAI service to generate summary
-	openAIService := services.NewOpenAIService()
-	
-	systemPrompt := `Du bist ein hilfreicher Assistent für ein Tagebuch-Tool. 
-Du sollst eine aussagekräftige und reflektierende Zusammenfassung der bereitgestellten Journal-Einträge erstellen.
-Die Zusammenfassung sollte:
-- Die wichtigsten Themen und Muster identifizieren
-- Die Entwicklung der Stimmung über die Zeit beschreiben
-- Wiederkehrende Themen oder Gewohnheiten hervorheben
-- Eine positive und reflektierende Perspektive bieten
-- Auf Deutsch formuliert sein
-- Maximal 500 Wörter lang sein
-
-Antworte nur mit der Zusammenfassung, kein zusätzlicher Text.`
-	
-	userMessage := fmt.Sprintf("Bitte erstelle eine Zusammenfassung dieser Journal-Einträge:\n\n%s", context)
+	c.JSON(http.StatusOK, gin.H{"message": "Journal template deleted successfully"})
+}
 
-	messages := []services.Message{
-		{Role: "system", Content: systemPrompt},
-		{Role: "user", Content: userMessage},
+// GetJournalTemplateInsights summarizes the answers to each field of a template over the
+// last days (90 unless ?days asks for up to a year): averages and the course of scales,
+// how often checkboxes were checked, the most frequent list items and how scales and
+// checkboxes go along with the mood
+func (h *JournalHandler) GetJournalTemplateInsights(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	templateID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
 	}
-
-	request := services.OpenAIRequest{
-		Model:       "gpt-3.5-turbo",
-		Messages:    messages,
-		MaxTokens:   800,
-		Temperature: 0.7,
+	days := defaultMoodInsightDays
+	if raw := c.Query("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxMoodInsightDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be between 1 and %d", maxMoodInsightDays)})
+			return
+		}
+		days = n
 	}
 
-	response, err := openAIService.MakeAPIRequest(request)
+	template, err := fetchJournalTemplate(userID.(int), templateID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Journal template not found"})
+		return
+	}
 	if err != nil {
-		log.Printf("OpenAI API Error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate summary"})
+		log.Printf("Failed to fetch journal template %d: %v", templateID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch journal template"})
 		return
 	}
 
-	if len(response.Choices) == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No response from AI"})
+	today := services.Today(services.UserLocation(userID.(int)))
+	from := today.AddDate(0, 0, 1-days)
+	rows, err := database.DB.Query(`
+		SELECT entry_date, mood, fields
+		FROM journal_entries
+		WHERE user_id = ? AND template_id = ? AND entry_date BETWEEN ? AND ? AND fields IS NOT NULL
+		ORDER BY entry_date
+	`, userID, templateID, from.Format(services.DateLayout), today.Format(services.DateLayout))
+	if err != nil {
+		log.Printf("Failed to query journal entries of template %d: %v", templateID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load journal template insights"})
 		return
 	}
+	defer rows.Close()
 
-	summary := response.Choices[0].Message.Content
-	
-	c.JSON(http.StatusOK, gin.H{"summary": summary})
+	var entries []services.JournalFieldEntry
+	for rows.Next() {
+		var date time.Time
+		var mood sql.NullString
+		var fields []byte
+		if err := rows.Scan(&date, &mood, &fields); err != nil {
+			log.Printf("Failed to scan journal entry: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load journal template insights"})
+			return
+		}
+		entry := services.JournalFieldEntry{Date: date.Format(services.DateLayout), Mood: mood.String}
+		if err := json.Unmarshal(fields, &entry.Fields); err != nil {
+			log.Printf("Skipping journal entry of %s with invalid fields: %v", entry.Date, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"template": template,
+		"from":     from.Format(services.DateLayout),
+		"to":       today.Format(services.DateLayout),
+		"entries":  len(entries),
+		"fields":   services.AnalyzeJournalFields(template.Fields, entries),
+	})
+}
+
+// journalEntryColumns are the columns scanJournalEntry expects, in order
+const journalEntryColumns = "id, user_id, entry_date, mood, content, template_id, fields, created_at, updated_at"
+
+// scanJournalEntry reads a journal entry selected with journalEntryColumns, without tags
+func scanJournalEntry(row rowScanner) (models.JournalEntry, error) {
+	var entry models.JournalEntry
+	var mood, content sql.NullString
+	var templateID sql.NullInt64
+	var fields []byte
+	err := row.Scan(&entry.ID, &entry.UserID, &entry.EntryDate, &mood, &content, &templateID, &fields, &entry.CreatedAt, &entry.UpdatedAt)
+	entry.Mood = mood.String
+	entry.Content = content.String
+	if templateID.Valid {
+		id := int(templateID.Int64)
+		entry.TemplateID = &id
+	}
+	if len(fields) > 0 {
+		entry.Fields = json.RawMessage(fields)
+	}
+	return entry, err
+}
+
+// fetchJournalEntry loads a journal entry with its tags
+func fetchJournalEntry(entryID int) (models.JournalEntry, error) {
+	entry, err := scanJournalEntry(database.DB.QueryRow(
+		"SELECT "+journalEntryColumns+" FROM journal_entries WHERE id = ?", entryID))
+	if err != nil {
+		return entry, err
+	}
+	entries := []models.JournalEntry{entry}
+	err = loadJournalTags(entries)
+	return entries[0], err
 }
 
-// UpdateJournalEntry updates a specific journal entry
-func (h *JournalHandler) UpdateJournalEntry(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	entryIDStr := c.Param("id")
-	entryID, err := strconv.Atoi(entryIDStr)
+// loadJournalTags fills in the tags of entries as a JSON array of names, in the
+// order they were given
+func loadJournalTags(entries []models.JournalEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	index := make(map[int]int, len(entries))
+	ids := make([]int, len(entries))
+	tags := make([][]string, len(entries))
+	for i := range entries {
+		index[entries[i].ID] = i
+		ids[i] = entries[i].ID
+		tags[i] = []string{}
+	}
+
+	placeholders := strings.Repeat("?,", len(ids))
+	placeholders = placeholders[:len(placeholders)-1]
+	rows, err := database.DB.Query(fmt.Sprintf(`
+		SELECT et.entry_id, t.name
+		FROM journal_entry_tags et
+		INNER JOIN journal_tags t ON t.id = et.tag_id
+		WHERE et.entry_id IN (%s)
+		ORDER BY et.position, t.name
+	`, placeholders), convertIntsToInterface(ids)...)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entry ID"})
-		return
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entryID int
+		var name string
+		if err := rows.Scan(&entryID, &name); err != nil {
+			return err
+		}
+		tags[index[entryID]] = append(tags[index[entryID]], name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
-	var req models.UpdateJournalEntryRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	for i := range entries {
+		encoded, _ := json.Marshal(tags[i])
+		entries[i].Tags = string(encoded)
 	}
+	return nil
+}
 
-	// Verify entry belongs to user
-	var entry models.JournalEntry
-	err = database.DB.QueryRow(`
-		SELECT id, user_id FROM journal_entries WHERE id = ? AND user_id = ?
-	`, entryID, userID).Scan(&entry.ID, &entry.UserID)
+// journalTemplateColumns are the columns scanJournalTemplate expects, in order
+const journalTemplateColumns = "id, user_id, name, description, fields, created_at, updated_at"
+
+// scanJournalTemplate reads a journal template selected with journalTemplateColumns
+func scanJournalTemplate(row rowScanner) (models.JournalTemplate, error) {
+	var template models.JournalTemplate
+	var description sql.NullString
+	var fields []byte
+	err := row.Scan(&template.ID, &template.UserID, &template.Name, &description, &fields, &template.CreatedAt, &template.UpdatedAt)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Journal entry not found"})
-		return
+		return template, err
 	}
+	template.Description = description.String
+	return template, json.Unmarshal(fields, &template.Fields)
+}
 
-	// Build update query dynamically
-	updateFields := []string{}
-	args := []interface{}{}
+// fetchJournalTemplate loads one of the user's journal templates
+func fetchJournalTemplate(userID, templateID int) (models.JournalTemplate, error) {
+	return scanJournalTemplate(database.DB.QueryRow(
+		"SELECT "+journalTemplateColumns+" FROM journal_templates WHERE id = ? AND user_id = ?", templateID, userID))
+}
 
-	if req.Mood != nil {
-		updateFields = append(updateFields, "mood = ?")
-		args = append(args, *req.Mood)
+// parseJournalTemplate validates a template request and returns its name and fields
+// as stored
+func parseJournalTemplate(req models.JournalTemplateRequest) (string, string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return "", "", errors.New("Name cannot be empty")
 	}
-	if req.Content != nil {
-		updateFields = append(updateFields, "content = ?")
-		args = append(args, *req.Content)
+	for i := range req.Fields {
+		req.Fields[i].Label = strings.TrimSpace(req.Fields[i].Label)
 	}
-	if req.Tags != nil {
-		updateFields = append(updateFields, "tags = ?")
-		args = append(args, *req.Tags)
+	if err := services.ValidateTemplateFields(req.Fields); err != nil {
+		return "", "", err
 	}
+	fields, err := json.Marshal(req.Fields)
+	return name, string(fields), err
+}
 
-	if len(updateFields) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
-		return
+// journalFieldColumns validates the answers of an entry against its template and returns
+// the values of the fields and fields_text columns
+func journalFieldColumns(template models.JournalTemplate, answers map[string]json.RawMessage) (string, string, error) {
+	values, err := services.ValidateJournalFields(template.Fields, answers)
+	if err != nil {
+		return "", "", err
 	}
+	fields, err := json.Marshal(values)
+	return string(fields), services.JournalFieldsText(template.Fields, values), err
+}
 
-	updateFields = append(updateFields, "updated_at = NOW()")
-	args = append(args, entryID)
-
-	query := fmt.Sprintf("UPDATE journal_entries SET %s WHERE id = ?", strings.Join(updateFields, ", "))
-	_, err = database.DB.Exec(query, args...)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update journal entry"})
-		return
+// journalFieldCondition turns a search filter on a template field into a condition on
+// entries: scales match a number or range ("7", "6-8"), checkboxes "true" or "false",
+// and lists and texts contain the value, ignoring case
+func journalFieldCondition(template models.JournalTemplate, key, value string) (string, []interface{}, error) {
+	var field *models.JournalTemplateField
+	for i := range template.Fields {
+		if template.Fields[i].Key == key {
+			field = &template.Fields[i]
+		}
 	}
+	if field == nil {
+		return "", nil, fmt.Errorf("unknown field %q", key)
+	}
+	// Keys may start with a digit, which a JSON path only allows in a quoted member
+	path := `$."` + field.Key + `"`
 
-	c.JSON(http.StatusOK, gin.H{"message": "Journal entry updated successfully"})
+	switch field.Type {
+	case models.JournalFieldScale:
+		low, high, isRange := strings.Cut(value, "-")
+		if !isRange {
+			high = low
+		}
+		min, errLow := strconv.Atoi(strings.TrimSpace(low))
+		max, errHigh := strconv.Atoi(strings.TrimSpace(high))
+		if errLow != nil || errHigh != nil || min > max {
+			return "", nil, fmt.Errorf("filter on field %q must be a number or a range such as 6-8", key)
+		}
+		return "CAST(JSON_EXTRACT(fields, ?) AS SIGNED) BETWEEN ? AND ?", []interface{}{path, min, max}, nil
+	case models.JournalFieldCheckbox:
+		checked, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("filter on field %q must be true or false", key)
+		}
+		return "JSON_EXTRACT(fields, ?) = CAST(? AS JSON)", []interface{}{path, strconv.FormatBool(checked)}, nil
+	}
+	return "LOWER(JSON_UNQUOTE(JSON_EXTRACT(fields, ?))) LIKE ?", []interface{}{path, "%" + escapeLike(strings.ToLower(value)) + "%"}, nil
 }
 
-// DeleteJournalEntry deletes a journal entry
-func (h *JournalHandler) DeleteJournalEntry(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	entryIDStr := c.Param("id")
-	entryID, err := strconv.Atoi(entryIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entry ID"})
-		return
+// parseJournalTags reads tags sent as a JSON array or comma-separated, dropping
+// empty ones and repetitions
+func parseJournalTags(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	var list []string
+	if strings.HasPrefix(raw, "[") {
+		if err := json.Unmarshal([]byte(raw), &list); err != nil {
+			list = strings.Split(strings.Trim(raw, "[]"), ",")
+		}
+	} else if raw != "" {
+		list = strings.Split(raw, ",")
 	}
 
-	// Verify entry belongs to user
-	var entry models.JournalEntry
-	err = database.DB.QueryRow(`
-		SELECT id, user_id FROM journal_entries WHERE id = ? AND user_id = ?
-	`, entryID, userID).Scan(&entry.ID, &entry.UserID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Journal entry not found"})
-		return
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range list {
+		tag = strings.TrimSpace(strings.Trim(strings.TrimSpace(tag), `"`))
+		if runes := []rune(tag); len(runes) > 255 {
+			tag = string(runes[:255])
+		}
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		tags = append(tags, tag)
 	}
+	return tags
+}
 
-	_, err = database.DB.Exec(`DELETE FROM journal_entries WHERE id = ?`, entryID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete journal entry"})
-		return
+// setJournalTags replaces the tags of an entry, creating tags the user doesn't have yet
+func setJournalTags(tx *sql.Tx, userID, entryID int, tags []string) error {
+	if _, err := tx.Exec("DELETE FROM journal_entry_tags WHERE entry_id = ?", entryID); err != nil {
+		return err
+	}
+	for position, name := range tags {
+		// The unique (user_id, name) key compares case-insensitively, so "Sport" finds "sport"
+		if _, err := tx.Exec("INSERT IGNORE INTO journal_tags (user_id, name) VALUES (?, ?)", userID, name); err != nil {
+			return err
+		}
+		var tagID int
+		if err := tx.QueryRow("SELECT id FROM journal_tags WHERE user_id = ? AND name = ?", userID, name).Scan(&tagID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+			INSERT IGNORE INTO journal_entry_tags (entry_id, tag_id, position) VALUES (?, ?, ?)
+		`, entryID, tagID, position)
+		if err != nil {
+			return err
+		}
 	}
+	return pruneJournalTags(tx, userID)
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Journal entry deleted successfully"})
+// pruneJournalTags deletes the user's tags no entry has anymore
+func pruneJournalTags(db sqlExecer, userID int) error {
+	_, err := db.Exec(`
+		DELETE t FROM journal_tags t
+		LEFT JOIN journal_entry_tags et ON et.tag_id = t.id
+		WHERE t.user_id = ? AND et.tag_id IS NULL
+	`, userID)
+	return err
+}
+
+// fulltextQuery turns search words into a boolean mode full-text query requiring all of
+// them as prefixes. Operators typed by the user are dropped.
+func fulltextQuery(q string) string {
+	words := strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = "+" + word + "*"
+	}
+	return strings.Join(terms, " ")
 }
 
 // GenerateJournalQuestions generates AI questions based on journal context
@@ -1368,6 +6301,81 @@ Kein zusätzlicher Text, nur das JSON-Array.`
 	c.JSON(http.StatusOK, questionObjects)
 }
 
+// Mood insights cover the last 90 days unless ?days asks for up to a year
+const (
+	defaultMoodInsightDays = 90
+	maxMoodInsightDays     = 365
+)
+
+// InsightsHandler handles analytics across habits and journal
+type InsightsHandler struct{}
+
+// NewInsightsHandler creates a new insights handler
+func NewInsightsHandler() *InsightsHandler {
+	return &InsightsHandler{}
+}
+
+// GetMoodInsights returns the user's moods of the last days, their weekly averages and how
+// the mood differs on days each habit was done
+func (h *InsightsHandler) GetMoodInsights(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	days := defaultMoodInsightDays
+	if raw := c.Query("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxMoodInsightDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be between 1 and %d", maxMoodInsightDays)})
+			return
+		}
+		days = n
+	}
+
+	today := services.Today(services.UserLocation(userID.(int)))
+	insights, err := loadMoodInsights(userID.(int), days, today)
+	if err != nil {
+		log.Printf("Failed to load mood insights: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load mood insights"})
+		return
+	}
+	c.JSON(http.StatusOK, insights)
+}
+
+// loadMoodInsights relates the user's moods to their habits over the days up to today
+func loadMoodInsights(userID int, days int, today time.Time) (services.MoodInsights, error) {
+	from := today.AddDate(0, 0, 1-days)
+	moods, err := services.LoadMoodDays(userID, from, today)
+	if err != nil {
+		return services.MoodInsights{}, err
+	}
+	habits, err := fetchUserHabits(userID, false)
+	if err != nil {
+		return services.MoodInsights{}, fmt.Errorf("failed to query habits: %v", err)
+	}
+	histories, err := loadHabitHistories(userID, habits, today.Location())
+	if err != nil {
+		return services.MoodInsights{}, err
+	}
+
+	moodHabits := make([]services.MoodHabit, len(histories))
+	for i, history := range histories {
+		moodHabits[i] = services.MoodHabit{Name: habits[i].Name, History: history}
+	}
+	insights := services.AnalyzeMood(moods, moodHabits)
+	insights.From, insights.To = from.Format(services.DateLayout), today.Format(services.DateLayout)
+	return insights, nil
+}
+
+// coachMoodInsights loads the mood insights the AI coach is given, or nil when they
+// can't be loaded
+func coachMoodInsights(userID int) *services.MoodInsights {
+	insights, err := loadMoodInsights(userID, defaultMoodInsightDays, services.Today(services.UserLocation(userID)))
+	if err != nil {
+		log.Printf("Failed to load mood insights: %v", err)
+		return nil
+	}
+	return &insights
+}
+
 // ChatHandler handles AI Coach chat endpoints
 type ChatHandler struct {
 	openAIService *services.OpenAIService
@@ -1578,7 +6586,7 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 	}
 
 	// Build RAG context from user data
-	userContext, err := services.BuildUserContext(userID.(int))
+	userContext, err := services.BuildUserContext(userID.(int), coachMoodInsights(userID.(int)))
 	if err != nil {
 		log.Printf("Failed to build user context: %v", err)
 		userContext = "" // Continue without context if it fails
@@ -1676,15 +6684,17 @@ func (h *NoteHandler) GetNotes(c *gin.Context) {
 		placeholders = placeholders[:len(placeholders)-1]
 		
 		checklistRows, err := database.DB.Query(fmt.Sprintf(`
-			SELECT id, note_id, text, is_checked, position, created_at, updated_at
-			FROM checklist_items WHERE note_id IN (%s)
-			ORDER BY note_id, position ASC, created_at ASC
+			SELECT ci.id, ci.note_id, ci.text, ci.is_checked, ci.position, t.id, ci.created_at, ci.updated_at
+			FROM checklist_items ci
+			LEFT JOIN tasks t ON t.checklist_item_id = ci.id
+			WHERE ci.note_id IN (%s)
+			ORDER BY ci.note_id, ci.position ASC, ci.created_at ASC
 		`, placeholders), convertIntsToInterface(noteIDs)...)
 		if err == nil {
 			defer checklistRows.Close()
 			for checklistRows.Next() {
 				var item models.ChecklistItem
-				err := checklistRows.Scan(&item.ID, &item.NoteID, &item.Text, &item.IsChecked, &item.Position, &item.CreatedAt, &item.UpdatedAt)
+				err := checklistRows.Scan(&item.ID, &item.NoteID, &item.Text, &item.IsChecked, &item.Position, &item.TaskID, &item.CreatedAt, &item.UpdatedAt)
 				if err == nil {
 					checklistMap[item.NoteID] = append(checklistMap[item.NoteID], item)
 				}
@@ -2035,6 +7045,17 @@ func (h *NoteHandler) UpdateChecklistItem(c *gin.Context) {
 		return
 	}
 
+	// A task promoted from the item is completed along with it
+	if req.IsChecked != nil {
+		_, err = database.DB.Exec(`
+			UPDATE tasks SET completed_at = IF(?, COALESCE(completed_at, NOW()), NULL)
+			WHERE checklist_item_id = ?
+		`, *req.IsChecked, itemID)
+		if err != nil {
+			log.Printf("Failed to sync task of checklist item %d: %v", itemID, err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Checklist item updated successfully"})
 }
 
@@ -2069,6 +7090,268 @@ func (h *NoteHandler) DeleteChecklistItem(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Checklist item deleted successfully"})
 }
 
+// PromoteChecklistItem turns a checklist item into a task linked to it. Without a due date
+// in the request, the task is due on the plan milestone the item belongs to.
+func (h *NoteHandler) PromoteChecklistItem(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
+		return
+	}
+	itemID, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	var req models.PromoteChecklistItemRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Priority == "" {
+		req.Priority = "medium"
+	}
+
+	// Verify item belongs to user's note
+	var item models.ChecklistItem
+	var noteTitle string
+	err = database.DB.QueryRow(`
+		SELECT ci.id, ci.note_id, ci.text, ci.is_checked, ci.position, t.id, n.title
+		FROM checklist_items ci
+		INNER JOIN notes n ON ci.note_id = n.id
+		LEFT JOIN tasks t ON t.checklist_item_id = ci.id
+		WHERE ci.id = ? AND ci.note_id = ? AND n.user_id = ?
+	`, itemID, noteID, userID).Scan(&item.ID, &item.NoteID, &item.Text, &item.IsChecked, &item.Position, &item.TaskID, &noteTitle)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Checklist item not found"})
+		return
+	}
+	if item.TaskID != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Checklist item is already a task", "task_id": *item.TaskID})
+		return
+	}
+	if req.ProjectID != nil {
+		msg, err := checkTaskProject(userID.(int), *req.ProjectID)
+		if err != nil {
+			log.Printf("Failed to check project for user %v: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check project"})
+			return
+		}
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
+	dueDate := req.DueDate
+	if dueDate == nil {
+		milestone, ok, err := itemMilestone(noteID, userID.(int), item)
+		if err != nil {
+			log.Printf("Failed to read milestones of note %d: %v", noteID, err)
+		} else if ok {
+			due := services.DueAt(milestone.Date)
+			dueDate = &due
+		}
+	}
+
+	var completedAt *time.Time
+	if item.IsChecked {
+		now := time.Now()
+		completedAt = &now
+	}
+	position, err := nextTaskPosition(userID.(int), nil)
+	if err != nil {
+		log.Printf("Failed to determine task position for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		return
+	}
+	result, err := database.DB.Exec(`
+		INSERT INTO tasks (user_id, title, description, priority, due_date, completed_at, position, project_id, note_id, checklist_item_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, userID, item.Text, fmt.Sprintf("Aus dem Plan \"%s\"", noteTitle), req.Priority, dueDate, completedAt, position, req.ProjectID, noteID, itemID)
+	if database.IsDuplicateKey(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Checklist item is already a task"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to promote checklist item %d: %v", itemID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		return
+	}
+
+	taskID, _ := result.LastInsertId()
+	task, err := fetchTaskWithLabels(int(taskID), userID.(int))
+	if err != nil {
+		log.Printf("Failed to fetch task %d: %v", taskID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, task)
+}
+
+// GetPlanProgress returns how far the checklist of a plan is done and its milestones
+func (h *NoteHandler) GetPlanProgress(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	noteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
+		return
+	}
+
+	// Verify note belongs to user
+	var exists int
+	err = database.DB.QueryRow(`
+		SELECT 1 FROM notes WHERE id = ? AND user_id = ?
+	`, noteID, userID).Scan(&exists)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+
+	progress := models.PlanProgress{NoteID: noteID, Milestones: []models.PlanMilestone{}}
+	err = database.DB.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(ci.is_checked), 0), COUNT(t.id),
+		       COALESCE(SUM(t.completed_at IS NULL AND t.due_date < NOW()), 0)
+		FROM checklist_items ci
+		LEFT JOIN tasks t ON t.checklist_item_id = ci.id
+		WHERE ci.note_id = ?
+	`, noteID).Scan(&progress.TotalItems, &progress.CheckedItems, &progress.PromotedItems, &progress.OverdueTasks)
+	if err != nil {
+		log.Printf("Failed to count checklist items of note %d: %v", noteID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch plan progress"})
+		return
+	}
+	if progress.TotalItems > 0 {
+		progress.Progress = math.Round(float64(progress.CheckedItems)/float64(progress.TotalItems)*1000) / 10
+	}
+
+	milestones, err := planMilestones(noteID, userID.(int))
+	if err != nil {
+		log.Printf("Failed to read milestones of note %d: %v", noteID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch plan progress"})
+		return
+	}
+	milestoneItems, err := milestoneChecklistItems(noteID, milestones)
+	if err != nil {
+		log.Printf("Failed to assign checklist items of note %d to milestones: %v", noteID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch plan progress"})
+		return
+	}
+	today := services.Today(services.UserLocation(userID.(int)))
+	for _, m := range milestones {
+		items := milestoneItems[m.Date.Format(services.DateLayout)]
+		milestone := models.PlanMilestone{
+			Text:         m.Text,
+			Date:         m.Date.Format(services.DateLayout),
+			TotalItems:   items.total,
+			CheckedItems: items.checked,
+			IsReached:    items.total > 0 && items.checked == items.total,
+			IsPast:       m.Date.Before(today),
+		}
+		progress.Milestones = append(progress.Milestones, milestone)
+		// Past milestones without items leave nothing to do
+		if progress.NextMilestone == nil && !milestone.IsReached && (milestone.TotalItems > 0 || !milestone.IsPast) {
+			progress.NextMilestone = &milestone
+		}
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// checklistCount is how many checklist items there are and how many are checked
+type checklistCount struct {
+	total, checked int
+}
+
+// milestoneChecklistItems counts the checklist items of a note belonging to each
+// milestone, as services.MilestoneFor assigns them, by the day of the milestone
+func milestoneChecklistItems(noteID int, milestones []services.Milestone) (map[string]checklistCount, error) {
+	counts := make(map[string]checklistCount)
+	if len(milestones) == 0 {
+		return counts, nil
+	}
+	rows, err := database.DB.Query(`
+		SELECT text, is_checked FROM checklist_items WHERE note_id = ? ORDER BY position, id
+	`, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type checklistEntry struct {
+		text    string
+		checked bool
+	}
+	var entries []checklistEntry
+	for rows.Next() {
+		var entry checklistEntry
+		if err := rows.Scan(&entry.text, &entry.checked); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, entry := range entries {
+		m, ok := services.MilestoneFor(milestones, entry.text, i, len(entries))
+		if !ok {
+			continue
+		}
+		key := m.Date.Format(services.DateLayout)
+		count := counts[key]
+		count.total++
+		if entry.checked {
+			count.checked++
+		}
+		counts[key] = count
+	}
+	return counts, nil
+}
+
+// planMilestones reads the dated milestones of a note's plan. Relative dates count from
+// the day the plan was created, in the user's time zone, so they don't move when the
+// plan is edited later.
+func planMilestones(noteID, userID int) ([]services.Milestone, error) {
+	var timeAndMilestones, generatedPlan sql.NullString
+	var createdAt time.Time
+	err := database.DB.QueryRow(`
+		SELECT time_and_milestones, generated_plan, created_at FROM plan_data WHERE note_id = ?
+	`, noteID).Scan(&timeAndMilestones, &generatedPlan, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ref := createdAt.In(services.UserLocation(userID))
+	return services.ParseMilestones(ref, timeAndMilestones.String, generatedPlan.String), nil
+}
+
+// itemMilestone finds the plan milestone a checklist item belongs to
+func itemMilestone(noteID, userID int, item models.ChecklistItem) (services.Milestone, bool, error) {
+	milestones, err := planMilestones(noteID, userID)
+	if err != nil || len(milestones) == 0 {
+		return services.Milestone{}, false, err
+	}
+	var index, count int
+	err = database.DB.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(position < ? OR (position = ? AND id < ?)), 0)
+		FROM checklist_items WHERE note_id = ?
+	`, item.Position, item.Position, item.ID, noteID).Scan(&count, &index)
+	if err != nil {
+		return services.Milestone{}, false, err
+	}
+	milestone, ok := services.MilestoneFor(milestones, item.Text, index, count)
+	return milestone, ok, nil
+}
+
 // Helper function to convert int slice to interface slice
 func convertIntsToInterface(ints []int) []interface{} {
 	result := make([]interface{}, len(ints))
@@ -3064,7 +8347,7 @@ func (h *MeditationHandler) StartMeditation(c *gin.Context) {
 	sessionID, _ := result.LastInsertId()
 
 	// Build user context for AI
-	userContext, err := services.BuildUserContext(userID.(int))
+	userContext, err := services.BuildUserContext(userID.(int), nil)
 	if err != nil {
 		log.Printf("Failed to build user context: %v", err)
 		userContext = "" // Continue without context if it fails
@@ -3197,7 +8480,7 @@ func (h *MeditationHandler) SendMeditationMessage(c *gin.Context) {
 	}
 
 	// Build user context
-	userContext, err := services.BuildUserContext(userID.(int))
+	userContext, err := services.BuildUserContext(userID.(int), nil)
 	if err != nil {
 		log.Printf("Failed to build user context: %v", err)
 		userContext = ""
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"habit-tracker-backend/internal/models"
+	"habit-tracker-backend/internal/services"
+)
+
+// testSeries is a series of the rule whose first occurrence is on start at 9:30
+func testSeries(t *testing.T, raw string, start time.Time) taskSeries {
+	t.Helper()
+	rule, err := services.ParseRecurrenceRule(raw)
+	if err != nil {
+		t.Fatalf("ParseRecurrenceRule(%q) failed: %v", raw, err)
+	}
+	return taskSeries{rule: rule, start: start.Add(9*time.Hour + 30*time.Minute)}
+}
+
+func testDay(month time.Month, day int) time.Time {
+	return time.Date(2026, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestSplitDay(t *testing.T) {
+	series := testSeries(t, "FREQ=WEEKLY;BYDAY=MO,WE,FR", testDay(1, 5))
+	occurrence := func(date string) models.Task {
+		return models.Task{OccurrenceDate: &date}
+	}
+	tests := []struct {
+		name    string
+		task    models.Task
+		scope   string
+		want    *time.Time
+		wantErr bool
+	}{
+		{name: "this", task: occurrence("2026-01-12"), scope: scopeThis},
+		{name: "all", task: occurrence("2026-01-12"), scope: scopeAll},
+		{name: "following from the first occurrence", task: occurrence("2026-01-05"), scope: scopeFollowing},
+		{name: "following from the template", task: models.Task{}, scope: scopeFollowing},
+		{name: "following from a later occurrence", task: occurrence("2026-01-12"), scope: scopeFollowing, want: &[]time.Time{testDay(1, 12)}[0]},
+		{name: "invalid occurrence date", task: occurrence("12.01.2026"), scope: scopeFollowing, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := series.splitDay(tt.task, tt.scope)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitDay() error = %v, want error %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || got != nil && !got.Equal(*tt.want) {
+				t.Errorf("splitDay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrencesBefore(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   string
+		start  time.Time
+		before time.Time
+		want   int
+	}{
+		{name: "first occurrence", rule: "FREQ=DAILY", start: testDay(1, 5), before: testDay(1, 5), want: 0},
+		{name: "daily", rule: "FREQ=DAILY", start: testDay(1, 5), before: testDay(1, 12), want: 7},
+		{name: "interval with weekdays", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", start: testDay(1, 8), before: testDay(2, 2), want: 3},
+		{name: "last friday", rule: "FREQ=MONTHLY;BYDAY=-1FR", start: testDay(1, 1), before: testDay(3, 27), want: 2},
+		{name: "31st", rule: "FREQ=MONTHLY;BYMONTHDAY=31", start: testDay(1, 31), before: testDay(5, 31), want: 2},
+		{name: "after the count ran out", rule: "FREQ=DAILY;COUNT=3", start: testDay(1, 5), before: testDay(1, 20), want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series := testSeries(t, tt.rule, tt.start)
+			if got := series.occurrencesBefore(tt.before); got != tt.want {
+				t.Errorf("occurrencesBefore(%s) = %d, want %d", tt.before.Format(services.DateLayout), got, tt.want)
+			}
+		})
+	}
+}
+
+// A split series keeps the occurrences of the original: the old part ends before the
+// split and the new one starting there continues its COUNT
+func TestSplitSeriesKeepsOccurrences(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		split time.Time
+	}{
+		{name: "count with weekdays", rule: "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=6", start: testDay(1, 5), split: testDay(1, 12)},
+		{name: "count with last friday", rule: "FREQ=MONTHLY;BYDAY=-1FR;COUNT=4", start: testDay(1, 1), split: testDay(2, 27)},
+		{name: "interval with weekdays", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=5", start: testDay(1, 8), split: testDay(1, 22)},
+		{name: "31st", rule: "FREQ=MONTHLY;BYMONTHDAY=31;COUNT=4", start: testDay(1, 31), split: testDay(5, 31)},
+		{name: "no count", rule: "FREQ=DAILY", start: testDay(1, 5), split: testDay(1, 9)},
+	}
+	from, to := testDay(1, 1), testDay(12, 31)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series := testSeries(t, tt.rule, tt.start)
+			want := series.rule.Occurrences(series.start, from, to)
+
+			got := series.endBefore(tt.split).Occurrences(series.start, from, to)
+			got = append(got, series.continueAt(tt.split).Occurrences(series.occurrence(tt.split), from, to)...)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("split at %s gives %v, want %v", tt.split.Format(services.DateLayout), got, want)
+			}
+		})
+	}
+}
+
+func TestJournalFieldCondition(t *testing.T) {
+	template := models.JournalTemplate{Fields: []models.JournalTemplateField{
+		{Key: "energy", Label: "Energie", Type: models.JournalFieldScale},
+		{Key: "3_things", Label: "Drei gute Dinge", Type: models.JournalFieldList},
+		{Key: "sport", Label: "Sport", Type: models.JournalFieldCheckbox},
+	}}
+	tests := []struct {
+		key, value string
+		condition  string
+		args       []interface{}
+		wantErr    bool
+	}{
+		{key: "energy", value: "7", condition: "CAST(JSON_EXTRACT(fields, ?) AS SIGNED) BETWEEN ? AND ?", args: []interface{}{`$."energy"`, 7, 7}},
+		{key: "energy", value: "6-8", condition: "CAST(JSON_EXTRACT(fields, ?) AS SIGNED) BETWEEN ? AND ?", args: []interface{}{`$."energy"`, 6, 8}},
+		{key: "3_things", value: "Kaffee_", condition: "LOWER(JSON_UNQUOTE(JSON_EXTRACT(fields, ?))) LIKE ?", args: []interface{}{`$."3_things"`, `%kaffee\_%`}},
+		{key: "sport", value: "true", condition: "JSON_EXTRACT(fields, ?) = CAST(? AS JSON)", args: []interface{}{`$."sport"`, "true"}},
+		{key: "energy", value: "8-6", wantErr: true},
+		{key: "sport", value: "ja", wantErr: true},
+		{key: "mood", value: "gut", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			condition, args, err := journalFieldCondition(template, tt.key, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("journalFieldCondition() error = %v, want error %v", err, tt.wantErr)
+			}
+			if condition != tt.condition || !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("journalFieldCondition() = %q %v, want %q %v", condition, args, tt.condition, tt.args)
+			}
+		})
+	}
+}
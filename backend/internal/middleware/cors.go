@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig controls which browser origins may call the API
+type CORSConfig struct {
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	ExposedHeaders []string
+	MaxAge         time.Duration // how long browsers may cache a preflight response
+}
+
+// CORSConfigFromEnv reads the CORS configuration from the environment:
+//
+//	CORS_ALLOWED_ORIGINS  comma separated origins, defaults to the local dev servers
+//	CORS_ALLOWED_METHODS  comma separated methods
+//	CORS_ALLOWED_HEADERS  comma separated request headers
+//	CORS_MAX_AGE          preflight cache lifetime, e.g. "2h" or seconds
+func CORSConfigFromEnv() CORSConfig {
+	config := CORSConfig{
+		AllowedOrigins: []string{"http://localhost:5173", "http://127.0.0.1:5173", "http://localhost:3000"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Authorization", "Content-Type", "Accept", "Cache-Control", "X-Requested-With"},
+		ExposedHeaders: []string{"Retry-After", "X-Next-Cursor"},
+		MaxAge:         2 * time.Hour,
+	}
+
+	if origins := splitList(os.Getenv("CORS_ALLOWED_ORIGINS")); len(origins) > 0 {
+		config.AllowedOrigins = origins
+	}
+	if methods := splitList(os.Getenv("CORS_ALLOWED_METHODS")); len(methods) > 0 {
+		config.AllowedMethods = methods
+	}
+	if headers := splitList(os.Getenv("CORS_ALLOWED_HEADERS")); len(headers) > 0 {
+		config.AllowedHeaders = headers
+	}
+	if raw := os.Getenv("CORS_MAX_AGE"); raw != "" {
+		if seconds, err := strconv.Atoi(raw); err == nil {
+			config.MaxAge = time.Duration(seconds) * time.Second
+		} else if d, err := time.ParseDuration(raw); err == nil {
+			config.MaxAge = d
+		} else {
+			log.Printf("Ignoring invalid CORS_MAX_AGE %q", raw)
+		}
+	}
+
+	for _, origin := range config.AllowedOrigins {
+		if origin == "*" {
+			log.Println("WARNING: CORS allows every origin, credentials are not allowed cross-origin")
+		}
+	}
+
+	return config
+}
+
+// CORSMiddleware answers preflight requests and adds CORS headers for allow-listed origins.
+// Requests from other origins are served without CORS headers, so browsers block them.
+func CORSMiddleware(config CORSConfig) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(config.AllowedOrigins))
+	allowAll := false
+	for _, origin := range config.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[strings.TrimRight(origin, "/")] = true
+	}
+	methods := strings.Join(config.AllowedMethods, ", ")
+	headers := strings.Join(config.AllowedHeaders, ", ")
+	exposed := strings.Join(config.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		// The response differs per origin, caches must not share it
+		c.Writer.Header().Add("Vary", "Origin")
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		if !allowAll && !allowed[origin] {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowAll {
+			// Credentials can't be combined with a wildcard origin
+			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.Writer.Header().Set("Access-Control-Allow-Methods", methods)
+			c.Writer.Header().Set("Access-Control-Allow-Headers", headers)
+			c.Writer.Header().Set("Access-Control-Max-Age", maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if exposed != "" {
+			c.Writer.Header().Set("Access-Control-Expose-Headers", exposed)
+		}
+		c.Next()
+	}
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
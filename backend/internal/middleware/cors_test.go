@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com", "http://localhost:5173/"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposedHeaders: []string{"Retry-After"},
+		MaxAge:         2 * time.Hour,
+	}
+	tests := []struct {
+		name        string
+		config      CORSConfig
+		method      string
+		origin      string
+		preflight   bool // sends Access-Control-Request-Method
+		wantCode    int
+		wantHandler bool
+		wantOrigin  string
+		wantCreds   string
+		wantVary    []string
+		wantMaxAge  string
+		wantExposed string
+		wantMethods string
+	}{
+		{
+			name: "allowed origin", config: config, method: http.MethodGet, origin: "https://app.example.com",
+			wantCode: http.StatusOK, wantHandler: true, wantOrigin: "https://app.example.com", wantCreds: "true",
+			wantVary: []string{"Origin"}, wantExposed: "Retry-After",
+		},
+		{
+			name: "configured with a trailing slash", config: config, method: http.MethodGet, origin: "http://localhost:5173",
+			wantCode: http.StatusOK, wantHandler: true, wantOrigin: "http://localhost:5173", wantCreds: "true",
+			wantVary: []string{"Origin"}, wantExposed: "Retry-After",
+		},
+		{
+			name: "no origin", config: config, method: http.MethodGet,
+			wantCode: http.StatusOK, wantHandler: true, wantVary: []string{"Origin"},
+		},
+		{
+			name: "other origin", config: config, method: http.MethodGet, origin: "https://evil.example.com",
+			wantCode: http.StatusOK, wantHandler: true, wantVary: []string{"Origin"},
+		},
+		{
+			name: "allowed origin as a prefix", config: config, method: http.MethodGet, origin: "https://app.example.com.evil.com",
+			wantCode: http.StatusOK, wantHandler: true, wantVary: []string{"Origin"},
+		},
+		{
+			name: "other scheme", config: config, method: http.MethodGet, origin: "http://app.example.com",
+			wantCode: http.StatusOK, wantHandler: true, wantVary: []string{"Origin"},
+		},
+		{
+			name: "other port", config: config, method: http.MethodGet, origin: "http://localhost:3000",
+			wantCode: http.StatusOK, wantHandler: true, wantVary: []string{"Origin"},
+		},
+		{
+			name: "null origin", config: config, method: http.MethodGet, origin: "null",
+			wantCode: http.StatusOK, wantHandler: true, wantVary: []string{"Origin"},
+		},
+		{
+			name: "preflight", config: config, method: http.MethodOptions, origin: "https://app.example.com", preflight: true,
+			wantCode: http.StatusNoContent, wantOrigin: "https://app.example.com", wantCreds: "true",
+			wantVary:   []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			wantMaxAge: "7200", wantMethods: "GET, POST",
+		},
+		{
+			name: "preflight from another origin", config: config, method: http.MethodOptions, origin: "https://evil.example.com", preflight: true,
+			wantCode: http.StatusForbidden,
+			wantVary: []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			name: "OPTIONS that isn't a preflight", config: config, method: http.MethodOptions, origin: "https://app.example.com",
+			wantCode: http.StatusOK, wantHandler: true, wantOrigin: "https://app.example.com", wantCreds: "true",
+			wantVary: []string{"Origin"}, wantExposed: "Retry-After",
+		},
+		{
+			name: "wildcard without credentials", config: CORSConfig{AllowedOrigins: []string{"*"}}, method: http.MethodGet, origin: "https://evil.example.com",
+			wantCode: http.StatusOK, wantHandler: true, wantOrigin: "*", wantVary: []string{"Origin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled := false
+			router := gin.New()
+			router.Use(CORSMiddleware(tt.config))
+			router.Handle(tt.method, "/api/habits", func(c *gin.Context) {
+				handled = true
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, "/api/habits", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+				req.Header.Set("Access-Control-Request-Headers", "authorization")
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode || handled != tt.wantHandler {
+				t.Fatalf("status = %d, handler called = %v, want %d, %v", w.Code, handled, tt.wantCode, tt.wantHandler)
+			}
+			header := w.Header()
+			if got := header.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := header.Get("Access-Control-Allow-Credentials"); got != tt.wantCreds {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.wantCreds)
+			}
+			if got := header.Values("Vary"); !reflect.DeepEqual(got, tt.wantVary) {
+				t.Errorf("Vary = %q, want %q", got, tt.wantVary)
+			}
+			if got := header.Get("Access-Control-Max-Age"); got != tt.wantMaxAge {
+				t.Errorf("Access-Control-Max-Age = %q, want %q", got, tt.wantMaxAge)
+			}
+			if got := header.Get("Access-Control-Expose-Headers"); got != tt.wantExposed {
+				t.Errorf("Access-Control-Expose-Headers = %q, want %q", got, tt.wantExposed)
+			}
+			if got := header.Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, tt.wantMethods)
+			}
+		})
+	}
+}
+
+func TestCORSConfigFromEnv(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "")
+	defaults := CORSConfigFromEnv()
+	tests := []struct {
+		name        string
+		origins     string
+		maxAge      string
+		wantOrigins []string
+		wantMaxAge  time.Duration
+	}{
+		{name: "defaults", wantOrigins: defaults.AllowedOrigins, wantMaxAge: 2 * time.Hour},
+		{
+			name:        "configured",
+			origins:     " https://app.example.com , ,https://admin.example.com",
+			maxAge:      "600",
+			wantOrigins: []string{"https://app.example.com", "https://admin.example.com"},
+			wantMaxAge:  10 * time.Minute,
+		},
+		{name: "max age as a duration", maxAge: "30m", wantOrigins: defaults.AllowedOrigins, wantMaxAge: 30 * time.Minute},
+		{name: "invalid max age", maxAge: "soon", wantOrigins: defaults.AllowedOrigins, wantMaxAge: 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CORS_ALLOWED_ORIGINS", tt.origins)
+			t.Setenv("CORS_MAX_AGE", tt.maxAge)
+
+			config := CORSConfigFromEnv()
+			if !reflect.DeepEqual(config.AllowedOrigins, tt.wantOrigins) {
+				t.Errorf("AllowedOrigins = %q, want %q", config.AllowedOrigins, tt.wantOrigins)
+			}
+			if config.MaxAge != tt.wantMaxAge {
+				t.Errorf("MaxAge = %v, want %v", config.MaxAge, tt.wantMaxAge)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLockoutPolicyDuration(t *testing.T) {
+	policy := LockoutPolicy{FreeAttempts: 5, BaseLockout: 30 * time.Second, MaxLockout: 5 * time.Minute}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 0},
+		{failures: 5, want: 0},
+		{failures: 6, want: 30 * time.Second},
+		{failures: 7, want: time.Minute},
+		{failures: 8, want: 2 * time.Minute},
+		{failures: 9, want: 4 * time.Minute},
+		{failures: 10, want: 5 * time.Minute},
+		{failures: 100, want: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := policy.Duration(tt.failures); got != tt.want {
+			t.Errorf("Duration(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+// loginAttempt is one request to the locked-out login handler
+type loginAttempt struct {
+	email    string
+	ip       string
+	password string        // "right" logs in, anything else fails
+	wait     time.Duration // time passed before the attempt
+}
+
+// newLoginRouter serves a fake login behind LoginLockout and records the emails it saw
+func newLoginRouter(store RateLimitStore, seen *[]string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/login", LoginLockout(store), func(c *gin.Context) {
+		var req struct {
+			Email    string `json:"email"`
+			Password string `json:"password"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		*seen = append(*seen, req.Email)
+		if req.Password != "right" {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func (a loginAttempt) do(router *gin.Engine) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"email": a.email, "password": a.password})
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = a.ip + ":1234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoginLockout(t *testing.T) {
+	wrong := func(email, ip string) loginAttempt { return loginAttempt{email: email, ip: ip, password: "wrong"} }
+	repeat := func(n int, attempt func(i int) loginAttempt) []loginAttempt {
+		attempts := make([]loginAttempt, n)
+		for i := range attempts {
+			attempts[i] = attempt(i)
+		}
+		return attempts
+	}
+	tests := []struct {
+		name     string
+		before   []loginAttempt
+		elapsed  time.Duration // time passed before the last attempt
+		attempt  loginAttempt
+		wantCode int
+	}{
+		{
+			name:     "free attempts",
+			before:   repeat(5, func(int) loginAttempt { return wrong("user@example.com", "203.0.113.1") }),
+			attempt:  loginAttempt{email: "user@example.com", ip: "203.0.113.1", password: "right"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "account locked",
+			before:   repeat(6, func(int) loginAttempt { return wrong("user@example.com", "203.0.113.1") }),
+			attempt:  loginAttempt{email: "user@example.com", ip: "203.0.113.1", password: "right"},
+			wantCode: http.StatusTooManyRequests,
+		},
+		{
+			name:     "account locked from every IP",
+			before:   repeat(6, func(i int) loginAttempt { return wrong("user@example.com", fmt.Sprintf("203.0.113.%d", i+1)) }),
+			attempt:  loginAttempt{email: "user@example.com", ip: "198.51.100.1", password: "right"},
+			wantCode: http.StatusTooManyRequests,
+		},
+		{
+			name:     "email is case insensitive",
+			before:   repeat(6, func(int) loginAttempt { return wrong(" User@Example.com", "203.0.113.1") }),
+			attempt:  loginAttempt{email: "user@example.com", ip: "198.51.100.1", password: "right"},
+			wantCode: http.StatusTooManyRequests,
+		},
+		{
+			name:     "other accounts aren't locked",
+			before:   repeat(6, func(int) loginAttempt { return wrong("user@example.com", "203.0.113.1") }),
+			attempt:  loginAttempt{email: "other@example.com", ip: "198.51.100.1", password: "right"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "lockout expired",
+			before:   repeat(6, func(int) loginAttempt { return wrong("user@example.com", "203.0.113.1") }),
+			elapsed:  30 * time.Second,
+			attempt:  loginAttempt{email: "user@example.com", ip: "203.0.113.1", password: "right"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "lockout doubles",
+			before: append(
+				repeat(6, func(int) loginAttempt { return wrong("user@example.com", "203.0.113.1") }),
+				loginAttempt{email: "user@example.com", ip: "203.0.113.1", password: "wrong", wait: 30 * time.Second},
+			),
+			elapsed:  30 * time.Second,
+			attempt:  loginAttempt{email: "user@example.com", ip: "203.0.113.1", password: "right"},
+			wantCode: http.StatusTooManyRequests,
+		},
+		{
+			name: "success resets the account",
+			before: append(
+				repeat(5, func(int) loginAttempt { return wrong("user@example.com", "203.0.113.1") }),
+				append(
+					[]loginAttempt{{email: "user@example.com", ip: "203.0.113.1", password: "right"}},
+					repeat(5, func(int) loginAttempt { return wrong("user@example.com", "203.0.113.1") })...,
+				)...,
+			),
+			attempt:  loginAttempt{email: "user@example.com", ip: "203.0.113.1", password: "right"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "IP locked across accounts",
+			before:   repeat(21, func(i int) loginAttempt { return wrong(fmt.Sprintf("user%d@example.com", i), "203.0.113.1") }),
+			attempt:  loginAttempt{email: "other@example.com", ip: "203.0.113.1", password: "right"},
+			wantCode: http.StatusTooManyRequests,
+		},
+		{
+			name:     "IP lockout spares other IPs",
+			before:   repeat(21, func(i int) loginAttempt { return wrong(fmt.Sprintf("user%d@example.com", i), "203.0.113.1") }),
+			attempt:  loginAttempt{email: "other@example.com", ip: "198.51.100.1", password: "right"},
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, clock := newTestStore()
+			var seen []string
+			router := newLoginRouter(store, &seen)
+			for i, attempt := range tt.before {
+				clock.Advance(attempt.wait)
+				if w := attempt.do(router); w.Code == http.StatusTooManyRequests {
+					t.Fatalf("attempt %d was locked out before the tested one", i+1)
+				}
+			}
+			clock.Advance(tt.elapsed)
+
+			seenBefore := len(seen)
+			w := tt.attempt.do(router)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			reachedHandler := len(seen) > seenBefore
+			if reachedHandler != (tt.wantCode != http.StatusTooManyRequests) {
+				t.Errorf("login handler called = %v for status %d", reachedHandler, w.Code)
+			}
+			if reachedHandler && seen[len(seen)-1] != tt.attempt.email {
+				t.Errorf("login handler read email %q, want %q", seen[len(seen)-1], tt.attempt.email)
+			}
+			if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Error("429 without Retry-After")
+			}
+		})
+	}
+}
+
+// Attempts while locked out don't extend the lockout
+func TestLoginLockoutRejectedAttemptsArentCounted(t *testing.T) {
+	store, clock := newTestStore()
+	var seen []string
+	router := newLoginRouter(store, &seen)
+	for i := 0; i < 6; i++ {
+		loginAttempt{email: "user@example.com", ip: "203.0.113.1", password: "wrong"}.do(router)
+	}
+	for i := 0; i < 10; i++ {
+		loginAttempt{email: "user@example.com", ip: "203.0.113.1", password: "wrong"}.do(router)
+	}
+	clock.Advance(30 * time.Second)
+
+	w := loginAttempt{email: "user@example.com", ip: "203.0.113.1", password: "right"}.do(router)
+	if w.Code != http.StatusOK {
+		body, _ := io.ReadAll(w.Body)
+		t.Errorf("status = %d (%s), want 200 once the first lockout expired", w.Code, body)
+	}
+}
@@ -0,0 +1,253 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limit is a token bucket: up to Burst requests at once, refilled by one token every Every
+type Limit struct {
+	Burst int
+	Every time.Duration
+}
+
+// PerPeriod allows n requests per period, all of which may be used at once
+func PerPeriod(n int, period time.Duration) Limit {
+	return Limit{Burst: n, Every: period / time.Duration(n)}
+}
+
+// LimitFromEnv reads a limit such as "30/1h" (30 requests per hour) from an
+// environment variable, falling back to def when unset or invalid
+func LimitFromEnv(name string, def Limit) Limit {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	limit, err := ParseLimit(raw)
+	if err != nil {
+		log.Printf("Ignoring %s: %v", name, err)
+		return def
+	}
+	return limit
+}
+
+// ParseLimit parses "<requests>/<duration>", e.g. "5/1m"
+func ParseLimit(raw string) (Limit, error) {
+	parts := strings.SplitN(raw, "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected <requests>/<duration>", raw)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid request count in rate limit %q", raw)
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("invalid period in rate limit %q", raw)
+	}
+	return PerPeriod(n, period), nil
+}
+
+// RateLimitStore keeps rate limit state. The in-memory store only works for a
+// single instance; running several replicas needs a shared implementation (e.g. Redis).
+type RateLimitStore interface {
+	// Take consumes one token from the bucket for key. When the bucket is empty
+	// it reports how long until the next token is available.
+	Take(key string, limit Limit) (allowed bool, retryAfter time.Duration, err error)
+
+	// LockedFor returns the remaining lockout for key, zero if it is not locked
+	LockedFor(key string) (time.Duration, error)
+	// RecordFailure counts a failed attempt for key and returns the number of
+	// consecutive failures. lockout maps that number to how long key gets locked.
+	RecordFailure(key string, lockout func(failures int) time.Duration) (int, error)
+	// ResetFailures clears the failure count and lockout of key
+	ResetFailures(key string) error
+}
+
+// RateLimit rejects requests with 429 once the bucket of the caller is empty.
+// Requests are keyed by user ID when authenticated, otherwise by client IP, and
+// every scope has its own buckets so route groups don't share a budget.
+func RateLimit(store RateLimitStore, scope string, limit Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "rl:" + scope + ":" + clientKey(c)
+
+		allowed, retryAfter, err := store.Take(key, limit)
+		if err != nil {
+			// Fail open: a broken limiter backend must not take the API down
+			log.Printf("Rate limiter error for %s: %v", key, err)
+			c.Next()
+			return
+		}
+		if !allowed {
+			tooManyRequests(c, retryAfter)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// TrustedProxiesFromEnv reads TRUSTED_PROXIES, the comma separated IPs or CIDR ranges
+// of reverse proxies whose X-Forwarded-For header gives the client address. Without
+// it no proxy is trusted and the client is the connecting address, so callers can't
+// pick their own IP to get around per-IP limits and lockouts.
+func TrustedProxiesFromEnv() []string {
+	return splitList(os.Getenv("TRUSTED_PROXIES"))
+}
+
+// clientKey identifies the caller for rate limiting; behind a proxy c.ClientIP is only
+// taken from X-Forwarded-For if the proxy is trusted (see TrustedProxiesFromEnv)
+func clientKey(c *gin.Context) string {
+	if userID, exists := c.Get("user_id"); exists {
+		return fmt.Sprintf("user:%v", userID)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// tooManyRequests aborts with 429 and a Retry-After header in whole seconds
+func tooManyRequests(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many requests, please try again later",
+		"retry_after": seconds,
+	})
+	c.Abort()
+}
+
+// MemoryStore is an in-process RateLimitStore
+type MemoryStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	failures map[string]*failureRecord
+	now      func() time.Time // the clock, time.Now outside tests
+}
+
+type bucket struct {
+	tokens   float64
+	last     time.Time
+	capacity int
+	every    time.Duration
+}
+
+type failureRecord struct {
+	count       int
+	lockedUntil time.Time
+	last        time.Time
+}
+
+// failureMemory is how long failed attempts are remembered without a new failure
+const failureMemory = 24 * time.Hour
+
+// NewMemoryStore creates an in-memory store that periodically drops idle entries
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		buckets:  make(map[string]*bucket),
+		failures: make(map[string]*failureRecord),
+		now:      time.Now,
+	}
+	go s.cleanup(10 * time.Minute)
+	return s
+}
+
+// Take implements RateLimitStore
+func (s *MemoryStore) Take(key string, limit Limit) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+	b.capacity, b.every = limit.Burst, limit.Every
+
+	// Refill for the time passed since the last request
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+float64(now.Sub(b.last))/float64(limit.Every))
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	return false, time.Duration((1 - b.tokens) * float64(limit.Every)), nil
+}
+
+// LockedFor implements RateLimitStore
+func (s *MemoryStore) LockedFor(key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.failures[key]
+	if !ok {
+		return 0, nil
+	}
+	if remaining := record.lockedUntil.Sub(s.now()); remaining > 0 {
+		return remaining, nil
+	}
+	return 0, nil
+}
+
+// RecordFailure implements RateLimitStore
+func (s *MemoryStore) RecordFailure(key string, lockout func(failures int) time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	record, ok := s.failures[key]
+	if !ok || now.Sub(record.last) > failureMemory {
+		record = &failureRecord{}
+		s.failures[key] = record
+	}
+	record.count++
+	record.last = now
+	if d := lockout(record.count); d > 0 {
+		record.lockedUntil = now.Add(d)
+	}
+	return record.count, nil
+}
+
+// ResetFailures implements RateLimitStore
+func (s *MemoryStore) ResetFailures(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, key)
+	return nil
+}
+
+// cleanup drops full buckets and forgotten failure records
+func (s *MemoryStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := s.now()
+		s.mu.Lock()
+		for key, b := range s.buckets {
+			// A bucket that has refilled completely is the same as a new one
+			if now.Sub(b.last) > time.Duration(b.capacity)*b.every {
+				delete(s.buckets, key)
+			}
+		}
+		for key, record := range s.failures {
+			if now.Sub(record.last) > failureMemory && now.After(record.lockedUntil) {
+				delete(s.failures, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testClock is a clock that only moves when told to
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time          { return c.now }
+func (c *testClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newTestStore returns a MemoryStore on a test clock, without the cleanup goroutine
+func newTestStore() (*MemoryStore, *testClock) {
+	clock := &testClock{now: time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)}
+	return &MemoryStore{
+		buckets:  make(map[string]*bucket),
+		failures: make(map[string]*failureRecord),
+		now:      clock.Now,
+	}, clock
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    Limit
+		wantErr bool
+	}{
+		{raw: "5/1m", want: Limit{Burst: 5, Every: 12 * time.Second}},
+		{raw: "30/1h", want: Limit{Burst: 30, Every: 2 * time.Minute}},
+		{raw: " 10 / 10s ", want: Limit{Burst: 10, Every: time.Second}},
+		{raw: "5", wantErr: true},
+		{raw: "five/1m", wantErr: true},
+		{raw: "0/1m", wantErr: true},
+		{raw: "-1/1m", wantErr: true},
+		{raw: "5/minute", wantErr: true},
+		{raw: "5/0s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseLimit(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit(%q) error = %v, want error %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitFromEnv(t *testing.T) {
+	def := PerPeriod(5, time.Minute)
+	tests := []struct {
+		name string
+		raw  string
+		want Limit
+	}{
+		{name: "unset", want: def},
+		{name: "configured", raw: "60/1h", want: Limit{Burst: 60, Every: time.Minute}},
+		{name: "invalid", raw: "lots", want: def},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_RATE_LIMIT", tt.raw)
+			if got := LimitFromEnv("TEST_RATE_LIMIT", def); got != tt.want {
+				t.Errorf("LimitFromEnv() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	limit := Limit{Burst: 3, Every: 10 * time.Second}
+	tests := []struct {
+		name           string
+		taken          int           // tokens taken at once beforehand
+		elapsed        time.Duration // time passed before the next request
+		wantAllowed    bool
+		wantRetryAfter time.Duration
+	}{
+		{name: "within the burst", taken: 2, wantAllowed: true},
+		{name: "burst used up", taken: 3, wantRetryAfter: 10 * time.Second},
+		{name: "partly refilled", taken: 3, elapsed: 4 * time.Second, wantRetryAfter: 6 * time.Second},
+		{name: "one token refilled", taken: 3, elapsed: 10 * time.Second, wantAllowed: true},
+		{name: "refill is capped at the burst", taken: 3, elapsed: time.Hour, wantAllowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, clock := newTestStore()
+			for i := 0; i < tt.taken; i++ {
+				if allowed, _, _ := store.Take("key", limit); !allowed {
+					t.Fatalf("request %d of the burst was rejected", i+1)
+				}
+			}
+			clock.Advance(tt.elapsed)
+
+			allowed, retryAfter, err := store.Take("key", limit)
+			if err != nil {
+				t.Fatalf("Take() failed: %v", err)
+			}
+			if allowed != tt.wantAllowed || retryAfter != tt.wantRetryAfter {
+				t.Errorf("Take() = %v, %v, want %v, %v", allowed, retryAfter, tt.wantAllowed, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+// A bucket refilled after a long pause allows one burst, not the tokens of the whole pause
+func TestMemoryStoreTakeBurstAfterPause(t *testing.T) {
+	store, clock := newTestStore()
+	limit := Limit{Burst: 3, Every: 10 * time.Second}
+	store.Take("key", limit)
+	clock.Advance(time.Hour)
+
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if ok, _, _ := store.Take("key", limit); ok {
+			allowed++
+		}
+	}
+	if allowed != limit.Burst {
+		t.Errorf("allowed %d requests after the pause, want %d", allowed, limit.Burst)
+	}
+	if ok, _, _ := store.Take("other", limit); !ok {
+		t.Error("another key shares the empty bucket")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddrs    []string // of the two requests
+		forwardedFor   []string
+		userIDs        []int // 0 for anonymous requests
+		wantSecond     int
+	}{
+		{
+			name:        "same client",
+			remoteAddrs: []string{"203.0.113.1:1000", "203.0.113.1:2000"},
+			wantSecond:  http.StatusTooManyRequests,
+		},
+		{
+			name:        "other client",
+			remoteAddrs: []string{"203.0.113.1:1000", "203.0.113.2:1000"},
+			wantSecond:  http.StatusOK,
+		},
+		{
+			name:         "spoofed X-Forwarded-For from an untrusted client",
+			remoteAddrs:  []string{"203.0.113.1:1000", "203.0.113.1:1000"},
+			forwardedFor: []string{"198.51.100.1", "198.51.100.2"},
+			wantSecond:   http.StatusTooManyRequests,
+		},
+		{
+			name:           "X-Forwarded-For from a trusted proxy",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddrs:    []string{"10.0.0.1:1000", "10.0.0.1:1000"},
+			forwardedFor:   []string{"198.51.100.1", "198.51.100.2"},
+			wantSecond:     http.StatusOK,
+		},
+		{
+			name:        "users behind the same IP",
+			remoteAddrs: []string{"203.0.113.1:1000", "203.0.113.1:1000"},
+			userIDs:     []int{1, 2},
+			wantSecond:  http.StatusOK,
+		},
+		{
+			name:        "same user from another IP",
+			remoteAddrs: []string{"203.0.113.1:1000", "203.0.113.2:1000"},
+			userIDs:     []int{1, 1},
+			wantSecond:  http.StatusTooManyRequests,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, _ := newTestStore()
+			router := gin.New()
+			if err := router.SetTrustedProxies(tt.trustedProxies); err != nil {
+				t.Fatalf("SetTrustedProxies() failed: %v", err)
+			}
+			router.Use(func(c *gin.Context) {
+				if id := c.GetHeader("X-Test-User"); id != "" {
+					c.Set("user_id", id)
+				}
+			})
+			router.GET("/", RateLimit(store, "test", Limit{Burst: 1, Every: time.Minute}), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			var codes []int
+			var last *httptest.ResponseRecorder
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr = tt.remoteAddrs[i]
+				if tt.forwardedFor != nil {
+					req.Header.Set("X-Forwarded-For", tt.forwardedFor[i])
+				}
+				if tt.userIDs != nil {
+					req.Header.Set("X-Test-User", strconv.Itoa(tt.userIDs[i]))
+				}
+				last = httptest.NewRecorder()
+				router.ServeHTTP(last, req)
+				codes = append(codes, last.Code)
+			}
+
+			if codes[0] != http.StatusOK || codes[1] != tt.wantSecond {
+				t.Fatalf("status codes = %v, want [200 %d]", codes, tt.wantSecond)
+			}
+			if tt.wantSecond == http.StatusTooManyRequests && last.Header().Get("Retry-After") != "60" {
+				t.Errorf("Retry-After = %q, want %q", last.Header().Get("Retry-After"), "60")
+			}
+		})
+	}
+}
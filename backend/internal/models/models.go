@@ -1,43 +1,64 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
 // User represents a user in the system
 type User struct {
-	ID           int       `json:"id" db:"id"`
-	Email        string    `json:"email" db:"email"`
-	PasswordHash string    `json:"-" db:"password_hash"`
-	Name         string    `json:"name" db:"name"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
-	Preferences  string    `json:"preferences" db:"preferences"`
-	Settings     string    `json:"settings" db:"settings"`
-}
-
-// UserSession represents a user session
+	ID              int        `json:"id" db:"id"`
+	Email           string     `json:"email" db:"email"`
+	PasswordHash    string     `json:"-" db:"password_hash"`
+	Name            string     `json:"name" db:"name"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at" db:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+	Preferences     string     `json:"preferences" db:"preferences"`
+	Settings        string     `json:"settings" db:"settings"`
+}
+
+// UserSession represents a refresh token of a user session
 type UserSession struct {
-	ID        int       `json:"id" db:"id"`
-	UserID    int       `json:"user_id" db:"user_id"`
-	Token     string    `json:"token" db:"token"`
-	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID         int        `json:"id" db:"id"`
+	UserID     int        `json:"user_id" db:"user_id"`
+	FamilyID   string     `json:"family_id" db:"family_id"`
+	Token      string     `json:"-" db:"token"` // SHA-256 of the refresh token
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	ReplacedBy *int       `json:"replaced_by" db:"replaced_by"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	IPAddress  string     `json:"ip_address" db:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
 }
 
 // Habit represents a habit
 type Habit struct {
-	ID              int       `json:"id" db:"id"`
-	UserID          int       `json:"user_id" db:"user_id"`
-	Name            string    `json:"name" db:"name"`
-	Description     string    `json:"description" db:"description"`
-	Category        string    `json:"category" db:"category"`
-	Icon            string    `json:"icon" db:"icon"`
-	Color           string    `json:"color" db:"color"`
-	TargetFrequency int       `json:"target_frequency" db:"target_frequency"`
-	IsActive        bool      `json:"is_active" db:"is_active"`
-	CreatedAt       time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+	ID              int           `json:"id" db:"id"`
+	UserID          int           `json:"user_id" db:"user_id"`
+	Name            string        `json:"name" db:"name"`
+	Description     string        `json:"description" db:"description"`
+	Category        string        `json:"category" db:"category"`
+	Icon            string        `json:"icon" db:"icon"`
+	Color           string        `json:"color" db:"color"`
+	TargetFrequency int           `json:"target_frequency" db:"target_frequency"`
+	Schedule        HabitSchedule `json:"schedule"`
+	Unit            *string       `json:"unit" db:"unit"`                   // e.g. "L", "pages", "steps"
+	TargetValue     *float64      `json:"target_value" db:"target_value"`   // daily target, nil for yes/no habits
+	ReminderTime    *string       `json:"reminder_time" db:"reminder_time"` // "HH:MM", reminded if not done by then
+	IsActive        bool          `json:"is_active" db:"is_active"`
+	ArchivedAt      *time.Time    `json:"archived_at" db:"archived_at"`
+	CreatedAt       time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// HabitSchedule describes on which days a habit is expected.
+// Type is one of daily, weekdays, per_week, per_month or interval.
+type HabitSchedule struct {
+	Type         string `json:"type"`
+	Days         []int  `json:"days,omitempty"`          // weekdays, 1 = Monday ... 7 = Sunday
+	Count        int    `json:"count,omitempty"`         // completions per week or month
+	IntervalDays int    `json:"interval_days,omitempty"` // days between completions
 }
 
 // HabitCompletion represents a habit completion
@@ -49,6 +70,41 @@ type HabitCompletion struct {
 	StreakCount  int       `json:"streak_count" db:"streak_count"`
 }
 
+// HabitCategory is a user-defined group habits are sorted into; habits refer to it by name
+type HabitCategory struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Icon      string    `json:"icon" db:"icon"`
+	Color     string    `json:"color" db:"color"`
+	Position  int       `json:"position" db:"position"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Routine is an ordered group of habits done one after another, e.g. a morning routine
+type Routine struct {
+	ID          int       `json:"id" db:"id"`
+	UserID      int       `json:"user_id" db:"user_id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	Icon        string    `json:"icon" db:"icon"`
+	Color       string    `json:"color" db:"color"`
+	HabitIDs    []int     `json:"habit_ids"` // in routine order
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RestPeriod is a range of days the user declared as rest or vacation.
+// Habits missed on these days don't break streaks.
+type RestPeriod struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	StartDate string    `json:"start_date" db:"start_date"`
+	EndDate   string    `json:"end_date" db:"end_date"` // inclusive
+	Reason    string    `json:"reason,omitempty" db:"reason"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
 // Task represents a task
 type Task struct {
 	ID                      int        `json:"id" db:"id"`
@@ -58,24 +114,107 @@ type Task struct {
 	Priority                string     `json:"priority" db:"priority"`
 	DueDate                 *time.Time `json:"due_date" db:"due_date"`
 	CompletedAt             *time.Time `json:"completed_at" db:"completed_at"`
-	ParentTaskID            *int       `json:"parent_task_id" db:"parent_task_id"`
+	ParentTaskID            *int       `json:"parent_task_id" db:"parent_task_id"` // recurring series
+	ParentID                *int       `json:"parent_id" db:"parent_id"`           // parent of a subtask
+	Position                int        `json:"position" db:"position"`             // manual order among siblings
 	IsRecurringTemplate     bool       `json:"is_recurring_template" db:"is_recurring_template"`
 	RecurrenceIntervalWeeks *int       `json:"recurrence_interval_weeks" db:"recurrence_interval_weeks"`
 	RecurrenceEndDate       *time.Time `json:"recurrence_end_date" db:"recurrence_end_date"`
+	RecurrenceRule          *string    `json:"recurrence_rule" db:"recurrence_rule"` // RRULE of a template
+	OccurrenceDate          *string    `json:"occurrence_date" db:"occurrence_date"` // original day of an instance
+	ProjectID               *int       `json:"project_id" db:"project_id"`
+	NoteID                  *int       `json:"note_id" db:"note_id"`                     // plan the task comes from
+	ChecklistItemID         *int       `json:"checklist_item_id" db:"checklist_item_id"` // kept in sync with the task
+	Labels                  []Label    `json:"labels"`
 	CreatedAt               time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt               time.Time  `json:"updated_at" db:"updated_at"`
 }
 
-// JournalEntry represents a journal entry
-type JournalEntry struct {
+// Project groups tasks; its progress is the share of its tasks that are completed
+type Project struct {
+	ID             int        `json:"id" db:"id"`
+	UserID         int        `json:"user_id" db:"user_id"`
+	Name           string     `json:"name" db:"name"`
+	Description    string     `json:"description" db:"description"`
+	Color          string     `json:"color" db:"color"`
+	IsArchived     bool       `json:"is_archived"`
+	ArchivedAt     *time.Time `json:"archived_at" db:"archived_at"`
+	TaskCount      int        `json:"task_count"`
+	CompletedCount int        `json:"completed_count"`
+	Progress       float64    `json:"progress"` // percent of tasks completed, 0 without tasks
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Label is a tag that can be put on any number of tasks
+type Label struct {
 	ID        int       `json:"id" db:"id"`
 	UserID    int       `json:"user_id" db:"user_id"`
-	EntryDate time.Time `json:"entry_date" db:"entry_date"`
-	Mood      string    `json:"mood" db:"mood"`
-	Content   string    `json:"content" db:"content"`
-	Tags      string    `json:"tags" db:"tags"`
+	Name      string    `json:"name" db:"name"`
+	Color     string    `json:"color" db:"color"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// JournalEntry represents a journal entry
+type JournalEntry struct {
+	ID         int             `json:"id" db:"id"`
+	UserID     int             `json:"user_id" db:"user_id"`
+	EntryDate  time.Time       `json:"entry_date" db:"entry_date"`
+	Mood       string          `json:"mood" db:"mood"`
+	Content    string          `json:"content" db:"content"`
+	Tags       string          `json:"tags"`                         // JSON array of tag names
+	TemplateID *int            `json:"template_id" db:"template_id"` // template the fields were written with
+	Fields     json.RawMessage `json:"fields,omitempty" db:"fields"` // answers by field key
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// Journal template field types
+const (
+	JournalFieldList     = "list"     // list of short texts, such as things one is grateful for
+	JournalFieldScale    = "scale"    // whole number from 1 to 10
+	JournalFieldText     = "text"     // free text
+	JournalFieldCheckbox = "checkbox" // true or false
+)
+
+// JournalTemplateField is a typed question of a journal template
+type JournalTemplateField struct {
+	Key      string `json:"key" binding:"required"` // answers are stored under it, a-z, 0-9 and _
+	Label    string `json:"label" binding:"required,max=255"`
+	Type     string `json:"type" binding:"required,oneof=list scale text checkbox"`
+	Required bool   `json:"required"`
+}
+
+// JournalTemplate is a user-defined structure for journal entries
+type JournalTemplate struct {
+	ID          int                    `json:"id" db:"id"`
+	UserID      int                    `json:"user_id" db:"user_id"`
+	Name        string                 `json:"name" db:"name"`
+	Description string                 `json:"description" db:"description"`
+	Fields      []JournalTemplateField `json:"fields" db:"fields"`
+	CreatedAt   time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at" db:"updated_at"`
+}
+
+// JournalSummary is a generated summary of a user's journal entries over a week or month
+type JournalSummary struct {
+	ID          int       `json:"id" db:"id"`
+	UserID      int       `json:"user_id" db:"user_id"`
+	Period      string    `json:"period" db:"period"` // "week" (Monday to Sunday) or "month"
+	PeriodStart time.Time `json:"period_start" db:"period_start"`
+	PeriodEnd   time.Time `json:"period_end" db:"period_end"`
+	Summary     string    `json:"summary" db:"summary"`
+	EntryCount  int       `json:"entry_count" db:"entry_count"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"` // when it was last regenerated
+}
+
+// JournalTag is a tag of journal entries; renaming it renames it on all of them
+type JournalTag struct {
+	ID         int       `json:"id" db:"id"`
+	Name       string    `json:"name" db:"name"`
+	EntryCount int       `json:"entry_count"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 }
 
 // ChatSession represents a chat session
@@ -110,33 +249,135 @@ type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
 	Name     string `json:"name" binding:"required"`
+	Timezone string `json:"timezone"` // IANA name, e.g. "Europe/Berlin"
+}
+
+// UpdateSettingsRequest changes user settings; omitted fields are left unchanged
+type UpdateSettingsRequest struct {
+	Timezone             *string `json:"timezone"`               // IANA name; days, streaks and due dates are evaluated in it
+	JournalReminderTime  *string `json:"journal_reminder_time"`  // "HH:MM" of the evening journal nudge, "" turns it off
+	EmailNotifications   *bool   `json:"email_notifications"`    // also send reminders by email
+	PushNotifications    *bool   `json:"push_notifications"`     // push reminders to subscribed browsers
+	WeeklyJournalReview  *bool   `json:"weekly_journal_review"`  // summarize each finished week of the journal
+	MonthlyJournalReview *bool   `json:"monthly_journal_review"` // summarize each finished month of the journal
+}
+
+// ForgotPasswordRequest requests a password reset email
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password using an emailed reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// VerifyEmailRequest confirms an email address using an emailed verification token
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
 }
 
 // AuthResponse represents authentication response
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token"` // short-lived access token
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	User         User   `json:"user"`
+}
+
+// RefreshTokenRequest represents a refresh or logout request
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// TokenResponse represents a renewed token pair
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
 }
 
 // CreateHabitRequest represents create habit request
 type CreateHabitRequest struct {
-	Name            string `json:"name" binding:"required"`
-	Description     string `json:"description"`
-	Category        string `json:"category" binding:"required,oneof=morning afternoon evening"`
-	Icon            string `json:"icon"`
-	Color           string `json:"color"`
-	TargetFrequency int    `json:"target_frequency"`
+	Name            string         `json:"name" binding:"required"`
+	Description     string         `json:"description"`
+	Category        string         `json:"category" binding:"required,max=50"` // name of one of the user's categories
+	Icon            string         `json:"icon"`
+	Color           string         `json:"color"`
+	TargetFrequency int            `json:"target_frequency"`
+	Schedule        *HabitSchedule `json:"schedule"`
+	Unit            *string        `json:"unit"`
+	TargetValue     *float64       `json:"target_value" binding:"omitempty,gt=0"`
+	ReminderTime    *string        `json:"reminder_time"` // "HH:MM"
 }
 
 // UpdateHabitRequest represents update habit request
 type UpdateHabitRequest struct {
-	Name            *string `json:"name"`
-	Description     *string `json:"description"`
-	Category        *string `json:"category"`
-	Icon            *string `json:"icon"`
-	Color           *string `json:"color"`
-	TargetFrequency *int    `json:"target_frequency"`
-	IsActive        *bool   `json:"is_active"`
+	Name            *string        `json:"name"`
+	Description     *string        `json:"description"`
+	Category        *string        `json:"category" binding:"omitempty,max=50"`
+	Icon            *string        `json:"icon"`
+	Color           *string        `json:"color"`
+	TargetFrequency *int           `json:"target_frequency"`
+	Schedule        *HabitSchedule `json:"schedule"`
+	Unit            *string        `json:"unit"`
+	TargetValue     *float64       `json:"target_value" binding:"omitempty,gte=0"` // 0 turns the habit back into a yes/no habit
+	ReminderTime    *string        `json:"reminder_time"`                          // "HH:MM", "" removes the reminder
+	IsActive        *bool          `json:"is_active"`
+}
+
+// SetHabitCompletionRequest sets or clears a habit completion for one day
+type SetHabitCompletionRequest struct {
+	Completed *bool `json:"completed" binding:"required"`
+}
+
+// LogHabitRequest adds a value to a measurable habit's daily total.
+// Negative values correct earlier entries. Date defaults to today.
+type LogHabitRequest struct {
+	Value float64 `json:"value" binding:"required"`
+	Date  string  `json:"date"`
+}
+
+// CreateHabitCategoryRequest represents create habit category request
+type CreateHabitCategoryRequest struct {
+	Name  string `json:"name" binding:"required,max=50"`
+	Icon  string `json:"icon"`
+	Color string `json:"color"`
+}
+
+// UpdateHabitCategoryRequest represents update habit category request.
+// Renaming a category moves its habits along.
+type UpdateHabitCategoryRequest struct {
+	Name     *string `json:"name" binding:"omitempty,min=1,max=50"`
+	Icon     *string `json:"icon"`
+	Color    *string `json:"color"`
+	Position *int    `json:"position"`
+}
+
+// CreateRoutineRequest represents create routine request
+type CreateRoutineRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	Color       string `json:"color"`
+	HabitIDs    []int  `json:"habit_ids"`
+}
+
+// UpdateRoutineRequest represents update routine request; HabitIDs replaces the steps and their order
+type UpdateRoutineRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Description *string `json:"description"`
+	Icon        *string `json:"icon"`
+	Color       *string `json:"color"`
+	HabitIDs    *[]int  `json:"habit_ids"`
+}
+
+// CreateRestPeriodRequest declares rest days from StartDate to EndDate (inclusive, YYYY-MM-DD)
+type CreateRestPeriodRequest struct {
+	StartDate string `json:"start_date" binding:"required"`
+	EndDate   string `json:"end_date" binding:"required"`
+	Reason    string `json:"reason" binding:"max=255"`
 }
 
 // CreateTaskRequest represents create task request
@@ -148,29 +389,105 @@ type CreateTaskRequest struct {
 	IsRecurring          bool       `json:"is_recurring"`
 	RecurrenceIntervalWeeks *int     `json:"recurrence_interval_weeks,omitempty"`
 	RecurrenceEndDate    *time.Time `json:"recurrence_end_date,omitempty"`
+	RecurrenceRule       string     `json:"recurrence_rule,omitempty"` // e.g. "FREQ=WEEKLY;BYDAY=MO,TH", replaces the weekly interval
+	ParentID             *int       `json:"parent_id"`
+	ProjectID            *int       `json:"project_id"`
+	LabelIDs             []int      `json:"label_ids"`
+}
+
+// QuickAddTaskRequest represents a task written as one line of text, e.g.
+// "Zahnarzt anrufen morgen 9 Uhr !hoch #gesundheit"
+type QuickAddTaskRequest struct {
+	Text      string `json:"text" binding:"required"`
+	Preview   bool   `json:"preview"` // only return how the text was read
+	UseAI     bool   `json:"use_ai"`  // let the AI read text the rules find ambiguous
+	ProjectID *int   `json:"project_id"`
 }
 
 // UpdateTaskRequest represents update task request
 type UpdateTaskRequest struct {
-	Title       *string    `json:"title"`
-	Description *string    `json:"description"`
-	Priority    *string    `json:"priority"`
-	DueDate     *time.Time `json:"due_date"`
+	Title        *string    `json:"title" binding:"omitempty,max=200"`
+	Description  *string    `json:"description"`
+	Priority     *string    `json:"priority" binding:"omitempty,oneof=high medium low"`
+	DueDate      *time.Time `json:"due_date"`
+	ClearDueDate bool       `json:"clear_due_date"`
+	ParentID     *int       `json:"parent_id"` // 0 moves the task to the top level
+	// RecurrenceRule changes the rule of a series, only when editing following or all occurrences
+	RecurrenceRule *string `json:"recurrence_rule"`
+	ProjectID      *int    `json:"project_id"` // 0 removes the task from its project
+	LabelIDs       *[]int  `json:"label_ids"`  // replaces the labels of the task
+}
+
+// CreateProjectRequest represents create project request
+type CreateProjectRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+	Color       string `json:"color" binding:"max=20"`
+}
+
+// UpdateProjectRequest represents update project request
+type UpdateProjectRequest struct {
+	Name        *string `json:"name" binding:"omitempty,max=100"`
+	Description *string `json:"description"`
+	Color       *string `json:"color" binding:"omitempty,max=20"`
+}
+
+// CreateLabelRequest represents create label request
+type CreateLabelRequest struct {
+	Name  string `json:"name" binding:"required,max=50"`
+	Color string `json:"color" binding:"max=20"`
+}
+
+// UpdateLabelRequest represents update label request
+type UpdateLabelRequest struct {
+	Name  *string `json:"name" binding:"omitempty,max=50"`
+	Color *string `json:"color" binding:"omitempty,max=20"`
+}
+
+// ReorderTasksRequest sets the order of sibling tasks
+type ReorderTasksRequest struct {
+	ParentID *int  `json:"parent_id"` // nil for top level tasks
+	TaskIDs  []int `json:"task_ids" binding:"required,min=1"`
 }
 
 // CreateJournalEntryRequest represents create journal entry request
 type CreateJournalEntryRequest struct {
-	EntryDate time.Time `json:"entry_date" binding:"required"`
-	Mood      string    `json:"mood"`
-	Content   string    `json:"content"`
-	Tags      string    `json:"tags"`
+	EntryDate  time.Time                  `json:"entry_date" binding:"required"`
+	Mood       string                     `json:"mood"`
+	Content    string                     `json:"content"`
+	Tags       string                     `json:"tags"`        // JSON array or comma-separated tag names
+	TemplateID *int                       `json:"template_id"` // fields are validated against it
+	Fields     map[string]json.RawMessage `json:"fields"`      // answers by field key
 }
 
 // UpdateJournalEntryRequest represents update journal entry request
 type UpdateJournalEntryRequest struct {
-	Mood    *string `json:"mood"`
-	Content *string `json:"content"`
-	Tags    *string `json:"tags"`
+	Mood       *string                    `json:"mood"`
+	Content    *string                    `json:"content"`
+	Tags       *string                    `json:"tags"`        // JSON array or comma-separated tag names
+	TemplateID *int                       `json:"template_id"` // 0 removes the template and its fields
+	Fields     map[string]json.RawMessage `json:"fields"`      // replaces all answers; omitted keeps them
+}
+
+// JournalTemplateRequest creates or replaces a journal template
+type JournalTemplateRequest struct {
+	Name        string                 `json:"name" binding:"required,max=255"`
+	Description string                 `json:"description"`
+	Fields      []JournalTemplateField `json:"fields" binding:"required,min=1,max=50,dive"`
+}
+
+// RenameJournalTagRequest renames a journal tag; a name another tag already has merges
+// the two
+type RenameJournalTagRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+// SummarizeJournalRequest asks for a summary of the user's journal entries, either of the
+// week or month containing date (today when empty), which is cached, or of the last days
+type SummarizeJournalRequest struct {
+	Period string `json:"period" binding:"omitempty,oneof=week month"`
+	Date   string `json:"date"` // YYYY-MM-DD
+	Days   int    `json:"days" binding:"omitempty,min=1,max=365"`
 }
 
 // CreateChatMessageRequest represents create chat message request
@@ -206,6 +523,7 @@ type ChecklistItem struct {
 	Text      string    `json:"text" db:"text"`
 	IsChecked bool      `json:"is_checked" db:"is_checked"`
 	Position  int       `json:"position" db:"position"`
+	TaskID    *int      `json:"task_id"` // task the item was promoted to
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -246,6 +564,36 @@ type UpdateChecklistItemRequest struct {
 	Position  *int    `json:"position"`
 }
 
+// PromoteChecklistItemRequest represents the request to turn a checklist item into a task.
+// Without a due date, the date of the plan milestone matching the item is used.
+type PromoteChecklistItemRequest struct {
+	DueDate   *time.Time `json:"due_date"`
+	Priority  string     `json:"priority" binding:"omitempty,oneof=high medium low"`
+	ProjectID *int       `json:"project_id"`
+}
+
+// PlanMilestone is a dated step of a plan, read from its time and milestones
+type PlanMilestone struct {
+	Text         string `json:"text"`
+	Date         string `json:"date"`
+	TotalItems   int    `json:"total_items"` // checklist items belonging to the milestone
+	CheckedItems int    `json:"checked_items"`
+	IsReached    bool   `json:"is_reached"` // it has checklist items and all are checked
+	IsPast       bool   `json:"is_past"`    // the date has passed
+}
+
+// PlanProgress summarizes how far the checklist of a plan is done
+type PlanProgress struct {
+	NoteID        int             `json:"note_id"`
+	TotalItems    int             `json:"total_items"`
+	CheckedItems  int             `json:"checked_items"`
+	PromotedItems int             `json:"promoted_items"` // items linked to a task
+	OverdueTasks  int             `json:"overdue_tasks"`
+	Progress      float64         `json:"progress"` // percent of items checked, 0 without items
+	Milestones    []PlanMilestone `json:"milestones"`
+	NextMilestone *PlanMilestone  `json:"next_milestone"`
+}
+
 // PlanData represents planning data for a note
 type PlanData struct {
 	ID              int       `json:"id" db:"id"`
@@ -323,4 +671,30 @@ type StartMeditationRequest struct {
 // SendMeditationMessageRequest represents the request to send a message in meditation
 type SendMeditationMessageRequest struct {
 	Content string `json:"content" binding:"required"`
-}
\ No newline at end of file
+}
+
+// Notification is a reminder or message in the user's in-app inbox
+type Notification struct {
+	ID        int        `json:"id" db:"id"`
+	Kind      string     `json:"kind" db:"kind"` // 'task_due', 'habit_reminder' or 'journal_nudge'
+	Title     string     `json:"title" db:"title"`
+	Body      string     `json:"body" db:"body"`
+	URL       string     `json:"url" db:"url"` // path in the app the notification opens
+	ReadAt    *time.Time `json:"read_at" db:"read_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// PushSubscriptionRequest registers a browser for Web Push, as serialized by
+// PushSubscription.toJSON()
+type PushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint" binding:"required,url,max=500"`
+	Keys     struct {
+		P256dh string `json:"p256dh" binding:"required,max=255"`
+		Auth   string `json:"auth" binding:"required,max=255"`
+	} `json:"keys" binding:"required"`
+}
+
+// DeletePushSubscriptionRequest unregisters a browser from Web Push
+type DeletePushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint" binding:"required"`
+}
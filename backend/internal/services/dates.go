@@ -0,0 +1,187 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// monthNames maps German and English month names and abbreviations to months
+var monthNames = map[string]time.Month{
+	"januar": time.January, "january": time.January, "jan": time.January,
+	"februar": time.February, "february": time.February, "feb": time.February,
+	"märz": time.March, "maerz": time.March, "march": time.March, "mär": time.March,
+	"april": time.April, "apr": time.April,
+	"mai": time.May, "may": time.May,
+	"juni": time.June, "june": time.June, "jun": time.June,
+	"juli": time.July, "july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sept": time.September, "sep": time.September,
+	"oktober": time.October, "october": time.October, "okt": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"dezember": time.December, "december": time.December, "dez": time.December, "dec": time.December,
+}
+
+const monthPattern = `(januar|january|jan|februar|february|feb|märz|maerz|march|mär|april|apr|mai|may|juni|june|jun|juli|july|jul|august|aug|september|sept|sep|oktober|october|okt|oct|november|nov|dezember|december|dez|dec)`
+
+// datePattern is a way of writing a date; resolve turns a match into a day relative to ref
+type datePattern struct {
+	re      *regexp.Regexp
+	resolve func(m []string, ref time.Time) (time.Time, bool)
+}
+
+// calendarDatePatterns are dates written with a day, month or distance
+var calendarDatePatterns = []datePattern{
+	// 2026-03-15
+	{regexp.MustCompile(`\b(\d{4})-(\d{1,2})-(\d{1,2})\b`), func(m []string, ref time.Time) (time.Time, bool) {
+		return makeDate(atoi(m[1]), time.Month(atoi(m[2])), atoi(m[3]), ref)
+	}},
+	// 15.03.2026, 15.3.26, 15.3.
+	{regexp.MustCompile(`\b(\d{1,2})\.(\d{1,2})\.(?:(\d{4}|\d{2})\b)?`), func(m []string, ref time.Time) (time.Time, bool) {
+		return dayOfMonth(atoi(m[1]), time.Month(atoi(m[2])), m[3], ref)
+	}},
+	// 15. März 2026, 15 March
+	{regexp.MustCompile(`(?i)\b(\d{1,2})\.?\s+` + monthPattern + `\b(?:\s+(\d{4})\b)?`), func(m []string, ref time.Time) (time.Time, bool) {
+		return dayOfMonth(atoi(m[1]), monthNames[strings.ToLower(m[2])], m[3], ref)
+	}},
+	// March 15, 2026
+	{regexp.MustCompile(`(?i)\b` + monthPattern + `\s+(\d{1,2})(?:st|nd|rd|th)?\b(?:,?\s+(\d{4})\b)?`), func(m []string, ref time.Time) (time.Time, bool) {
+		return dayOfMonth(atoi(m[2]), monthNames[strings.ToLower(m[1])], m[3], ref)
+	}},
+	// Ende März, bis Juni 2026, early May; a bare month name is too ambiguous ("may")
+	{regexp.MustCompile(`(?i)\b(anfang|mitte|ende|bis|im|early|mid|end of|by|until|in)\s+` + monthPattern + `\b(?:\s+(\d{4})\b)?`), func(m []string, ref time.Time) (time.Time, bool) {
+		day := 0 // last day of the month
+		switch strings.ToLower(m[1]) {
+		case "anfang", "early":
+			day = 1
+		case "mitte", "mid":
+			day = 15
+		}
+		month := monthNames[strings.ToLower(m[2])]
+		year := ref.Year()
+		if m[3] != "" {
+			year = atoi(m[3])
+		} else if month < ref.Month() {
+			year++
+		}
+		if day == 0 {
+			return time.Date(year, month+1, 0, 0, 0, 0, 0, ref.Location()), true
+		}
+		return time.Date(year, month, day, 0, 0, 0, 0, ref.Location()), true
+	}},
+	// in 3 Wochen, nach 2 Monaten, within 10 days
+	{regexp.MustCompile(`(?i)\b(?:in|nach|within|after)\s+(\d{1,3})\s+(tagen|tag|wochen|woche|monaten|monate|monat|jahren|jahr|days|day|weeks|week|months|month|years|year)\b`), func(m []string, ref time.Time) (time.Time, bool) {
+		return addUnits(ref, atoi(m[1]), m[2]), true
+	}},
+}
+
+// planStepPattern reads "Woche 3", "Week 1-2" or "Monat 2" as the end of that step of a
+// plan starting on ref
+var planStepPattern = datePattern{
+	regexp.MustCompile(`(?i)\b(tag|woche|monat|day|week|month)\s+(\d{1,2})(?:\s*[-–]\s*(\d{1,2}))?\b`),
+	func(m []string, ref time.Time) (time.Time, bool) {
+		n := atoi(m[2])
+		if m[3] != "" {
+			n = atoi(m[3])
+		}
+		if n < 1 {
+			return time.Time{}, false
+		}
+		return addUnits(ref, n, m[1]).AddDate(0, 0, -1), true
+	},
+}
+
+// FindDate finds the first date mentioned in text, in German or English, including plan
+// steps like "Woche 3". Dates without a year and relative ones ("in 2 Wochen") are resolved
+// against ref, the day they were written; a day without year is the next one on or after
+// ref. It returns the day at midnight in ref's location and the matched text.
+func FindDate(text string, ref time.Time) (time.Time, string, bool) {
+	return findDate(text, ref, milestoneDatePatterns)
+}
+
+var milestoneDatePatterns = append(calendarDatePatterns[:len(calendarDatePatterns):len(calendarDatePatterns)], planStepPattern)
+
+// findDate finds the first date in text written in one of patterns; at the same
+// position the longest match wins
+func findDate(text string, ref time.Time, patterns []datePattern) (time.Time, string, bool) {
+	ref = time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, ref.Location())
+	var best []int
+	var bestDate time.Time
+	for _, p := range patterns {
+		for _, loc := range p.re.FindAllStringSubmatchIndex(text, -1) {
+			if best != nil && (loc[0] > best[0] || (loc[0] == best[0] && loc[1] <= best[1])) {
+				break
+			}
+			date, ok := p.resolve(submatches(text, loc), ref)
+			if ok {
+				best, bestDate = loc, date
+				break
+			}
+		}
+	}
+	if best == nil {
+		return time.Time{}, "", false
+	}
+	return bestDate, text[best[0]:best[1]], true
+}
+
+// submatches returns the groups of a match found with FindAllStringSubmatchIndex
+func submatches(text string, loc []int) []string {
+	m := make([]string, len(loc)/2)
+	for i := range m {
+		if loc[2*i] >= 0 {
+			m[i] = text[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return m
+}
+
+// dayOfMonth resolves a day and month with an optional two or four digit year
+func dayOfMonth(day int, month time.Month, year string, ref time.Time) (time.Time, bool) {
+	if year != "" {
+		y := atoi(year)
+		if y < 100 {
+			y += 2000
+		}
+		return makeDate(y, month, day, ref)
+	}
+	date, ok := makeDate(ref.Year(), month, day, ref)
+	if ok && date.Before(ref) {
+		return makeDate(ref.Year()+1, month, day, ref)
+	}
+	return date, ok
+}
+
+// makeDate builds a day in ref's location, rejecting days like 31.02.
+func makeDate(year int, month time.Month, day int, ref time.Time) (time.Time, bool) {
+	if month < time.January || month > time.December {
+		return time.Time{}, false
+	}
+	date := time.Date(year, month, day, 0, 0, 0, 0, ref.Location())
+	return date, date.Day() == day && date.Month() == month
+}
+
+// addUnits adds n days, weeks, months or years, named in German or English
+func addUnits(ref time.Time, n int, unit string) time.Time {
+	switch unit = strings.ToLower(unit); {
+	case strings.HasPrefix(unit, "tag"), strings.HasPrefix(unit, "day"):
+		return ref.AddDate(0, 0, n)
+	case strings.HasPrefix(unit, "woche"), strings.HasPrefix(unit, "week"):
+		return ref.AddDate(0, 0, 7*n)
+	case strings.HasPrefix(unit, "monat"), strings.HasPrefix(unit, "month"):
+		return ref.AddDate(0, n, 0)
+	default:
+		return ref.AddDate(n, 0, 0)
+	}
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// DueAt is when a task due on day without a time of day is due: at the end of that day
+func DueAt(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 0, 0, day.Location())
+}
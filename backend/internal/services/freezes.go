@@ -0,0 +1,188 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"habit-tracker-backend/internal/database"
+)
+
+// StreakFreezesPerMonth is how many missed days per calendar month are covered
+// automatically, set by STREAK_FREEZES_PER_MONTH (default 2)
+func StreakFreezesPerMonth() int {
+	if freezes, err := strconv.Atoi(os.Getenv("STREAK_FREEZES_PER_MONTH")); err == nil && freezes >= 0 {
+		return freezes
+	}
+	return 2
+}
+
+// SettleStreakFreeze settles a single finished day of the user, see SettleStreakFreezeDays
+func SettleStreakFreeze(userID int, day time.Time, perMonth int) error {
+	return SettleStreakFreezeDays(userID, day, day, perMonth)
+}
+
+// SettleStreakFreezeDays decides which finished days of the user from from to to,
+// midnights in their time zone, are covered by a streak freeze. A missed day that would
+// break a running streak is frozen while the month of that day has freezes left. Frozen
+// days up to to that turned out completed after all (e.g. by backfilling), or on which
+// nothing is due anymore, give their freeze back. Streaks here are those across all
+// habits, so a freeze covers the whole day for every habit.
+func SettleStreakFreezeDays(userID int, from, to time.Time, perMonth int) error {
+	excused, err := LoadExcusedDays(userID)
+	if err != nil {
+		return err
+	}
+	histories, err := LoadHabitHistories(userID, nil, to.Location(), excused)
+	if err != nil {
+		return err
+	}
+	freeze, refund := streakFreezeChanges(DailyResults(histories, to), excused.Frozen, from, to, perMonth)
+
+	for _, day := range refund {
+		if _, err := database.DB.Exec("DELETE FROM streak_freezes WHERE user_id = ? AND frozen_on = ?", userID, day); err != nil {
+			return fmt.Errorf("failed to return streak freeze: %v", err)
+		}
+	}
+	for _, day := range freeze {
+		_, err = database.DB.Exec(`
+			INSERT INTO streak_freezes (user_id, frozen_on) VALUES (?, ?)
+			ON DUPLICATE KEY UPDATE id = id
+		`, userID, day)
+		if err != nil {
+			return fmt.Errorf("failed to record streak freeze: %v", err)
+		}
+	}
+	return nil
+}
+
+// streakFreezeChanges works out, from the daily results up to to, which frozen days up to
+// to give their freeze back and which days from from on are frozen, oldest first
+func streakFreezeChanges(results []PeriodResult, frozen map[string]bool, from, to time.Time, perMonth int) (freeze, refund []string) {
+	missed := make(map[string]bool)
+	for _, r := range results {
+		if !r.Met() {
+			missed[r.Start.Format(DateLayout)] = true
+		}
+	}
+	kept := make(map[string]bool, len(frozen))
+	last := to.Format(DateLayout)
+	for day := range frozen {
+		if day > last || missed[day] {
+			kept[day] = true
+		} else {
+			refund = append(refund, day)
+		}
+	}
+	sort.Strings(refund)
+
+	results = append([]PeriodResult(nil), results...)
+	for i, day := range results {
+		if day.Start.Before(from) || day.Met() || day.Excused {
+			continue // rest and already frozen days keep the streak as it is
+		}
+
+		alive := false // whether a streak runs into the day
+		for j := i - 1; j >= 0; j-- {
+			if !results[j].Excused {
+				alive = results[j].Met()
+				break
+			}
+		}
+		if !alive || freezesInMonth(kept, day.Start) >= perMonth {
+			continue
+		}
+
+		dayStr := day.Start.Format(DateLayout)
+		freeze = append(freeze, dayStr)
+		kept[dayStr] = true
+		results[i].Excused = true
+	}
+	return freeze, refund
+}
+
+// freezesInMonth counts the frozen days in the calendar month of day
+func freezesInMonth(frozen map[string]bool, day time.Time) int {
+	month := day.Format("2006-01")
+	used := 0
+	for frozenOn := range frozen {
+		if strings.HasPrefix(frozenOn, month) {
+			used++
+		}
+	}
+	return used
+}
+
+// SettleStreakFreezes settles, for all users with active habits, the days since the job
+// last settled theirs up to yesterday in each user's time zone, so days missed while the
+// server was down are caught up on. A user's first settlement starts with yesterday. It
+// can run any number of times a day; the first run after a user's midnight is the one
+// that matters. A user whose days can't be settled is logged and tried again next run.
+func SettleStreakFreezes(perMonth int, now time.Time) error {
+	rows, err := database.DB.Query(`
+		SELECT u.id, u.freezes_settled_through
+		FROM users u
+		WHERE EXISTS(SELECT 1 FROM habits h WHERE h.user_id = u.id AND h.is_active = true)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query users with habits: %v", err)
+	}
+	type settlement struct {
+		UserID         int
+		SettledThrough sql.NullTime
+	}
+	var settlements []settlement
+	for rows.Next() {
+		var s settlement
+		if err := rows.Scan(&s.UserID, &s.SettledThrough); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan user: %v", err)
+		}
+		settlements = append(settlements, s)
+	}
+	rows.Close()
+
+	for _, s := range settlements {
+		loc := UserLocation(s.UserID)
+		yesterday := StartOfDay(now, loc).AddDate(0, 0, -1)
+		from := yesterday
+		if s.SettledThrough.Valid {
+			settled := s.SettledThrough.Time
+			from = time.Date(settled.Year(), settled.Month(), settled.Day()+1, 0, 0, 0, 0, loc)
+		}
+		if from.After(yesterday) {
+			continue
+		}
+		if err := SettleStreakFreezeDays(s.UserID, from, yesterday, perMonth); err != nil {
+			log.Printf("Failed to settle streak freezes of user %d: %v", s.UserID, err)
+			continue
+		}
+		_, err := database.DB.Exec(`
+			UPDATE users SET freezes_settled_through = ? WHERE id = ?
+		`, yesterday.Format(DateLayout), s.UserID)
+		if err != nil {
+			log.Printf("Failed to record streak freeze settlement of user %d: %v", s.UserID, err)
+		}
+	}
+	return nil
+}
+
+// StartStreakFreezeJob spends streak freezes on missed days once they are over, checking
+// once at startup and then every interval
+func StartStreakFreezeJob(perMonth int, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := SettleStreakFreezes(perMonth, time.Now()); err != nil {
+				log.Printf("Streak freeze job failed: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
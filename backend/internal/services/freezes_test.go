@@ -0,0 +1,124 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStreakFreezeChanges(t *testing.T) {
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	met := func(month time.Month, d int) PeriodResult {
+		return PeriodResult{Start: day(month, d), End: day(month, d+1), Completed: 1, Required: 1}
+	}
+	missed := func(month time.Month, d int) PeriodResult {
+		return PeriodResult{Start: day(month, d), End: day(month, d+1), Required: 1}
+	}
+	excused := func(r PeriodResult) PeriodResult {
+		r.Excused = true
+		return r
+	}
+	tests := []struct {
+		name     string
+		results  []PeriodResult
+		frozen   []string
+		from, to time.Time
+		perMonth int
+		freeze   []string
+		refund   []string
+	}{
+		{
+			name:    "missed day after a completed one",
+			results: []PeriodResult{met(3, 2), missed(3, 3)},
+			from:    day(3, 3), to: day(3, 3), perMonth: 2,
+			freeze: []string{"2026-03-03"},
+		},
+		{
+			name:    "no streak to keep",
+			results: []PeriodResult{missed(3, 2), missed(3, 3)},
+			from:    day(3, 3), to: day(3, 3), perMonth: 2,
+		},
+		{
+			name:    "days before from are settled already",
+			results: []PeriodResult{met(3, 1), missed(3, 2), met(3, 3), missed(3, 4)},
+			from:    day(3, 3), to: day(3, 4), perMonth: 2,
+			freeze: []string{"2026-03-04"},
+		},
+		{
+			name:    "catching up stops when the month runs out of freezes",
+			results: []PeriodResult{met(3, 2), missed(3, 3), missed(3, 4), missed(3, 5)},
+			from:    day(3, 3), to: day(3, 5), perMonth: 2,
+			freeze: []string{"2026-03-03", "2026-03-04"},
+		},
+		{
+			name:    "freezes of the month count against the limit",
+			results: []PeriodResult{met(3, 2), missed(3, 3), missed(3, 4)},
+			frozen:  []string{"2026-03-20"},
+			from:    day(3, 3), to: day(3, 4), perMonth: 2,
+			freeze: []string{"2026-03-03"},
+		},
+		{
+			name:    "a new month has freezes again",
+			results: []PeriodResult{met(3, 28), excused(missed(3, 29)), excused(missed(3, 30)), met(3, 31), missed(4, 1)},
+			frozen:  []string{"2026-03-29", "2026-03-30"},
+			from:    day(4, 1), to: day(4, 1), perMonth: 2,
+			freeze: []string{"2026-04-01"},
+		},
+		{
+			name:    "rest days keep the streak running",
+			results: []PeriodResult{met(3, 2), excused(missed(3, 3)), missed(3, 4)},
+			from:    day(3, 3), to: day(3, 4), perMonth: 2,
+			freeze: []string{"2026-03-04"},
+		},
+		{
+			name:    "no freezes",
+			results: []PeriodResult{met(3, 2), missed(3, 3)},
+			from:    day(3, 3), to: day(3, 3), perMonth: 0,
+		},
+		{
+			name:    "frozen day completed after all",
+			results: []PeriodResult{met(3, 2), met(3, 3), met(3, 4)},
+			frozen:  []string{"2026-03-03"},
+			from:    day(3, 4), to: day(3, 4), perMonth: 2,
+			refund: []string{"2026-03-03"},
+		},
+		{
+			name:    "nothing due on a frozen day anymore",
+			results: []PeriodResult{met(3, 2), met(3, 4)},
+			frozen:  []string{"2026-03-03"},
+			from:    day(3, 4), to: day(3, 4), perMonth: 2,
+			refund: []string{"2026-03-03"},
+		},
+		{
+			name:    "frozen days still missed and after to are kept",
+			results: []PeriodResult{met(3, 2), excused(missed(3, 3))},
+			frozen:  []string{"2026-03-03", "2026-03-10"},
+			from:    day(3, 3), to: day(3, 3), perMonth: 2,
+		},
+		{
+			name:    "a returned freeze can be spent again",
+			results: []PeriodResult{met(3, 2), met(3, 3), missed(3, 4), missed(3, 5)},
+			frozen:  []string{"2026-03-03", "2026-03-20"},
+			from:    day(3, 4), to: day(3, 5), perMonth: 2,
+			freeze: []string{"2026-03-04"},
+			refund: []string{"2026-03-03"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frozen := make(map[string]bool)
+			for _, d := range tt.frozen {
+				frozen[d] = true
+			}
+			freeze, refund := streakFreezeChanges(tt.results, frozen, tt.from, tt.to, tt.perMonth)
+			if !reflect.DeepEqual(freeze, tt.freeze) || !reflect.DeepEqual(refund, tt.refund) {
+				t.Errorf("streakFreezeChanges() = freeze %v, refund %v, want freeze %v, refund %v", freeze, refund, tt.freeze, tt.refund)
+			}
+			if len(frozen) != len(tt.frozen) {
+				t.Errorf("streakFreezeChanges() changed the frozen days to %v", frozen)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"habit-tracker-backend/internal/database"
+	"habit-tracker-backend/internal/models"
+)
+
+// ArchiveRanges holds the archived stretches of a user's habits by habit ID
+type ArchiveRanges map[int][]struct {
+	From, Until string // Until is exclusive, empty while still archived
+}
+
+// Contains reports whether the habit was archived on the given day
+func (a ArchiveRanges) Contains(habitID int, day string) bool {
+	for _, r := range a[habitID] {
+		if day >= r.From && (r.Until == "" || day < r.Until) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcusedDays are the days of a user on which missed habits don't break streaks
+type ExcusedDays struct {
+	Rest   map[string]bool // days inside a rest period
+	Frozen map[string]bool // missed days covered by a streak freeze
+}
+
+// Contains reports whether day is a rest or frozen day
+func (e ExcusedDays) Contains(day time.Time) bool {
+	dayStr := day.Format(DateLayout)
+	return e.Rest[dayStr] || e.Frozen[dayStr]
+}
+
+// LoadExcusedDays loads the user's rest periods and streak freezes
+func LoadExcusedDays(userID int) (ExcusedDays, error) {
+	excused := ExcusedDays{Rest: make(map[string]bool), Frozen: make(map[string]bool)}
+
+	rows, err := database.DB.Query(`
+		SELECT start_date, end_date FROM rest_periods WHERE user_id = ?
+	`, userID)
+	if err != nil {
+		return excused, fmt.Errorf("failed to query rest periods: %v", err)
+	}
+	for rows.Next() {
+		var start, end time.Time
+		if err := rows.Scan(&start, &end); err != nil {
+			rows.Close()
+			return excused, fmt.Errorf("failed to scan rest period: %v", err)
+		}
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			excused.Rest[day.Format(DateLayout)] = true
+		}
+	}
+	rows.Close()
+
+	rows, err = database.DB.Query("SELECT frozen_on FROM streak_freezes WHERE user_id = ?", userID)
+	if err != nil {
+		return excused, fmt.Errorf("failed to query streak freezes: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return excused, fmt.Errorf("failed to scan streak freeze: %v", err)
+		}
+		excused.Frozen[day.Format(DateLayout)] = true
+	}
+	return excused, nil
+}
+
+// LoadArchiveRanges loads when each of the user's habits was archived
+func LoadArchiveRanges(userID int) (ArchiveRanges, error) {
+	rows, err := database.DB.Query(`
+		SELECT habit_id, archived_on, restored_on FROM habit_archive_periods WHERE user_id = ?
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query archive periods: %v", err)
+	}
+	defer rows.Close()
+
+	ranges := make(ArchiveRanges)
+	for rows.Next() {
+		var habitID int
+		var archivedOn time.Time
+		var restoredOn sql.NullTime
+		if err := rows.Scan(&habitID, &archivedOn, &restoredOn); err != nil {
+			return nil, fmt.Errorf("failed to scan archive period: %v", err)
+		}
+		r := struct{ From, Until string }{From: archivedOn.Format(DateLayout)}
+		if restoredOn.Valid {
+			r.Until = restoredOn.Time.Format(DateLayout)
+		}
+		ranges[habitID] = append(ranges[habitID], r)
+	}
+	return ranges, nil
+}
+
+// CompletionsByHabit counts a user's completions per habit and day since the given day
+func CompletionsByHabit(userID int, since time.Time) (map[int]map[string]int, error) {
+	rows, err := database.DB.Query(`
+		SELECT habit_id, completed_date, COUNT(*)
+		FROM habit_completions
+		WHERE user_id = ? AND completed_date >= ?
+		GROUP BY habit_id, completed_date
+	`, userID, since.Format(DateLayout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query completions of user %d: %v", userID, err)
+	}
+	defer rows.Close()
+
+	completed := make(map[int]map[string]int)
+	for rows.Next() {
+		var habitID, count int
+		var date time.Time
+		if err := rows.Scan(&habitID, &date, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan completions of user %d: %v", userID, err)
+		}
+		if completed[habitID] == nil {
+			completed[habitID] = make(map[string]int)
+		}
+		completed[habitID][date.Format(DateLayout)] = count
+	}
+	return completed, nil
+}
+
+// LoggedTotals sums the values logged for each of the user's measurable habits on day
+func LoggedTotals(userID int, day time.Time) (map[int]float64, error) {
+	rows, err := database.DB.Query(`
+		SELECT habit_id, SUM(value)
+		FROM habit_logs
+		WHERE user_id = ? AND log_date = ?
+		GROUP BY habit_id
+	`, userID, day.Format(DateLayout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query logged values of user %d: %v", userID, err)
+	}
+	defer rows.Close()
+
+	totals := make(map[int]float64)
+	for rows.Next() {
+		var habitID int
+		var total float64
+		if err := rows.Scan(&habitID, &total); err != nil {
+			return nil, fmt.Errorf("failed to scan logged values of user %d: %v", userID, err)
+		}
+		totals[habitID] = total
+	}
+	return totals, rows.Err()
+}
+
+// loadHabitSchedules loads the ID, schedule and creation time of all the user's habits,
+// archived ones included, which is all a history needs of them
+func loadHabitSchedules(userID int) ([]models.Habit, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, schedule_type, schedule_days, schedule_count, schedule_interval, created_at
+		FROM habits WHERE user_id = ?
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query habits: %v", err)
+	}
+	defer rows.Close()
+
+	var habits []models.Habit
+	for rows.Next() {
+		habit := models.Habit{UserID: userID}
+		var scheduleType string
+		var scheduleDays sql.NullString
+		var scheduleCount, scheduleInterval sql.NullInt64
+		if err := rows.Scan(&habit.ID, &scheduleType, &scheduleDays, &scheduleCount, &scheduleInterval, &habit.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan habit: %v", err)
+		}
+		habit.Schedule = ScheduleFromColumns(scheduleType, scheduleDays, scheduleCount, scheduleInterval)
+		habits = append(habits, habit)
+	}
+	return habits, rows.Err()
+}
+
+// LoadHabitHistories loads the schedules, completions and archived periods of the given
+// habits, or of all the user's habits when habits is nil, with days in loc. Rest and
+// frozen days are taken from excused.
+func LoadHabitHistories(userID int, habits []models.Habit, loc *time.Location, excused ExcusedDays) ([]HabitHistory, error) {
+	if habits == nil {
+		var err error
+		if habits, err = loadHabitSchedules(userID); err != nil {
+			return nil, err
+		}
+	}
+	if len(habits) == 0 {
+		return nil, nil
+	}
+
+	since := time.Now()
+	for _, habit := range habits {
+		if habit.CreatedAt.Before(since) {
+			since = habit.CreatedAt
+		}
+	}
+	completed, err := CompletionsByHabit(userID, StartOfDay(since, loc))
+	if err != nil {
+		return nil, err
+	}
+	archived, err := LoadArchiveRanges(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	histories := make([]HabitHistory, 0, len(habits))
+	for _, habit := range habits {
+		habitID := habit.ID
+		histories = append(histories, HabitHistory{
+			HabitID:   habitID,
+			Schedule:  habit.Schedule,
+			Anchor:    StartOfDay(habit.CreatedAt, loc),
+			Completed: completed[habitID],
+			Archived: func(day time.Time) bool {
+				return archived.Contains(habitID, day.Format(DateLayout))
+			},
+			Excused: excused.Contains,
+		})
+	}
+	return histories, nil
+}
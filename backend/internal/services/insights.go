@@ -0,0 +1,230 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"habit-tracker-backend/internal/database"
+)
+
+// MoodScores rates the moods of journal entries from 1 (terrible) to 5 (excellent)
+var MoodScores = map[string]int{
+	"terrible":  1,
+	"bad":       2,
+	"okay":      3,
+	"good":      4,
+	"excellent": 5,
+}
+
+// minMoodSamples is how many days with and without a habit need a mood before the
+// two are compared; fewer days say nothing
+const minMoodSamples = 3
+
+// MoodDay is the mood of one day
+type MoodDay struct {
+	Date  string `json:"date"`
+	Mood  string `json:"mood"`
+	Score int    `json:"score"`
+}
+
+// MoodWeek is the average mood of the days of an ISO week that have one
+type MoodWeek struct {
+	WeekStart string  `json:"week_start"` // Monday
+	Average   float64 `json:"average"`
+	Days      int     `json:"days"`
+}
+
+// MoodHabit is a habit whose completions are compared with the mood
+type MoodHabit struct {
+	Name    string
+	History HabitHistory
+}
+
+// HabitMoodCorrelation compares the mood on days a habit was done with days it was
+// due but not done
+type HabitMoodCorrelation struct {
+	HabitID     int     `json:"habit_id"`
+	Name        string  `json:"name"`
+	DaysDone    int     `json:"days_done"`
+	DaysMissed  int     `json:"days_missed"`
+	MoodDone    float64 `json:"mood_done"`   // average score on days done
+	MoodMissed  float64 `json:"mood_missed"` // average score on days missed
+	Difference  float64 `json:"difference"`  // mood_done - mood_missed
+	Correlation float64 `json:"correlation"` // point-biserial correlation, -1 to 1
+	Summary     string  `json:"summary"`
+}
+
+// MoodInsights relates a user's moods to their habits over a range of days
+type MoodInsights struct {
+	From    string                 `json:"from"`
+	To      string                 `json:"to"`
+	Average *float64               `json:"average"` // nil without moods
+	Days    []MoodDay              `json:"days"`
+	Weekly  []MoodWeek             `json:"weekly"`
+	Habits  []HabitMoodCorrelation `json:"habits"` // strongest difference first
+}
+
+// LoadMoodDays loads the moods of the user's journal entries from one day to another,
+// oldest first. Entries without a known mood are skipped.
+func LoadMoodDays(userID int, from, to time.Time) ([]MoodDay, error) {
+	rows, err := database.DB.Query(`
+		SELECT entry_date, mood FROM journal_entries
+		WHERE user_id = ? AND entry_date BETWEEN ? AND ? AND mood IS NOT NULL
+		ORDER BY entry_date
+	`, userID, from.Format(DateLayout), to.Format(DateLayout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query moods: %v", err)
+	}
+	defer rows.Close()
+
+	days := []MoodDay{}
+	for rows.Next() {
+		var date time.Time
+		var mood sql.NullString
+		if err := rows.Scan(&date, &mood); err != nil {
+			return nil, fmt.Errorf("failed to scan mood: %v", err)
+		}
+		if score, ok := MoodScores[mood.String]; ok {
+			days = append(days, MoodDay{Date: date.Format(DateLayout), Mood: mood.String, Score: score})
+		}
+	}
+	return days, nil
+}
+
+// AnalyzeMood computes weekly averages of the moods and, for each habit, how the mood
+// differs between days it was done and days it was due but not done. Days before a habit
+// was created, while it was archived, on rest days and, for habits with fixed days, on
+// days it wasn't scheduled are left out. Habits with fewer than minMoodSamples days on
+// either side are not compared.
+func AnalyzeMood(days []MoodDay, habits []MoodHabit) MoodInsights {
+	insights := MoodInsights{Days: days, Weekly: []MoodWeek{}, Habits: []HabitMoodCorrelation{}}
+	if len(days) == 0 {
+		return insights
+	}
+	insights.From, insights.To = days[0].Date, days[len(days)-1].Date
+
+	total := 0
+	var week *MoodWeek
+	weekTotal := 0
+	for _, d := range days {
+		total += d.Score
+		day, err := time.Parse(DateLayout, d.Date)
+		if err != nil {
+			continue
+		}
+		start := day.AddDate(0, 0, 1-isoWeekday(day)).Format(DateLayout)
+		if week == nil || week.WeekStart != start {
+			insights.Weekly = append(insights.Weekly, MoodWeek{WeekStart: start})
+			week = &insights.Weekly[len(insights.Weekly)-1]
+			weekTotal = 0
+		}
+		weekTotal += d.Score
+		week.Days++
+		week.Average = round2(float64(weekTotal) / float64(week.Days))
+	}
+	average := round2(float64(total) / float64(len(days)))
+	insights.Average = &average
+
+	for _, habit := range habits {
+		if c, ok := correlateMood(days, habit); ok {
+			insights.Habits = append(insights.Habits, c)
+		}
+	}
+	sort.SliceStable(insights.Habits, func(i, j int) bool {
+		return math.Abs(insights.Habits[i].Difference) > math.Abs(insights.Habits[j].Difference)
+	})
+	return insights
+}
+
+// correlateMood compares the mood on days the habit was done and missed
+func correlateMood(days []MoodDay, habit MoodHabit) (HabitMoodCorrelation, bool) {
+	h := habit.History
+	var done, missed []float64
+	for _, d := range days {
+		day, err := time.Parse(DateLayout, d.Date)
+		if err != nil {
+			continue
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, h.Anchor.Location())
+		switch {
+		case h.Completed[d.Date] > 0:
+			if h.active(day) {
+				done = append(done, float64(d.Score))
+			}
+		case !h.active(day) || h.excused(day):
+		case (h.Schedule.Type == ScheduleDaily || h.Schedule.Type == "" || h.Schedule.Type == ScheduleWeekdays) && !IsScheduledDay(h.Schedule, day):
+		default:
+			missed = append(missed, float64(d.Score))
+		}
+	}
+	if len(done) < minMoodSamples || len(missed) < minMoodSamples {
+		return HabitMoodCorrelation{}, false
+	}
+
+	meanDone, meanMissed := mean(done), mean(missed)
+	all := append(append([]float64{}, done...), missed...)
+	n := float64(len(all))
+	correlation := 0.0
+	if sd := stddev(all); sd > 0 {
+		correlation = (meanDone - meanMissed) / sd * math.Sqrt(float64(len(done))*float64(len(missed))/(n*n))
+	}
+
+	c := HabitMoodCorrelation{
+		HabitID:     h.HabitID,
+		Name:        habit.Name,
+		DaysDone:    len(done),
+		DaysMissed:  len(missed),
+		MoodDone:    round2(meanDone),
+		MoodMissed:  round2(meanMissed),
+		Difference:  round2(meanDone - meanMissed),
+		Correlation: round2(correlation),
+	}
+	c.Summary = describeMoodDifference(c)
+	return c, true
+}
+
+// describeMoodDifference puts a correlation into a sentence, such as "An Tagen mit
+// „Meditieren“ ist deine Stimmung im Schnitt 0,8 Punkte höher (12 von 30 Tagen)."
+func describeMoodDifference(c HabitMoodCorrelation) string {
+	days := fmt.Sprintf("(%d von %d Tagen)", c.DaysDone, c.DaysDone+c.DaysMissed)
+	amount := formatMoodScore(math.Abs(c.Difference))
+	switch {
+	case amount == "0,0":
+		return fmt.Sprintf("An Tagen mit „%s“ ist deine Stimmung im Schnitt gleich %s.", c.Name, days)
+	case c.Difference > 0:
+		return fmt.Sprintf("An Tagen mit „%s“ ist deine Stimmung im Schnitt %s Punkte höher %s.", c.Name, amount, days)
+	default:
+		return fmt.Sprintf("An Tagen mit „%s“ ist deine Stimmung im Schnitt %s Punkte niedriger %s.", c.Name, amount, days)
+	}
+}
+
+// formatMoodScore prints a mood score with one decimal and a decimal comma
+func formatMoodScore(value float64) string {
+	return strings.Replace(fmt.Sprintf("%.1f", value), ".", ",", 1)
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// stddev is the population standard deviation of values
+func stddev(values []float64) float64 {
+	m := mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
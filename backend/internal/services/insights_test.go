@@ -0,0 +1,193 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"habit-tracker-backend/internal/models"
+)
+
+// moodDays are the moods of Monday 2 to Tuesday 10 March 2026
+var moodDays = []MoodDay{
+	{Date: "2026-03-02", Mood: "excellent", Score: 5},
+	{Date: "2026-03-03", Mood: "good", Score: 4},
+	{Date: "2026-03-04", Mood: "excellent", Score: 5},
+	{Date: "2026-03-05", Mood: "bad", Score: 2},
+	{Date: "2026-03-06", Mood: "terrible", Score: 1},
+	{Date: "2026-03-07", Mood: "bad", Score: 2},
+	{Date: "2026-03-08", Mood: "okay", Score: 3},
+	{Date: "2026-03-09", Mood: "bad", Score: 2},
+	{Date: "2026-03-10", Mood: "terrible", Score: 1},
+}
+
+// moodHistory is a habit created on 1 March and done on 2, 3, 4 and 9 March
+func moodHistory(schedule models.HabitSchedule) HabitHistory {
+	return HabitHistory{
+		HabitID:   1,
+		Schedule:  schedule,
+		Anchor:    time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		Completed: map[string]int{"2026-03-02": 1, "2026-03-03": 1, "2026-03-04": 1, "2026-03-09": 1},
+	}
+}
+
+// onDays reports whether a day is one of dates
+func onDays(dates ...string) func(time.Time) bool {
+	return func(day time.Time) bool {
+		for _, date := range dates {
+			if day.Format(DateLayout) == date {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestAnalyzeMoodWeekly(t *testing.T) {
+	days := []MoodDay{
+		{Date: "2026-03-02", Score: 4}, // Monday
+		{Date: "2026-03-04", Score: 2},
+		{Date: "2026-03-08", Score: 3}, // Sunday
+		{Date: "2026-03-09", Score: 5}, // next Monday
+		{Date: "2026-03-10", Score: 4},
+	}
+	insights := AnalyzeMood(days, nil)
+
+	if insights.From != "2026-03-02" || insights.To != "2026-03-10" {
+		t.Errorf("range = %s to %s, want 2026-03-02 to 2026-03-10", insights.From, insights.To)
+	}
+	if insights.Average == nil || *insights.Average != 3.6 {
+		t.Errorf("average = %v, want 3.6", insights.Average)
+	}
+	want := []MoodWeek{
+		{WeekStart: "2026-03-02", Average: 3, Days: 3},
+		{WeekStart: "2026-03-09", Average: 4.5, Days: 2},
+	}
+	if !reflect.DeepEqual(insights.Weekly, want) {
+		t.Errorf("weekly = %+v, want %+v", insights.Weekly, want)
+	}
+}
+
+func TestAnalyzeMoodWithoutMoods(t *testing.T) {
+	insights := AnalyzeMood([]MoodDay{}, []MoodHabit{{Name: "Lesen", History: moodHistory(models.HabitSchedule{Type: ScheduleDaily})}})
+	if insights.Average != nil || len(insights.Weekly) != 0 || len(insights.Habits) != 0 {
+		t.Errorf("AnalyzeMood without moods = %+v, want no average, weeks or habits", insights)
+	}
+}
+
+func TestCorrelateMood(t *testing.T) {
+	daily := models.HabitSchedule{Type: ScheduleDaily}
+	tests := []struct {
+		name   string
+		modify func(h *HabitHistory)
+		ok     bool
+		done   int
+		missed int
+	}{
+		{name: "daily", ok: true, done: 4, missed: 5},
+		{
+			name:   "archived days are left out",
+			modify: func(h *HabitHistory) { h.Archived = onDays("2026-03-06") },
+			ok:     true, done: 4, missed: 4,
+		},
+		{
+			name:   "rest days are left out",
+			modify: func(h *HabitHistory) { h.Excused = onDays("2026-03-05") },
+			ok:     true, done: 4, missed: 4,
+		},
+		{
+			name:   "days before the habit was created are left out",
+			modify: func(h *HabitHistory) { h.Anchor = time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC) },
+			ok:     true, done: 3, missed: 5,
+		},
+		{
+			name: "unscheduled weekdays are left out",
+			modify: func(h *HabitHistory) {
+				h.Schedule = models.HabitSchedule{Type: ScheduleWeekdays, Days: []int{1, 2, 3, 4, 5}}
+			},
+			ok: true, done: 4, missed: 3,
+		},
+		{
+			name:   "quota habits can be done on any day",
+			modify: func(h *HabitHistory) { h.Schedule = models.HabitSchedule{Type: SchedulePerWeek, Count: 3} },
+			ok:     true, done: 4, missed: 5,
+		},
+		{
+			name: "too few days done",
+			modify: func(h *HabitHistory) {
+				h.Completed = map[string]int{"2026-03-02": 1, "2026-03-03": 1}
+			},
+		},
+		{
+			name: "too few days missed",
+			modify: func(h *HabitHistory) {
+				h.Schedule = models.HabitSchedule{Type: ScheduleWeekdays, Days: []int{1, 2, 3, 4, 5}}
+				h.Excused = onDays("2026-03-05")
+			},
+		},
+		{
+			name:   "archived while done",
+			modify: func(h *HabitHistory) { h.Archived = onDays("2026-03-02", "2026-03-03") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := moodHistory(daily)
+			if tt.modify != nil {
+				tt.modify(&h)
+			}
+			c, ok := correlateMood(moodDays, MoodHabit{Name: "Meditieren", History: h})
+			if ok != tt.ok {
+				t.Fatalf("correlateMood() ok = %v, want %v (%+v)", ok, tt.ok, c)
+			}
+			if ok && (c.DaysDone != tt.done || c.DaysMissed != tt.missed) {
+				t.Errorf("days done/missed = %d/%d, want %d/%d", c.DaysDone, c.DaysMissed, tt.done, tt.missed)
+			}
+		})
+	}
+}
+
+func TestCorrelateMoodValues(t *testing.T) {
+	// Done on the first three days, missed on the next three
+	c, ok := correlateMood(moodDays[:6], MoodHabit{Name: "Meditieren", History: moodHistory(models.HabitSchedule{Type: ScheduleDaily})})
+	if !ok {
+		t.Fatal("correlateMood() found no correlation")
+	}
+	want := HabitMoodCorrelation{
+		HabitID:     1,
+		Name:        "Meditieren",
+		DaysDone:    3,
+		DaysMissed:  3,
+		MoodDone:    4.67,
+		MoodMissed:  1.67,
+		Difference:  3,
+		Correlation: 0.95,
+		Summary:     "An Tagen mit „Meditieren“ ist deine Stimmung im Schnitt 3,0 Punkte höher (3 von 6 Tagen).",
+	}
+	if c != want {
+		t.Errorf("correlateMood() = %+v, want %+v", c, want)
+	}
+}
+
+func TestAnalyzeMoodOrdersHabits(t *testing.T) {
+	weak := moodHistory(models.HabitSchedule{Type: ScheduleDaily})
+	weak.HabitID = 2
+	weak.Completed = map[string]int{"2026-03-02": 1, "2026-03-05": 1, "2026-03-07": 1}
+	strong := moodHistory(models.HabitSchedule{Type: ScheduleDaily})
+	rare := moodHistory(models.HabitSchedule{Type: ScheduleDaily})
+	rare.HabitID = 3
+	rare.Completed = map[string]int{"2026-03-02": 1}
+
+	insights := AnalyzeMood(moodDays, []MoodHabit{
+		{Name: "Spazieren", History: weak},
+		{Name: "Meditieren", History: strong},
+		{Name: "Laufen", History: rare},
+	})
+	var names []string
+	for _, c := range insights.Habits {
+		names = append(names, c.Name)
+	}
+	if want := []string{"Meditieren", "Spazieren"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("habits = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"habit-tracker-backend/internal/database"
+	"habit-tracker-backend/internal/models"
+)
+
+// Periods journal summaries are cached for
+const (
+	JournalPeriodWeek  = "week"
+	JournalPeriodMonth = "month"
+)
+
+// journalSummaryMaxTokens bounds the length of a generated summary
+const journalSummaryMaxTokens = 800
+
+// ErrNoJournalEntries is returned when there is nothing to summarize
+var ErrNoJournalEntries = errors.New("no journal entries in this period")
+
+const journalSummarySystemPrompt = `Du bist ein hilfreicher Assistent für ein Tagebuch-Tool.
+Du sollst eine aussagekräftige und reflektierende Zusammenfassung der bereitgestellten Journal-Einträge erstellen.
+Die Zusammenfassung sollte:
+- Die wichtigsten Themen und Muster identifizieren
+- Die Entwicklung der Stimmung über die Zeit beschreiben
+- Wiederkehrende Themen oder Gewohnheiten hervorheben
+- Eine positive und reflektierende Perspektive bieten
+- Auf Deutsch formuliert sein
+- Maximal 500 Wörter lang sein
+
+Antworte nur mit der Zusammenfassung, kein zusätzlicher Text.`
+
+var journalMoodLabels = map[string]string{
+	"excellent": "Ausgezeichnet",
+	"good":      "Gut",
+	"okay":      "Okay",
+	"bad":       "Schlecht",
+	"terrible":  "Schrecklich",
+}
+
+// JournalPeriodBounds returns the first and last day of the week (Monday to Sunday) or
+// month that contains day
+func JournalPeriodBounds(period string, day time.Time) (time.Time, time.Time, error) {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	switch period {
+	case JournalPeriodWeek:
+		start := day.AddDate(0, 0, 1-isoWeekday(day))
+		return start, start.AddDate(0, 0, 6), nil
+	case JournalPeriodMonth:
+		start := day.AddDate(0, 0, 1-day.Day())
+		return start, start.AddDate(0, 1, -1), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid period %q, expected week or month", period)
+}
+
+// JournalSummarizer generates summaries of users' journal entries from what is stored
+// for them
+type JournalSummarizer struct {
+	AI *OpenAIService
+}
+
+// NewJournalSummarizer creates a summarizer using the OpenAI service
+func NewJournalSummarizer() *JournalSummarizer {
+	return &JournalSummarizer{AI: NewOpenAIService()}
+}
+
+// SummarizeRange generates a summary of the user's entries from one day to another. It is
+// not cached.
+func (s *JournalSummarizer) SummarizeRange(userID int, from, to time.Time) (string, int, error) {
+	prompt, count, err := journalSummaryPrompt(userID, from, to)
+	if err != nil {
+		return "", 0, err
+	}
+	summary, err := s.AI.GenerateResponseWithMaxTokens(prompt, journalSummarySystemPrompt, journalSummaryMaxTokens)
+	return summary, count, err
+}
+
+// SummarizePeriod returns the summary of the week or month containing day. It is only
+// generated when there is none yet or the entries changed since; the second result tells
+// whether it was.
+func (s *JournalSummarizer) SummarizePeriod(userID int, period string, day time.Time) (models.JournalSummary, bool, error) {
+	start, end, err := JournalPeriodBounds(period, day)
+	if err != nil {
+		return models.JournalSummary{}, false, err
+	}
+	prompt, count, err := journalSummaryPrompt(userID, start, end)
+	if err != nil {
+		return models.JournalSummary{}, false, err
+	}
+	sum := sha256.Sum256([]byte(prompt))
+	hash := hex.EncodeToString(sum[:])
+
+	cached, cachedHash, err := fetchJournalSummary(userID, period, start)
+	if err == nil && cachedHash == hash {
+		return cached, false, nil
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return models.JournalSummary{}, false, err
+	}
+
+	summary, err := s.AI.GenerateResponseWithMaxTokens(prompt, journalSummarySystemPrompt, journalSummaryMaxTokens)
+	if err != nil {
+		return models.JournalSummary{}, false, err
+	}
+	_, err = database.DB.Exec(`
+		INSERT INTO journal_summaries (user_id, period, period_start, period_end, summary, entry_count, entries_hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE summary = VALUES(summary), entry_count = VALUES(entry_count),
+			entries_hash = VALUES(entries_hash), period_end = VALUES(period_end)
+	`, userID, period, start.Format(DateLayout), end.Format(DateLayout), summary, count, hash)
+	if err != nil {
+		return models.JournalSummary{}, false, fmt.Errorf("failed to store journal summary: %v", err)
+	}
+	stored, _, err := fetchJournalSummary(userID, period, start)
+	return stored, true, err
+}
+
+// fetchJournalSummary loads a cached summary and the hash of the entries it was made of
+func fetchJournalSummary(userID int, period string, start time.Time) (models.JournalSummary, string, error) {
+	var summary models.JournalSummary
+	var hash string
+	err := database.DB.QueryRow(`
+		SELECT id, user_id, period, period_start, period_end, summary, entry_count, entries_hash, created_at, updated_at
+		FROM journal_summaries WHERE user_id = ? AND period = ? AND period_start = ?
+	`, userID, period, start.Format(DateLayout)).Scan(&summary.ID, &summary.UserID, &summary.Period, &summary.PeriodStart,
+		&summary.PeriodEnd, &summary.Summary, &summary.EntryCount, &hash, &summary.CreatedAt, &summary.UpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return summary, "", fmt.Errorf("failed to query journal summary: %v", err)
+	}
+	return summary, hash, err
+}
+
+// journalSummaryPrompt loads the user's entries from one day to another and writes them
+// into the prompt a summary is generated from, returning how many there are. The prompt
+// covers everything a summary depends on, so its hash tells whether one is outdated.
+func journalSummaryPrompt(userID int, from, to time.Time) (string, int, error) {
+	rows, err := database.DB.Query(`
+		SELECT entry_date, mood, content, fields_text,
+		       (SELECT GROUP_CONCAT(t.name ORDER BY et.position SEPARATOR ', ')
+		        FROM journal_entry_tags et
+		        INNER JOIN journal_tags t ON t.id = et.tag_id
+		        WHERE et.entry_id = journal_entries.id) AS tags
+		FROM journal_entries
+		WHERE user_id = ? AND entry_date BETWEEN ? AND ?
+		ORDER BY entry_date
+	`, userID, from.Format(DateLayout), to.Format(DateLayout))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to query journal entries: %v", err)
+	}
+	defer rows.Close()
+
+	var sb strings.Builder
+	count := 0
+	for rows.Next() {
+		var date time.Time
+		var mood, content, fields, tags sql.NullString
+		if err := rows.Scan(&date, &mood, &content, &fields, &tags); err != nil {
+			return "", 0, fmt.Errorf("failed to scan journal entry: %v", err)
+		}
+		count++
+		sb.WriteString(fmt.Sprintf("=== Eintrag %d: %s ===\n", count, date.Format("02.01.2006")))
+		if label, ok := journalMoodLabels[mood.String]; ok {
+			sb.WriteString(fmt.Sprintf("Stimmung: %s\n", label))
+		}
+		if tags.String != "" {
+			sb.WriteString(fmt.Sprintf("Tags: %s\n", tags.String))
+		}
+		if fields.String != "" {
+			sb.WriteString(fmt.Sprintf("%s\n", fields.String))
+		}
+		if content.String != "" {
+			sb.WriteString(fmt.Sprintf("Inhalt:\n%s\n\n", content.String))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", 0, fmt.Errorf("failed to read journal entries: %v", err)
+	}
+	if count == 0 {
+		return "", 0, ErrNoJournalEntries
+	}
+
+	return fmt.Sprintf("Bitte erstelle eine Zusammenfassung dieser Journal-Einträge vom %s bis %s:\n\n%s",
+		from.Format("02.01.2006"), to.Format("02.01.2006"), sb.String()), count, nil
+}
+
+// SendJournalReviews summarizes the last finished week and month of users who turned on
+// "weekly_journal_review" or "monthly_journal_review" and wrote in them, and notifies them
+// once a review is ready. Reviews already generated are only regenerated when entries of
+// their period changed after all.
+func SendJournalReviews(summarizer *JournalSummarizer, sender reminderSender, now time.Time) error {
+	if summarizer.AI.APIKey == "" {
+		return nil
+	}
+	rows, err := database.DB.Query(`
+		SELECT id,
+		       COALESCE(JSON_EXTRACT(settings, '$.weekly_journal_review') = true, false),
+		       COALESCE(JSON_EXTRACT(settings, '$.monthly_journal_review') = true, false)
+		FROM users
+		WHERE JSON_EXTRACT(settings, '$.weekly_journal_review') = true
+		   OR JSON_EXTRACT(settings, '$.monthly_journal_review') = true
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query journal reviews: %v", err)
+	}
+	type journalReview struct {
+		UserID  int
+		Weekly  bool
+		Monthly bool
+	}
+	var reviews []journalReview
+	for rows.Next() {
+		var r journalReview
+		if err := rows.Scan(&r.UserID, &r.Weekly, &r.Monthly); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan journal review: %v", err)
+		}
+		reviews = append(reviews, r)
+	}
+	rows.Close()
+
+	for _, r := range reviews {
+		today := StartOfDay(now, UserLocation(r.UserID))
+		if r.Weekly {
+			lastWeek, _, _ := JournalPeriodBounds(JournalPeriodWeek, today.AddDate(0, 0, -7))
+			if err := sendJournalReview(summarizer, sender, r.UserID, JournalPeriodWeek, lastWeek); err != nil {
+				return err
+			}
+		}
+		if r.Monthly {
+			lastMonth := time.Date(today.Year(), today.Month()-1, 1, 0, 0, 0, 0, today.Location())
+			if err := sendJournalReview(summarizer, sender, r.UserID, JournalPeriodMonth, lastMonth); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sendJournalReview summarizes the period starting at start and notifies the user of it
+func sendJournalReview(summarizer *JournalSummarizer, sender reminderSender, userID int, period string, start time.Time) error {
+	summary, _, err := summarizer.SummarizePeriod(userID, period, start)
+	if err == ErrNoJournalEntries {
+		return nil
+	}
+	if err != nil {
+		// A failed generation is retried on the next run and shouldn't hold up other users
+		log.Printf("Failed to generate %s journal review of user %d: %v", period, userID, err)
+		return nil
+	}
+
+	title := "Dein Wochenrückblick ist da"
+	if period == JournalPeriodMonth {
+		title = "Dein Monatsrückblick ist da"
+	}
+	return sender.Send(Notification{
+		UserID: userID,
+		Kind:   NotificationJournalReview,
+		Title:  title,
+		Body: fmt.Sprintf("%s bis %s, %d Einträge", summary.PeriodStart.Format("02.01."),
+			summary.PeriodEnd.Format("02.01.2006"), summary.EntryCount),
+		URL: "/journal",
+		Key: fmt.Sprintf("journal_review:%s:%s", period, start.Format(DateLayout)),
+	})
+}
+
+// StartJournalReviewJob generates scheduled journal reviews and sends them through the
+// dispatcher, checking once at startup and then every interval
+func StartJournalReviewJob(summarizer *JournalSummarizer, dispatcher *NotificationDispatcher, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := SendJournalReviews(summarizer, dispatcher, time.Now()); err != nil {
+				log.Printf("Journal review job failed: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"habit-tracker-backend/internal/models"
+)
+
+// journalFieldKey is what the keys of template fields look like
+var journalFieldKey = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// maxJournalListItems is how many items a list answer may have
+const maxJournalListItems = 50
+
+// journalTopItems is how many of the most frequent list items field stats report
+const journalTopItems = 10
+
+// ValidateTemplateFields checks that the fields of a template have well-formed, unique
+// keys, a label and a known type
+func ValidateTemplateFields(fields []models.JournalTemplateField) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("a template needs at least one field")
+	}
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if !journalFieldKey.MatchString(field.Key) {
+			return fmt.Errorf("invalid field key %q, use 1 to 50 of a-z, 0-9 and _", field.Key)
+		}
+		if seen[field.Key] {
+			return fmt.Errorf("duplicate field key %q", field.Key)
+		}
+		seen[field.Key] = true
+		if strings.TrimSpace(field.Label) == "" {
+			return fmt.Errorf("field %q needs a label", field.Key)
+		}
+		switch field.Type {
+		case models.JournalFieldList, models.JournalFieldScale, models.JournalFieldText, models.JournalFieldCheckbox:
+		default:
+			return fmt.Errorf("field %q has unknown type %q", field.Key, field.Type)
+		}
+	}
+	return nil
+}
+
+// ValidateJournalFields checks the answers of an entry against the fields of its template
+// and returns them normalized: texts and list items trimmed, empty list items dropped and
+// empty answers left out. Answers to unknown fields and unanswered required fields are
+// errors.
+func ValidateJournalFields(fields []models.JournalTemplateField, answers map[string]json.RawMessage) (map[string]interface{}, error) {
+	byKey := make(map[string]models.JournalTemplateField, len(fields))
+	for _, field := range fields {
+		byKey[field.Key] = field
+	}
+	for key := range answers {
+		if _, ok := byKey[key]; !ok {
+			return nil, fmt.Errorf("unknown field %q", key)
+		}
+	}
+
+	values := make(map[string]interface{}, len(answers))
+	for _, field := range fields {
+		raw, ok := answers[field.Key]
+		if ok && string(raw) != "null" {
+			value, err := parseJournalAnswer(field, raw)
+			if err != nil {
+				return nil, err
+			}
+			if value != nil {
+				values[field.Key] = value
+			}
+		}
+		if _, answered := values[field.Key]; field.Required && !answered {
+			return nil, fmt.Errorf("field %q is required", field.Key)
+		}
+	}
+	return values, nil
+}
+
+// parseJournalAnswer decodes the answer to a field, returning nil for an empty one
+func parseJournalAnswer(field models.JournalTemplateField, raw json.RawMessage) (interface{}, error) {
+	switch field.Type {
+	case models.JournalFieldList:
+		var items []string
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, fmt.Errorf("field %q must be a list of texts", field.Key)
+		}
+		list := []string{}
+		for _, item := range items {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		if len(list) > maxJournalListItems {
+			return nil, fmt.Errorf("field %q has more than %d items", field.Key, maxJournalListItems)
+		}
+		if len(list) == 0 {
+			return nil, nil
+		}
+		return list, nil
+	case models.JournalFieldScale:
+		var n float64
+		if err := json.Unmarshal(raw, &n); err != nil || n != math.Trunc(n) || n < 1 || n > 10 {
+			return nil, fmt.Errorf("field %q must be a whole number from 1 to 10", field.Key)
+		}
+		return int(n), nil
+	case models.JournalFieldText:
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, fmt.Errorf("field %q must be a text", field.Key)
+		}
+		if text = strings.TrimSpace(text); text == "" {
+			return nil, nil
+		}
+		return text, nil
+	case models.JournalFieldCheckbox:
+		var checked bool
+		if err := json.Unmarshal(raw, &checked); err != nil {
+			return nil, fmt.Errorf("field %q must be true or false", field.Key)
+		}
+		return checked, nil
+	}
+	return nil, fmt.Errorf("field %q has unknown type %q", field.Key, field.Type)
+}
+
+// JournalFieldsText writes the answers of an entry as "Label: answer" lines in the order
+// of the template's fields, for full-text search and the AI
+func JournalFieldsText(fields []models.JournalTemplateField, values map[string]interface{}) string {
+	var lines []string
+	for _, field := range fields {
+		value, ok := values[field.Key]
+		if !ok {
+			continue
+		}
+		var answer string
+		switch v := value.(type) {
+		case []string:
+			answer = strings.Join(v, ", ")
+		case int:
+			answer = fmt.Sprintf("%d/10", v)
+		case bool:
+			answer = "nein"
+			if v {
+				answer = "ja"
+			}
+		default:
+			answer = fmt.Sprint(v)
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", field.Label, answer))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// JournalFieldEntry is the mood and the stored answers of one journal entry
+type JournalFieldEntry struct {
+	Date   string
+	Mood   string
+	Fields map[string]json.RawMessage
+}
+
+// JournalScalePoint is the answer to a scale field on one day
+type JournalScalePoint struct {
+	Date  string `json:"date"`
+	Value int    `json:"value"`
+}
+
+// JournalListItem is an item of list answers and on how many days it was given
+type JournalListItem struct {
+	Item  string `json:"item"`
+	Count int    `json:"count"`
+}
+
+// JournalFieldStats summarizes the answers to one template field. Which of the optional
+// parts are set depends on the field's type.
+type JournalFieldStats struct {
+	Key             string              `json:"key"`
+	Label           string              `json:"label"`
+	Type            string              `json:"type"`
+	Answers         int                 `json:"answers"`
+	Average         *float64            `json:"average,omitempty"`          // scale
+	Series          []JournalScalePoint `json:"series,omitempty"`           // scale, oldest first
+	Checked         *int                `json:"checked,omitempty"`          // checkbox
+	Rate            *float64            `json:"rate,omitempty"`             // checkbox, share of answers checked
+	TopItems        []JournalListItem   `json:"top_items,omitempty"`        // list, most given first
+	MoodCorrelation *float64            `json:"mood_correlation,omitempty"` // scale and checkbox, -1 to 1
+}
+
+// AnalyzeJournalFields summarizes the answers of entries, oldest first, to each field
+// of a template. Scale and checkbox answers are correlated with the mood of the same
+// entry once minMoodSamples entries have both. List items are counted ignoring case.
+// Answers that don't match the field's current type, such as after the template was
+// changed, are skipped.
+func AnalyzeJournalFields(fields []models.JournalTemplateField, entries []JournalFieldEntry) []JournalFieldStats {
+	stats := make([]JournalFieldStats, 0, len(fields))
+	for _, field := range fields {
+		s := JournalFieldStats{Key: field.Key, Label: field.Label, Type: field.Type}
+		var values, moods []float64
+		checked := 0
+		items := map[string]*JournalListItem{}
+		var order []string
+
+		for _, entry := range entries {
+			raw, ok := entry.Fields[field.Key]
+			if !ok {
+				continue
+			}
+			value, err := parseJournalAnswer(field, raw)
+			if err != nil || value == nil {
+				continue
+			}
+			s.Answers++
+
+			number := 0.0
+			switch v := value.(type) {
+			case int:
+				number = float64(v)
+				s.Series = append(s.Series, JournalScalePoint{Date: entry.Date, Value: v})
+			case bool:
+				if v {
+					number = 1
+					checked++
+				}
+			case []string:
+				seen := map[string]bool{}
+				for _, item := range v {
+					key := strings.ToLower(item)
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+					if items[key] == nil {
+						items[key] = &JournalListItem{Item: item}
+						order = append(order, key)
+					}
+					items[key].Count++
+				}
+			}
+			if score, ok := MoodScores[entry.Mood]; ok {
+				values = append(values, number)
+				moods = append(moods, float64(score))
+			}
+		}
+
+		switch field.Type {
+		case models.JournalFieldScale:
+			if s.Answers > 0 {
+				total := 0
+				for _, point := range s.Series {
+					total += point.Value
+				}
+				average := round2(float64(total) / float64(s.Answers))
+				s.Average = &average
+			}
+		case models.JournalFieldCheckbox:
+			s.Checked = &checked
+			if s.Answers > 0 {
+				rate := round2(float64(checked) / float64(s.Answers))
+				s.Rate = &rate
+			}
+		case models.JournalFieldList:
+			for _, key := range order {
+				s.TopItems = append(s.TopItems, *items[key])
+			}
+			sort.SliceStable(s.TopItems, func(i, j int) bool { return s.TopItems[i].Count > s.TopItems[j].Count })
+			if len(s.TopItems) > journalTopItems {
+				s.TopItems = s.TopItems[:journalTopItems]
+			}
+		}
+		if field.Type == models.JournalFieldScale || field.Type == models.JournalFieldCheckbox {
+			if r, ok := pearson(values, moods); ok && len(values) >= minMoodSamples {
+				r = round2(r)
+				s.MoodCorrelation = &r
+			}
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// pearson computes the correlation of xs and ys; there is none when either doesn't vary
+func pearson(xs, ys []float64) (float64, bool) {
+	if len(xs) == 0 || len(xs) != len(ys) {
+		return 0, false
+	}
+	mx, my := mean(xs), mean(ys)
+	var cov, vx, vy float64
+	for i := range xs {
+		cov += (xs[i] - mx) * (ys[i] - my)
+		vx += (xs[i] - mx) * (xs[i] - mx)
+		vy += (ys[i] - my) * (ys[i] - my)
+	}
+	if vx == 0 || vy == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(vx*vy), true
+}
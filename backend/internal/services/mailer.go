@@ -0,0 +1,133 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Email is a plain text message to a single recipient
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails
+type Mailer interface {
+	Send(email Email) error
+}
+
+// NewMailer creates the mailer selected by MAIL_DRIVER ("smtp" or "log", default "log")
+func NewMailer() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Habit Tracker <no-reply@localhost>"
+	}
+
+	switch os.Getenv("MAIL_DRIVER") {
+	case "smtp":
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	default:
+		return &LogMailer{Path: os.Getenv("MAIL_LOG_FILE"), From: from}
+	}
+}
+
+// SMTPMailer sends emails through an SMTP server
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers the email via SMTP, using STARTTLS when the server offers it
+func (m *SMTPMailer) Send(email Email) error {
+	if m.Host == "" {
+		return fmt.Errorf("SMTP_HOST is not configured")
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	err := smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, envelopeAddress(m.From), []string{email.To}, formatEmail(m.From, email))
+	if err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+	return nil
+}
+
+// LogMailer writes emails to a file, or to the server log when no path is set.
+// Meant for local development and tests.
+type LogMailer struct {
+	Path string
+	From string
+	mu   sync.Mutex
+}
+
+// Send appends the email to the mail log
+func (m *LogMailer) Send(email Email) error {
+	message := formatEmail(m.From, email)
+	if m.Path == "" {
+		log.Printf("Outgoing email:\n%s", message)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open mail log: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "%s\n\n", message); err != nil {
+		return fmt.Errorf("failed to write mail log: %v", err)
+	}
+	return nil
+}
+
+// formatEmail renders the email as an RFC 5322 message
+func formatEmail(from string, email Email) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + email.To + "\r\n")
+	// Headers are ASCII; umlauts in the subject are sent as an RFC 2047 encoded word
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", email.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(email.Body)
+	return []byte(b.String())
+}
+
+// envelopeAddress extracts the bare address from "Name <address>"
+func envelopeAddress(from string) string {
+	if start := strings.Index(from, "<"); start != -1 {
+		if end := strings.Index(from[start:], ">"); end != -1 {
+			return from[start+1 : start+end]
+		}
+	}
+	return from
+}
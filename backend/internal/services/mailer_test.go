@@ -0,0 +1,51 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatEmail(t *testing.T) {
+	tests := []struct {
+		name        string
+		subject     string
+		wantSubject string
+	}{
+		{name: "ascii", subject: "Reset your password", wantSubject: "Subject: Reset your password\r\n"},
+		{name: "umlauts", subject: "Passwort zurücksetzen", wantSubject: "Subject: =?utf-8?q?Passwort_zur=C3=BCcksetzen?=\r\n"},
+		{name: "header injection", subject: "Hi\r\nBcc: victim@example.com", wantSubject: "Subject: =?utf-8?q?Hi=0D=0ABcc:_victim@example.com?=\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := string(formatEmail("Habit Tracker <no-reply@example.com>", Email{To: "user@example.com", Subject: tt.subject, Body: "Hallo"}))
+			headers, body, ok := strings.Cut(message, "\r\n\r\n")
+			if !ok || body != "Hallo" {
+				t.Fatalf("message = %q, want headers and the body after an empty line", message)
+			}
+			if !strings.Contains(headers+"\r\n", tt.wantSubject) {
+				t.Errorf("headers = %q, want %q", headers, tt.wantSubject)
+			}
+			for _, line := range strings.Split(headers, "\r\n") {
+				if strings.HasPrefix(line, "Bcc:") {
+					t.Errorf("subject injected the header %q", line)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvelopeAddress(t *testing.T) {
+	tests := []struct {
+		from string
+		want string
+	}{
+		{from: "Habit Tracker <no-reply@example.com>", want: "no-reply@example.com"},
+		{from: "no-reply@example.com", want: "no-reply@example.com"},
+		{from: "Broken <no-reply@example.com", want: "Broken <no-reply@example.com"},
+	}
+	for _, tt := range tests {
+		if got := envelopeAddress(tt.from); got != tt.want {
+			t.Errorf("envelopeAddress(%q) = %q, want %q", tt.from, got, tt.want)
+		}
+	}
+}
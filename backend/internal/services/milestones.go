@@ -0,0 +1,88 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Milestone is a dated line of a plan
+type Milestone struct {
+	Text string
+	Date time.Time
+}
+
+// ParseMilestones reads the dated lines of plan texts, such as the "time and milestones"
+// answer and the generated plan, ordered by date. Relative dates ("Woche 2", "in 3 Monaten")
+// count from ref, the day the plan was written. A date found in several texts is kept once.
+func ParseMilestones(ref time.Time, texts ...string) []Milestone {
+	var milestones []Milestone
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*•#"))
+			if line == "" {
+				continue
+			}
+			date, _, ok := FindDate(line, ref)
+			if !ok {
+				continue
+			}
+			key := date.Format(DateLayout)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			milestones = append(milestones, Milestone{Text: line, Date: date})
+		}
+	}
+	sort.SliceStable(milestones, func(i, j int) bool {
+		return milestones[i].Date.Before(milestones[j].Date)
+	})
+	return milestones
+}
+
+// MilestoneFor picks the milestone a checklist item belongs to: the one sharing the most
+// words with it, or else the one at the same share of the plan as the item's index among
+// count items, since checklists are generated in the order of the plan
+func MilestoneFor(milestones []Milestone, itemText string, index, count int) (Milestone, bool) {
+	if len(milestones) == 0 {
+		return Milestone{}, false
+	}
+
+	itemWords := significantWords(itemText)
+	best, bestScore := 0, 0
+	for i, m := range milestones {
+		score := 0
+		for word := range significantWords(m.Text) {
+			if itemWords[word] {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if bestScore > 0 {
+		return milestones[best], true
+	}
+
+	if count < 1 || index < 0 || index >= count {
+		return milestones[len(milestones)-1], true
+	}
+	return milestones[(index*len(milestones))/count], true
+}
+
+// significantWords returns the lower-cased words of text long enough to carry meaning
+func significantWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) >= 5 {
+			words[word] = true
+		}
+	}
+	return words
+}
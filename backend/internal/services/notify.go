@@ -0,0 +1,486 @@
+package services
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"habit-tracker-backend/internal/database"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Notification kinds
+const (
+	NotificationTaskDue       = "task_due"
+	NotificationHabitReminder = "habit_reminder"
+	NotificationJournalNudge  = "journal_nudge"
+	NotificationJournalReview = "journal_review"
+)
+
+// Notification is a message to a user. Key identifies what it is about, such as
+// "habit:3:2026-03-15"; a user never receives two notifications with the same key.
+type Notification struct {
+	UserID int
+	Kind   string
+	Title  string
+	Body   string
+	URL    string // path in the app the notification opens
+	Key    string
+}
+
+// Notifier delivers notifications through one channel. Notifiers decide themselves
+// whether the user wants to be reached through their channel.
+type Notifier interface {
+	Name() string
+	Notify(n Notification) error
+}
+
+// InboxNotifier stores notifications in the user's in-app inbox
+type InboxNotifier struct{}
+
+// Name returns "inbox"
+func (InboxNotifier) Name() string { return "inbox" }
+
+// Notify stores the notification unless one with the same key exists
+func (i InboxNotifier) Notify(n Notification) error {
+	_, err := i.Add(n)
+	return err
+}
+
+// Add stores the notification and reports whether it is new. The unique
+// (user_id, dedupe_key) key makes concurrent and repeated runs harmless.
+func (InboxNotifier) Add(n Notification) (bool, error) {
+	result, err := database.DB.Exec(`
+		INSERT IGNORE INTO notifications (user_id, kind, title, body, url, dedupe_key)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, n.UserID, n.Kind, n.Title, n.Body, n.URL, n.Key)
+	if err != nil {
+		return false, fmt.Errorf("failed to store notification: %v", err)
+	}
+	inserted, _ := result.RowsAffected()
+	return inserted == 1, nil
+}
+
+// EmailNotifier emails notifications to users who turned on "email_notifications"
+type EmailNotifier struct {
+	Mailer Mailer
+	AppURL string // prefix of notification URLs in the email
+}
+
+// Name returns "email"
+func (EmailNotifier) Name() string { return "email" }
+
+// Notify emails the notification if the user opted in
+func (e EmailNotifier) Notify(n Notification) error {
+	var email string
+	var enabled sql.NullBool
+	err := database.DB.QueryRow(`
+		SELECT email, JSON_EXTRACT(settings, '$.email_notifications') = true FROM users WHERE id = ?
+	`, n.UserID).Scan(&email, &enabled)
+	if err != nil {
+		return fmt.Errorf("failed to load email settings: %v", err)
+	}
+	if !enabled.Bool {
+		return nil
+	}
+
+	body := n.Body
+	if n.URL != "" {
+		body += "\n\n" + strings.TrimRight(e.AppURL, "/") + n.URL
+	}
+	return e.Mailer.Send(Email{To: email, Subject: n.Title, Body: body})
+}
+
+// WebPushNotifier sends notifications to the browsers a user subscribed with, unless
+// they turned off "push_notifications". Messages are encrypted as described in
+// RFC 8291 and authorized with a VAPID token (RFC 8292).
+type WebPushNotifier struct {
+	PublicKey  string // base64url encoded uncompressed P-256 point, handed to browsers
+	privateKey *ecdsa.PrivateKey
+	subject    string
+	client     *http.Client
+}
+
+// NewWebPushNotifier reads the VAPID key pair from VAPID_PUBLIC_KEY and VAPID_PRIVATE_KEY
+// (base64url, as generated by "npx web-push generate-vapid-keys") and the contact
+// from VAPID_SUBJECT. It returns nil when no keys are configured.
+func NewWebPushNotifier() (*WebPushNotifier, error) {
+	public, private := os.Getenv("VAPID_PUBLIC_KEY"), os.Getenv("VAPID_PRIVATE_KEY")
+	if public == "" && private == "" {
+		return nil, nil
+	}
+
+	publicBytes, err := decodeBase64URL(public)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID_PUBLIC_KEY: %v", err)
+	}
+	privateBytes, err := decodeBase64URL(private)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID_PRIVATE_KEY: %v", err)
+	}
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), privateBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID_PRIVATE_KEY: %v", err)
+	}
+	derived, err := key.PublicKey.Bytes()
+	if err != nil || !bytes.Equal(derived, publicBytes) {
+		return nil, fmt.Errorf("VAPID_PUBLIC_KEY does not belong to VAPID_PRIVATE_KEY")
+	}
+
+	subject := os.Getenv("VAPID_SUBJECT")
+	if subject == "" {
+		subject = "mailto:admin@localhost"
+	}
+	// Endpoints come from browsers, so the client only connects to public addresses,
+	// whatever a host resolves to when it is dialed (also on redirects). There is no
+	// proxy, which would connect on the client's behalf.
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: dialPublicOnly}
+	return &WebPushNotifier{
+		PublicKey:  public,
+		privateKey: key,
+		subject:    subject,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: 10 * time.Second,
+			},
+		},
+	}, nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which is not reachable
+// from the internet either
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// publicIP reports whether ip is an address on the internet rather than a loopback,
+// private, link-local (such as cloud metadata at 169.254.169.254) or otherwise local one
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() &&
+		!ip.IsUnspecified() && !sharedAddressSpace.Contains(ip)
+}
+
+// dialPublicOnly is a net.Dialer Control function refusing connections to addresses
+// that are not public
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("refusing to connect to non-public address %s", host)
+	}
+	return nil
+}
+
+// ValidatePushEndpoint checks that a push endpoint is an https URL on a public host.
+// Push services are on the internet, so anything resolving to a loopback, private or
+// link-local address would only make the server send requests into its own network.
+func ValidatePushEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("push endpoint must be an https URL")
+	}
+	ips, err := net.LookupIP(u.Hostname())
+	if err != nil || len(ips) == 0 {
+		return fmt.Errorf("push endpoint host can't be resolved")
+	}
+	for _, ip := range ips {
+		if !publicIP(ip) {
+			return fmt.Errorf("push endpoint must be on a public host")
+		}
+	}
+	return nil
+}
+
+// Name returns "push"
+func (*WebPushNotifier) Name() string { return "push" }
+
+// pushSubscription is a browser's push endpoint and the keys to encrypt for it
+type pushSubscription struct {
+	ID       int
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+// Notify pushes the notification to all of the user's subscriptions. Subscriptions
+// the push service reports as gone are deleted.
+func (p *WebPushNotifier) Notify(n Notification) error {
+	var disabled sql.NullBool
+	err := database.DB.QueryRow(`
+		SELECT JSON_EXTRACT(settings, '$.push_notifications') = false FROM users WHERE id = ?
+	`, n.UserID).Scan(&disabled)
+	if err != nil {
+		return fmt.Errorf("failed to load push settings: %v", err)
+	}
+	if disabled.Bool {
+		return nil
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, endpoint, p256dh, auth FROM push_subscriptions WHERE user_id = ?
+	`, n.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to query push subscriptions: %v", err)
+	}
+	var subscriptions []pushSubscription
+	for rows.Next() {
+		var s pushSubscription
+		if err := rows.Scan(&s.ID, &s.Endpoint, &s.P256dh, &s.Auth); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan push subscription: %v", err)
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	rows.Close()
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"kind":  n.Kind,
+		"title": n.Title,
+		"body":  n.Body,
+		"url":   n.URL,
+		"tag":   n.Key,
+	})
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, s := range subscriptions {
+		gone, err := p.send(s, payload)
+		if gone {
+			if _, err := database.DB.Exec("DELETE FROM push_subscriptions WHERE id = ?", s.ID); err != nil {
+				log.Printf("Failed to delete expired push subscription %d: %v", s.ID, err)
+			}
+			continue
+		}
+		if err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to push to %d of %d subscriptions: %s", len(failed), len(subscriptions), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// send posts an encrypted payload to one subscription. gone reports that the push
+// service no longer knows the subscription.
+func (p *WebPushNotifier) send(s pushSubscription, payload []byte) (gone bool, err error) {
+	body, err := encryptPushPayload(s, payload)
+	if err != nil {
+		return false, err
+	}
+	// Subscriptions saved before endpoints were validated may point anywhere; the
+	// client's dialer refuses non-public addresses in any case
+	endpoint, err := url.Parse(s.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Hostname() == "" {
+		return true, fmt.Errorf("invalid endpoint %q", s.Endpoint)
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": endpoint.Scheme + "://" + endpoint.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": p.subject,
+	}).SignedString(p.privateKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to sign VAPID token: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", "86400")
+	req.Header.Set("Urgency", "normal")
+	req.Header.Set("Authorization", "vapid t="+token+", k="+p.PublicKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("push request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return true, nil
+	}
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return false, fmt.Errorf("push service returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return false, nil
+}
+
+// pushRecordSize is the record size announced in the aes128gcm header; payloads
+// always fit a single record
+const pushRecordSize = 4096
+
+// encryptPushPayload encrypts payload for the subscription's browser (RFC 8291)
+func encryptPushPayload(s pushSubscription, payload []byte) ([]byte, error) {
+	uaPublicBytes, err := decodeBase64URL(s.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %v", err)
+	}
+	authSecret, err := decodeBase64URL(s.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth secret: %v", err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %v", err)
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	keyInfo := "WebPush: info\x00" + string(uaPublicBytes) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 0x02 marks the last (and only) record
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	if len(plaintext)+gcm.Overhead() > pushRecordSize {
+		return nil, fmt.Errorf("push payload too large")
+	}
+
+	header := make([]byte, 0, 21+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, pushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// decodeBase64URL decodes base64url with or without padding, as browsers and key
+// generators differ
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(strings.TrimSpace(s), "="))
+}
+
+// NotificationDispatcher stores notifications in the inbox and fans new ones out to
+// the other channels
+type NotificationDispatcher struct {
+	Inbox    InboxNotifier
+	Channels []Notifier
+}
+
+// NewNotificationDispatcher sets up the inbox, email and, when VAPID keys are
+// configured, Web Push. Email links point to the frontend at APP_URL.
+func NewNotificationDispatcher() *NotificationDispatcher {
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:5173"
+	}
+	d := &NotificationDispatcher{}
+	d.Channels = append(d.Channels, EmailNotifier{Mailer: NewMailer(), AppURL: appURL})
+	push, err := NewWebPushNotifier()
+	if err != nil {
+		log.Printf("Web Push disabled: %v", err)
+	} else if push != nil {
+		d.Channels = append(d.Channels, push)
+	}
+	return d
+}
+
+// Send delivers a notification once: if the inbox already holds one with the same
+// key, nothing is sent again. A channel failing doesn't stop the others.
+func (d *NotificationDispatcher) Send(n Notification) error {
+	added, err := d.Inbox.Add(n)
+	if err != nil || !added {
+		return err
+	}
+	for _, channel := range d.Channels {
+		if err := channel.Notify(n); err != nil {
+			log.Printf("Failed to send %s notification %q to user %d: %v", channel.Name(), n.Key, n.UserID, err)
+		}
+	}
+	return nil
+}
+
+// PushPublicKey returns the VAPID public key browsers subscribe with, or "" when Web
+// Push is not configured
+func (d *NotificationDispatcher) PushPublicKey() string {
+	for _, channel := range d.Channels {
+		if push, ok := channel.(*WebPushNotifier); ok {
+			return push.PublicKey
+		}
+	}
+	return ""
+}
+
+// ValidatePushKeys checks the keys of a browser's push subscription: an uncompressed
+// P-256 point and a 16 byte auth secret, both base64url encoded
+func ValidatePushKeys(p256dh, auth string) error {
+	key, err := decodeBase64URL(p256dh)
+	if err != nil {
+		return fmt.Errorf("invalid p256dh key")
+	}
+	if _, err := ecdh.P256().NewPublicKey(key); err != nil {
+		return fmt.Errorf("invalid p256dh key")
+	}
+	secret, err := decodeBase64URL(auth)
+	if err != nil || len(secret) != 16 {
+		return fmt.Errorf("invalid auth secret")
+	}
+	return nil
+}
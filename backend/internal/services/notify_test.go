@@ -0,0 +1,55 @@
+package services
+
+import "testing"
+
+func TestValidatePushEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		wantErr  bool
+	}{
+		{name: "public address", endpoint: "https://8.8.8.8/push/abc"},
+		{name: "public ipv6 address", endpoint: "https://[2001:4860:4860::8888]/push/abc"},
+		{name: "http", endpoint: "http://8.8.8.8/push/abc", wantErr: true},
+		{name: "no host", endpoint: "https:///push/abc", wantErr: true},
+		{name: "not a url", endpoint: "push service", wantErr: true},
+		{name: "loopback", endpoint: "https://127.0.0.1:8080/admin", wantErr: true},
+		{name: "ipv6 loopback", endpoint: "https://[::1]/admin", wantErr: true},
+		{name: "private network", endpoint: "https://10.0.0.5/admin", wantErr: true},
+		{name: "private network 192.168", endpoint: "https://192.168.1.1/", wantErr: true},
+		{name: "cloud metadata", endpoint: "https://169.254.169.254/latest/meta-data", wantErr: true},
+		{name: "ipv4 mapped ipv6", endpoint: "https://[::ffff:127.0.0.1]/admin", wantErr: true},
+		{name: "unspecified", endpoint: "https://0.0.0.0/", wantErr: true},
+		{name: "shared address space", endpoint: "https://100.64.0.1/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePushEndpoint(tt.endpoint); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePushEndpoint(%q) error = %v, want error %v", tt.endpoint, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDialPublicOnly(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{address: "8.8.8.8:443"},
+		{address: "[2001:4860:4860::8888]:443"},
+		{address: "127.0.0.1:443", wantErr: true},
+		{address: "172.16.0.1:443", wantErr: true},
+		{address: "169.254.169.254:80", wantErr: true},
+		{address: "[fe80::1]:443", wantErr: true},
+		{address: "[fd00::1]:443", wantErr: true},
+		{address: "8.8.8.8", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if err := dialPublicOnly("tcp", tt.address, nil); (err != nil) != tt.wantErr {
+				t.Errorf("dialPublicOnly(%q) error = %v, want error %v", tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Sources of a quick-add parse
+const (
+	QuickParseRules = "rules"
+	QuickParseAI    = "ai"
+)
+
+// QuickTask is a task read from one line of text such as
+// "Zahnarzt anrufen morgen 9 Uhr !hoch #gesundheit"
+type QuickTask struct {
+	Title      string     `json:"title"`
+	DueDate    *time.Time `json:"due_date"`
+	HasTime    bool       `json:"has_time"` // false: due at the end of the day
+	Priority   string     `json:"priority"` // "" when no marker was given
+	Tags       []string   `json:"tags"`
+	Recognized []string   `json:"recognized"` // the parts of the text that were read as fields
+	Ambiguous  bool       `json:"ambiguous"`  // the rules may have misread the text
+	Source     string     `json:"source"`     // QuickParseRules or QuickParseAI
+}
+
+// priorityMarkers maps what follows the "!" of a priority marker to a priority;
+// "!!!" and "!!" are high and medium
+var priorityMarkers = map[string]string{
+	"hoch": "high", "high": "high", "h": "high", "1": "high", "!!": "high",
+	"mittel": "medium", "medium": "medium", "m": "medium", "2": "medium", "!": "medium",
+	"niedrig": "low", "low": "low", "n": "low", "l": "low", "3": "low",
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"montag": time.Monday, "monday": time.Monday,
+	"dienstag": time.Tuesday, "tuesday": time.Tuesday,
+	"mittwoch": time.Wednesday, "wednesday": time.Wednesday,
+	"donnerstag": time.Thursday, "thursday": time.Thursday,
+	"freitag": time.Friday, "friday": time.Friday,
+	"samstag": time.Saturday, "sonnabend": time.Saturday, "saturday": time.Saturday,
+	"sonntag": time.Sunday, "sunday": time.Sunday,
+}
+
+// dayNamePatterns are dates named relative to today
+var dayNamePatterns = []datePattern{
+	{regexp.MustCompile(`(?i)(?:^|\s)(?:übermorgen|uebermorgen)\b|\bday after tomorrow\b`), func(m []string, ref time.Time) (time.Time, bool) {
+		return ref.AddDate(0, 0, 2), true
+	}},
+	{regexp.MustCompile(`(?i)\b(?:heute|today)\b`), func(m []string, ref time.Time) (time.Time, bool) {
+		return ref, true
+	}},
+	{regexp.MustCompile(`(?i)\b(?:morgen|tomorrow)\b`), func(m []string, ref time.Time) (time.Time, bool) {
+		return ref.AddDate(0, 0, 1), true
+	}},
+	// am Freitag, next Monday: the next such day after today
+	{regexp.MustCompile(`(?i)\b(?:(?:am|on|nächsten|nächster|kommenden|next|this|diesen)\s+)?(montag|dienstag|mittwoch|donnerstag|freitag|samstag|sonnabend|sonntag|monday|tuesday|wednesday|thursday|friday|saturday|sunday)\b`), func(m []string, ref time.Time) (time.Time, bool) {
+		days := (int(weekdayNames[strings.ToLower(m[1])]) - int(ref.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return ref.AddDate(0, 0, days), true
+	}},
+	// nächste Woche: Monday of next week
+	{regexp.MustCompile(`(?i)\b(?:nächste|nächsten|next)\s+(?:woche|week)\b`), func(m []string, ref time.Time) (time.Time, bool) {
+		return ref.AddDate(0, 0, 7-(int(ref.Weekday())+6)%7), true
+	}},
+	// am Wochenende: the coming Saturday, today if it is one
+	{regexp.MustCompile(`(?i)\b(?:(?:am|this|on the)\s+)?(?:wochenende|weekend)\b`), func(m []string, ref time.Time) (time.Time, bool) {
+		return ref.AddDate(0, 0, (int(time.Saturday)-int(ref.Weekday())+7)%7), true
+	}},
+}
+
+var quickDatePatterns = append(dayNamePatterns[:len(dayNamePatterns):len(dayNamePatterns)], calendarDatePatterns...)
+
+// timeOfDayPattern is a time of day and how to read its hour and minute
+type timeOfDayPattern struct {
+	re      *regexp.Regexp
+	resolve func(m []string) (hour, minute int, ok bool)
+	bare    bool // without minutes, a match may be a morning or evening hour or no time at all
+}
+
+var timeWords = map[string]int{
+	"morgens": 8, "früh": 8, "vormittags": 10, "mittags": 12, "nachmittags": 15, "abends": 19,
+	"morning": 8, "noon": 12, "afternoon": 15, "evening": 19, "tonight": 19,
+}
+
+var timeOfDayPatterns = []timeOfDayPattern{
+	// 9 Uhr, um 9:30 Uhr, 9.30 Uhr
+	{regexp.MustCompile(`(?i)(?:\b(?:um|at)\s+)?\b(\d{1,2})(?:[:.](\d{2}))?\s*uhr\b`), clockTime, false},
+	// 9am, 5:30 pm
+	{regexp.MustCompile(`(?i)(?:\b(?:um|at)\s+)?\b(\d{1,2})(?::(\d{2}))?\s*([ap])\.?m\.?(?:\s|$)`), func(m []string) (int, int, bool) {
+		hour, minute, ok := clockTime(m)
+		if !ok || hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		hour %= 12
+		if strings.EqualFold(m[3], "p") {
+			hour += 12
+		}
+		return hour, minute, true
+	}, false},
+	// um 9, at 14:30, 14:30
+	{regexp.MustCompile(`(?i)\b(?:um|at)\s+(\d{1,2})(?::(\d{2}))?\b`), clockTime, true},
+	{regexp.MustCompile(`\b(\d{1,2}):(\d{2})\b`), clockTime, false},
+	// abends, in the evening
+	{regexp.MustCompile(`(?i)(?:^|\s)(?:(?:in the|am)\s+)?(morgens|früh|vormittags|mittags|nachmittags|abends|morning|noon|afternoon|evening|tonight)\b`), func(m []string) (int, int, bool) {
+		return timeWords[strings.ToLower(m[1])], 0, true
+	}, false},
+}
+
+func clockTime(m []string) (int, int, bool) {
+	hour, minute := atoi(m[1]), 0
+	if m[2] != "" {
+		minute = atoi(m[2])
+	}
+	return hour, minute, hour < 24 && minute < 60
+}
+
+// temporalHints are words that point at a date or time the rules didn't read
+var temporalHints = []string{
+	"uhr", "tag", "tage", "tagen", "woche", "wochen", "monat", "monate", "monaten", "übernächste", "übernächsten",
+	"nächste", "nächsten", "bald", "später", "irgendwann", "abend", "nachmittag", "vormittag", "feierabend",
+	"day", "days", "week", "weeks", "month", "months", "next", "soon", "later", "someday", "evening", "o'clock",
+}
+
+// danglingWords are dropped from the end of a title once the date after them is read
+var danglingWords = []string{"bis", "am", "um", "ab", "spätestens", "by", "on", "at", "until", "due"}
+
+// ParseQuickTask reads a task from a line of text with rules for German and English dates
+// and times ("morgen 9 Uhr", "next friday", "15.3."), priority markers ("!hoch", "!!!")
+// and tags ("#gesundheit"). now is the current time in the user's time zone.
+func ParseQuickTask(text string, now time.Time) QuickTask {
+	q := QuickTask{Tags: []string{}, Recognized: []string{}, Source: QuickParseRules}
+
+	var words []string
+	for _, word := range strings.Fields(text) {
+		switch {
+		case len(word) > 1 && word[0] == '#':
+			tag := strings.TrimRight(word[1:], ".,;:!?")
+			if tag == "" {
+				words = append(words, word)
+				continue
+			}
+			if !containsFold(q.Tags, tag) {
+				q.Tags = append(q.Tags, tag)
+			}
+			q.Recognized = append(q.Recognized, word)
+		case len(word) > 1 && word[0] == '!' && priorityMarkers[strings.ToLower(word[1:])] != "":
+			priority := priorityMarkers[strings.ToLower(word[1:])]
+			if q.Priority != "" && q.Priority != priority {
+				q.Ambiguous = true
+			}
+			q.Priority = priority
+			q.Recognized = append(q.Recognized, word)
+		default:
+			words = append(words, word)
+		}
+	}
+	rest := strings.Join(words, " ")
+
+	hour, minute, hasTime := 0, 0, false
+	for _, p := range timeOfDayPatterns {
+		loc := p.re.FindStringSubmatchIndex(rest)
+		if loc == nil {
+			continue
+		}
+		sub := submatches(rest, loc)
+		if h, m, ok := p.resolve(sub); ok {
+			hour, minute, hasTime = h, m, true
+			// "at 5" may be 5 am, 5 pm or a number that isn't a time
+			if p.bare && sub[2] == "" {
+				q.Ambiguous = true
+			}
+			q.Recognized = append(q.Recognized, strings.TrimSpace(rest[loc[0]:loc[1]]))
+			rest = rest[:loc[0]] + " " + rest[loc[1]:]
+			break
+		}
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	day, match, hasDate := findDate(rest, today, quickDatePatterns)
+	if hasDate {
+		q.Recognized = append(q.Recognized, strings.TrimSpace(match))
+		rest = strings.Replace(rest, match, " ", 1)
+		if _, _, another := findDate(rest, today, quickDatePatterns); another {
+			q.Ambiguous = true
+		}
+	}
+
+	switch {
+	case hasTime:
+		if !hasDate {
+			// A time alone means its next occurrence
+			day = today
+			if time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location()).Before(now) {
+				day = day.AddDate(0, 0, 1)
+			}
+		}
+		due := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location())
+		q.DueDate, q.HasTime = &due, true
+	case hasDate:
+		due := DueAt(day)
+		q.DueDate = &due
+	}
+
+	titleWords := strings.Fields(rest)
+	// "Steuer bis 15.3." leaves a dangling "bis"
+	for len(titleWords) > 1 && containsFold(danglingWords, titleWords[len(titleWords)-1]) {
+		titleWords = titleWords[:len(titleWords)-1]
+	}
+	q.Title = strings.TrimSpace(strings.TrimRight(strings.Join(titleWords, " "), ",;:-"))
+	for _, word := range strings.Fields(strings.ToLower(q.Title)) {
+		if containsFold(temporalHints, strings.Trim(word, ".,;:!?")) {
+			q.Ambiguous = true
+			break
+		}
+	}
+	if q.Title == "" {
+		q.Ambiguous = true
+	}
+	return q
+}
+
+// ParseQuickTask asks the model to read a task from text the rules found ambiguous.
+// Tags written with "#" are kept even if the model drops them.
+func (s *OpenAIService) ParseQuickTask(text string, now time.Time) (QuickTask, error) {
+	prompt := fmt.Sprintf(`Lies aus folgender Notiz eine Aufgabe heraus.
+Jetzt ist %s, %s (Zeitzone %s).
+
+Notiz: %s
+
+Antworte NUR mit einem JSON-Objekt in diesem Format:
+{"title": "Titel ohne Datum, Priorität und Tags", "date": "YYYY-MM-DD oder null", "time": "HH:MM oder null", "priority": "high, medium, low oder null", "tags": ["tag"]}`,
+		now.Weekday(), now.Format("2006-01-02 15:04"), now.Location(), text)
+
+	response, err := s.GenerateResponseWithMaxTokens(prompt, "Du extrahierst Aufgaben aus kurzen Notizen und antwortest ausschließlich mit JSON.", 200)
+	if err != nil {
+		return QuickTask{}, err
+	}
+	start, end := strings.Index(response, "{"), strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return QuickTask{}, fmt.Errorf("no JSON object in response")
+	}
+	var parsed struct {
+		Title    string   `json:"title"`
+		Date     *string  `json:"date"`
+		Time     *string  `json:"time"`
+		Priority *string  `json:"priority"`
+		Tags     []string `json:"tags"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &parsed); err != nil {
+		return QuickTask{}, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	q := ParseQuickTask(text, now)
+	q.Source, q.Ambiguous = QuickParseAI, false
+	if title := strings.TrimSpace(parsed.Title); title != "" {
+		q.Title = title
+	}
+	if parsed.Priority != nil {
+		if priority := strings.ToLower(*parsed.Priority); priority == "high" || priority == "medium" || priority == "low" {
+			q.Priority = priority
+		}
+	}
+	for _, tag := range parsed.Tags {
+		if tag = strings.TrimPrefix(strings.TrimSpace(tag), "#"); tag != "" && !containsFold(q.Tags, tag) {
+			q.Tags = append(q.Tags, tag)
+		}
+	}
+	q.DueDate, q.HasTime = nil, false
+	if parsed.Date != nil {
+		day, err := time.ParseInLocation(DateLayout, *parsed.Date, now.Location())
+		if err != nil {
+			return QuickTask{}, fmt.Errorf("invalid date %q", *parsed.Date)
+		}
+		due := DueAt(day)
+		if parsed.Time != nil {
+			if clock, err := time.Parse("15:04", *parsed.Time); err == nil {
+				due = time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, day.Location())
+				q.HasTime = true
+			}
+		}
+		q.DueDate = &due
+	}
+	return q, nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuickTask(t *testing.T) {
+	// A Wednesday morning
+	now := time.Date(2026, 3, 11, 10, 0, 0, 0, time.UTC)
+	at := func(day, hour, minute int) *time.Time {
+		due := time.Date(2026, 3, day, hour, minute, 0, 0, time.UTC)
+		return &due
+	}
+	tests := []struct {
+		text      string
+		title     string
+		due       *time.Time
+		hasTime   bool
+		priority  string
+		tags      []string
+		ambiguous bool
+	}{
+		{text: "Zahnarzt anrufen morgen 9 Uhr !hoch #gesundheit", title: "Zahnarzt anrufen", due: at(12, 9, 0), hasTime: true, priority: "high", tags: []string{"gesundheit"}},
+		{text: "Call mom at 5pm", title: "Call mom", due: at(11, 17, 0), hasTime: true},
+		{text: "Meeting um 14:30", title: "Meeting", due: at(11, 14, 30), hasTime: true},
+		{text: "Standup 9:15", title: "Standup", due: at(12, 9, 15), hasTime: true},
+		{text: "Steuer bis 15.3.", title: "Steuer", due: at(15, 23, 59)},
+		{text: "Report next friday !!!", title: "Report", due: at(13, 23, 59), priority: "high"},
+		{text: "Einkaufen abends", title: "Einkaufen", due: at(11, 19, 0), hasTime: true},
+		{text: "Lesen", title: "Lesen"},
+		// A bare hour may be morning or evening, or no time at all
+		{text: "Buy 2 apples at 5", title: "Buy 2 apples", due: at(12, 5, 0), hasTime: true, ambiguous: true},
+		{text: "Treffen um 9", title: "Treffen", due: at(12, 9, 0), hasTime: true, ambiguous: true},
+		{text: "Bald Fenster putzen", title: "Bald Fenster putzen", ambiguous: true},
+		{text: "Sport !hoch !niedrig", title: "Sport", priority: "low", ambiguous: true},
+		{text: "Arzt morgen oder Freitag", title: "Arzt oder Freitag", due: at(12, 23, 59), ambiguous: true},
+		{text: "#einkauf", title: "", tags: []string{"einkauf"}, ambiguous: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			q := ParseQuickTask(tt.text, now)
+			if q.Title != tt.title {
+				t.Errorf("title = %q, want %q", q.Title, tt.title)
+			}
+			if (q.DueDate == nil) != (tt.due == nil) || q.DueDate != nil && !q.DueDate.Equal(*tt.due) {
+				t.Errorf("due date = %v, want %v", q.DueDate, tt.due)
+			}
+			if q.HasTime != tt.hasTime {
+				t.Errorf("has time = %v, want %v", q.HasTime, tt.hasTime)
+			}
+			if q.Priority != tt.priority {
+				t.Errorf("priority = %q, want %q", q.Priority, tt.priority)
+			}
+			tags := tt.tags
+			if tags == nil {
+				tags = []string{}
+			}
+			if !reflect.DeepEqual(q.Tags, tags) {
+				t.Errorf("tags = %v, want %v", q.Tags, tags)
+			}
+			if q.Ambiguous != tt.ambiguous {
+				t.Errorf("ambiguous = %v, want %v", q.Ambiguous, tt.ambiguous)
+			}
+			if q.Source != QuickParseRules {
+				t.Errorf("source = %q, want %q", q.Source, QuickParseRules)
+			}
+		})
+	}
+}
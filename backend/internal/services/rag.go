@@ -3,6 +3,7 @@ package services
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 	"habit-tracker-backend/internal/database"
@@ -14,6 +15,7 @@ type UserContext struct {
 	Tasks         []TaskInfo
 	JournalEntries []JournalInfo
 	Stats         UserStats
+	Mood          *MoodInsights // nil when not given
 }
 
 type HabitInfo struct {
@@ -22,6 +24,9 @@ type HabitInfo struct {
 	Description     string
 	IsCompletedToday bool
 	StreakCount     int
+	Unit            string
+	TargetValue     *float64 // nil for yes/no habits
+	TodayValue      float64
 }
 
 type TaskInfo struct {
@@ -30,13 +35,17 @@ type TaskInfo struct {
 	Priority    string
 	DueDate     *time.Time
 	IsCompleted bool
+	Project     string // "" when the task is in no project
+	Labels      string // label names, comma separated
+	Plan        string // title of the note the task was promoted from
 }
 
 type JournalInfo struct {
 	Date    time.Time
 	Mood    string
 	Content string
-	Tags    string
+	Fields  string // template answers, one "Label: answer" per line
+	Tags    string // comma-separated tag names
 }
 
 type UserStats struct {
@@ -48,12 +57,14 @@ type UserStats struct {
 	BestStreak        int
 }
 
-// BuildUserContext retrieves all user data for RAG
-func BuildUserContext(userID int) (string, error) {
-	context := UserContext{}
+// BuildUserContext retrieves all user data for RAG. Mood insights, when given, are
+// added as they are.
+func BuildUserContext(userID int, mood *MoodInsights) (string, error) {
+	context := UserContext{Mood: mood}
+	today := Today(UserLocation(userID))
 	
 	// Get habits
-	habits, err := getUserHabits(userID)
+	habits, err := getUserHabits(userID, today)
 	if err != nil {
 		return "", fmt.Errorf("failed to get habits: %v", err)
 	}
@@ -67,14 +78,14 @@ func BuildUserContext(userID int) (string, error) {
 	context.Tasks = tasks
 	
 	// Get recent journal entries (last 30 days for better context)
-	journalEntries, err := getUserJournalEntries(userID, 30)
+	journalEntries, err := getUserJournalEntries(userID, 30, today)
 	if err != nil {
 		return "", fmt.Errorf("failed to get journal entries: %v", err)
 	}
 	context.JournalEntries = journalEntries
 	
 	// Get statistics
-	stats, err := getUserStats(userID)
+	stats, err := getUserStats(userID, today)
 	if err != nil {
 		return "", fmt.Errorf("failed to get stats: %v", err)
 	}
@@ -84,17 +95,20 @@ func BuildUserContext(userID int) (string, error) {
 	return formatContext(context), nil
 }
 
-func getUserHabits(userID int) ([]HabitInfo, error) {
+func getUserHabits(userID int, today time.Time) ([]HabitInfo, error) {
 	rows, err := database.DB.Query(`
 		SELECT h.name, h.category, h.description,
 		       CASE WHEN hc.id IS NOT NULL THEN true ELSE false END as completed_today,
-		       COALESCE(MAX(hc.streak_count), 0) as streak_count
+		       COALESCE(MAX(hc.streak_count), 0) as streak_count,
+		       COALESCE(h.unit, ''), h.target_value,
+		       (SELECT COALESCE(SUM(hl.value), 0) FROM habit_logs hl
+		        WHERE hl.habit_id = h.id AND hl.log_date = ?) as today_value
 		FROM habits h
-		LEFT JOIN habit_completions hc ON h.id = hc.habit_id AND DATE(hc.completed_at) = CURDATE()
+		LEFT JOIN habit_completions hc ON h.id = hc.habit_id AND hc.completed_date = ?
 		WHERE h.user_id = ? AND h.is_active = true
 		GROUP BY h.id, h.name, h.category, h.description, hc.id
 		ORDER BY h.category, h.name
-	`, userID)
+	`, today.Format(DateLayout), today.Format(DateLayout), userID)
 	if err != nil {
 		return nil, err
 	}
@@ -104,7 +118,8 @@ func getUserHabits(userID int) ([]HabitInfo, error) {
 	for rows.Next() {
 		var habit HabitInfo
 		var streakCount int
-		err := rows.Scan(&habit.Name, &habit.Category, &habit.Description, &habit.IsCompletedToday, &streakCount)
+		err := rows.Scan(&habit.Name, &habit.Category, &habit.Description, &habit.IsCompletedToday, &streakCount,
+			&habit.Unit, &habit.TargetValue, &habit.TodayValue)
 		if err != nil {
 			continue
 		}
@@ -117,18 +132,25 @@ func getUserHabits(userID int) ([]HabitInfo, error) {
 
 func getUserTasks(userID int) ([]TaskInfo, error) {
 	rows, err := database.DB.Query(`
-		SELECT title, description, priority, due_date, 
-		       CASE WHEN completed_at IS NOT NULL THEN true ELSE false END as is_completed
-		FROM tasks 
-		WHERE user_id = ?
-		ORDER BY 
-			CASE WHEN completed_at IS NULL THEN 0 ELSE 1 END,
-			CASE priority 
-				WHEN 'high' THEN 1 
-				WHEN 'medium' THEN 2 
-				WHEN 'low' THEN 3 
+		SELECT t.title, t.description, t.priority, t.due_date,
+		       CASE WHEN t.completed_at IS NOT NULL THEN true ELSE false END as is_completed,
+		       COALESCE(p.name, ''),
+		       COALESCE((SELECT GROUP_CONCAT(l.name ORDER BY l.name SEPARATOR ', ')
+		                 FROM task_labels tl INNER JOIN labels l ON l.id = tl.label_id
+		                 WHERE tl.task_id = t.id), ''),
+		       COALESCE(n.title, '')
+		FROM tasks t
+		LEFT JOIN projects p ON p.id = t.project_id
+		LEFT JOIN notes n ON n.id = t.note_id
+		WHERE t.user_id = ? AND t.is_recurring_template = FALSE AND ` + NextOpenInstance("t") + `
+		ORDER BY
+			CASE WHEN t.completed_at IS NULL THEN 0 ELSE 1 END,
+			CASE t.priority
+				WHEN 'high' THEN 1
+				WHEN 'medium' THEN 2
+				WHEN 'low' THEN 3
 			END,
-			due_date ASC
+			t.due_date ASC
 		LIMIT 20
 	`, userID)
 	if err != nil {
@@ -140,7 +162,7 @@ func getUserTasks(userID int) ([]TaskInfo, error) {
 	for rows.Next() {
 		var task TaskInfo
 		var dueDate sql.NullTime
-		err := rows.Scan(&task.Title, &task.Description, &task.Priority, &dueDate, &task.IsCompleted)
+		err := rows.Scan(&task.Title, &task.Description, &task.Priority, &dueDate, &task.IsCompleted, &task.Project, &task.Labels, &task.Plan)
 		if err != nil {
 			continue
 		}
@@ -153,15 +175,19 @@ func getUserTasks(userID int) ([]TaskInfo, error) {
 	return tasks, nil
 }
 
-func getUserJournalEntries(userID int, days int) ([]JournalInfo, error) {
+func getUserJournalEntries(userID int, days int, today time.Time) ([]JournalInfo, error) {
 	rows, err := database.DB.Query(`
-		SELECT entry_date, mood, content, tags
+		SELECT entry_date, mood, content, fields_text,
+		       (SELECT GROUP_CONCAT(t.name ORDER BY et.position SEPARATOR ', ')
+		        FROM journal_entry_tags et
+		        INNER JOIN journal_tags t ON t.id = et.tag_id
+		        WHERE et.entry_id = journal_entries.id) AS tags
 		FROM journal_entries 
 		WHERE user_id = ? 
-		AND entry_date >= DATE_SUB(CURDATE(), INTERVAL ? DAY)
+		AND entry_date >= ?
 		ORDER BY entry_date DESC
 		LIMIT 50
-	`, userID, days)
+	`, userID, today.AddDate(0, 0, -days).Format(DateLayout))
 	if err != nil {
 		return nil, err
 	}
@@ -172,8 +198,9 @@ func getUserJournalEntries(userID int, days int) ([]JournalInfo, error) {
 		var entry JournalInfo
 		var tags sql.NullString
 		var content sql.NullString
+		var fields sql.NullString
 		var mood sql.NullString
-		err := rows.Scan(&entry.Date, &mood, &content, &tags)
+		err := rows.Scan(&entry.Date, &mood, &content, &fields, &tags)
 		if err != nil {
 			continue
 		}
@@ -183,6 +210,7 @@ func getUserJournalEntries(userID int, days int) ([]JournalInfo, error) {
 		if content.Valid {
 			entry.Content = content.String
 		}
+		entry.Fields = fields.String
 		if tags.Valid {
 			entry.Tags = tags.String
 		}
@@ -192,7 +220,7 @@ func getUserJournalEntries(userID int, days int) ([]JournalInfo, error) {
 	return entries, nil
 }
 
-func getUserStats(userID int) (UserStats, error) {
+func getUserStats(userID int, today time.Time) (UserStats, error) {
 	stats := UserStats{}
 	
 	// Total habits
@@ -208,15 +236,15 @@ func getUserStats(userID int) (UserStats, error) {
 		SELECT COUNT(DISTINCT hc.habit_id)
 		FROM habit_completions hc
 		INNER JOIN habits h ON hc.habit_id = h.id
-		WHERE hc.user_id = ? AND h.user_id = ? AND DATE(hc.completed_at) = CURDATE()
-	`, userID, userID).Scan(&stats.CompletedHabitsToday)
+		WHERE hc.user_id = ? AND h.user_id = ? AND hc.completed_date = ?
+	`, userID, userID, today.Format(DateLayout)).Scan(&stats.CompletedHabitsToday)
 	if err != nil && err != sql.ErrNoRows {
 		return stats, err
 	}
 	
 	// Total tasks
 	err = database.DB.QueryRow(`
-		SELECT COUNT(*) FROM tasks WHERE user_id = ?
+		SELECT COUNT(*) FROM tasks WHERE user_id = ? AND is_recurring_template = FALSE AND ` + NextOpenInstance("tasks") + `
 	`, userID).Scan(&stats.TotalTasks)
 	if err != nil {
 		return stats, err
@@ -230,40 +258,17 @@ func getUserStats(userID int) (UserStats, error) {
 		return stats, err
 	}
 	
-	// Calculate streaks (simplified - using overall streak)
-	rows, err := database.DB.Query(`
-		SELECT DATE(completed_at) as completion_date, COUNT(DISTINCT habit_id) as habit_count
-		FROM habit_completions hc
-		INNER JOIN habits h ON hc.habit_id = h.id
-		WHERE hc.user_id = ? AND h.user_id = ? AND h.is_active = true
-		GROUP BY DATE(completed_at)
-		HAVING habit_count >= (SELECT COUNT(*) FROM habits WHERE user_id = ? AND is_active = true)
-		ORDER BY completion_date DESC
-	`, userID, userID, userID)
-	if err == nil {
-		defer rows.Close()
-		var dates []time.Time
-		for rows.Next() {
-			var date time.Time
-			var count int
-			if err := rows.Scan(&date, &count); err == nil {
-				dates = append(dates, date.Truncate(24 * time.Hour))
-			}
-		}
-		
-		if len(dates) > 0 {
-			today := time.Now().Truncate(24 * time.Hour)
-			expectedDate := today
-			for i := 0; i < len(dates); i++ {
-				if dates[i].Equal(expectedDate) {
-					stats.CurrentStreak++
-					expectedDate = expectedDate.AddDate(0, 0, -1)
-				} else if dates[i].Before(expectedDate) {
-					break
-				}
-			}
-		}
+	// Streaks across all habits, counted the way the habit views do: only days on which
+	// habits were scheduled and active, with rest and frozen days not breaking them
+	excused, err := LoadExcusedDays(userID)
+	if err != nil {
+		return stats, err
 	}
+	histories, err := LoadHabitHistories(userID, nil, today.Location(), excused)
+	if err != nil {
+		return stats, err
+	}
+	stats.CurrentStreak, stats.BestStreak = Streaks(DailyResults(histories, today), today)
 	
 	return stats, nil
 }
@@ -278,6 +283,7 @@ func formatContext(context UserContext) string {
 	sb.WriteString(fmt.Sprintf("- Aktive Gewohnheiten: %d\n", context.Stats.TotalHabits))
 	sb.WriteString(fmt.Sprintf("- Heute abgeschlossene Gewohnheiten: %d\n", context.Stats.CompletedHabitsToday))
 	sb.WriteString(fmt.Sprintf("- Aktuelle Serie: %d Tage\n", context.Stats.CurrentStreak))
+	sb.WriteString(fmt.Sprintf("- Beste Serie: %d Tage\n", context.Stats.BestStreak))
 	sb.WriteString(fmt.Sprintf("- Gesamt Aufgaben: %d\n", context.Stats.TotalTasks))
 	sb.WriteString(fmt.Sprintf("- Abgeschlossene Aufgaben: %d\n\n", context.Stats.CompletedTasks))
 	
@@ -289,6 +295,13 @@ func formatContext(context UserContext) string {
 			if habit.IsCompletedToday {
 				status = "erledigt"
 			}
+			if habit.TargetValue != nil {
+				// Measurable habits show how far today's target is reached
+				if !habit.IsCompletedToday && habit.TodayValue > 0 {
+					status = "teilweise erledigt"
+				}
+				status = fmt.Sprintf("%s/%s %s, %s", formatAmount(habit.TodayValue), formatAmount(*habit.TargetValue), habit.Unit, status)
+			}
 			sb.WriteString(fmt.Sprintf("- %s (%s): %s [Heute: %s, Serie: %d Tage]\n", 
 				habit.Name, habit.Category, habit.Description, status, habit.StreakCount))
 		}
@@ -310,15 +323,29 @@ func formatContext(context UserContext) string {
 		
 		if len(openTasks) > 0 {
 			sb.WriteString("Offene Aufgaben:\n")
-			for _, task := range openTasks {
-				dueStr := "kein Fälligkeitsdatum"
-				if task.DueDate != nil {
-					dueStr = task.DueDate.Format("02.01.2006")
+			projects, byProject := groupTasksByProject(openTasks)
+			for _, project := range projects {
+				if project == "" {
+					sb.WriteString("Ohne Projekt:\n")
+				} else {
+					sb.WriteString(fmt.Sprintf("Projekt: %s\n", project))
 				}
-				sb.WriteString(fmt.Sprintf("- %s [Priorität: %s, Fällig: %s]\n", 
-					task.Title, task.Priority, dueStr))
-				if task.Description != "" {
-					sb.WriteString(fmt.Sprintf("  Beschreibung: %s\n", task.Description))
+				for _, task := range byProject[project] {
+					dueStr := "kein Fälligkeitsdatum"
+					if task.DueDate != nil {
+						dueStr = task.DueDate.Format("02.01.2006")
+					}
+					sb.WriteString(fmt.Sprintf("- %s [Priorität: %s, Fällig: %s]\n",
+						task.Title, task.Priority, dueStr))
+					if task.Labels != "" {
+						sb.WriteString(fmt.Sprintf("  Labels: %s\n", task.Labels))
+					}
+					if task.Plan != "" {
+						sb.WriteString(fmt.Sprintf("  Aus Plan: %s\n", task.Plan))
+					}
+					if task.Description != "" {
+						sb.WriteString(fmt.Sprintf("  Beschreibung: %s\n", task.Description))
+					}
 				}
 			}
 		}
@@ -362,25 +389,51 @@ func formatContext(context UserContext) string {
 					}
 				}
 			}
-			if entry.Tags != "" && entry.Tags != "[]" {
-				// Parse JSON tags if needed
-				tagsStr := entry.Tags
-				if strings.HasPrefix(tagsStr, "[") {
-					// It's JSON, try to extract tags
-					tagsStr = strings.Trim(tagsStr, "[]\"")
-					tagsStr = strings.ReplaceAll(tagsStr, "\"", "")
-				}
-				if tagsStr != "" && tagsStr != "[]" {
-					sb.WriteString(fmt.Sprintf("Tags: %s\n", tagsStr))
-				}
+			if entry.Fields != "" {
+				sb.WriteString(fmt.Sprintf("%s\n", entry.Fields))
+			}
+			if entry.Tags != "" {
+				sb.WriteString(fmt.Sprintf("Tags: %s\n", entry.Tags))
 			}
 			sb.WriteString("\n")
 		}
 		sb.WriteString("\n")
 	}
 	
+	// Mood insights
+	if context.Mood != nil && context.Mood.Average != nil {
+		sb.WriteString(fmt.Sprintf("## Stimmung (%s bis %s)\n", context.Mood.From, context.Mood.To))
+		sb.WriteString(fmt.Sprintf("- Durchschnitt: %s von 5 an %d Tagen\n", formatMoodScore(*context.Mood.Average), len(context.Mood.Days)))
+		for _, habit := range context.Mood.Habits {
+			sb.WriteString(fmt.Sprintf("- %s\n", habit.Summary))
+		}
+		sb.WriteString("\n")
+	}
+	
 	sb.WriteString("=== ENDE KONTEXT ===\n")
 	
 	return sb.String()
 }
 
+
+// formatAmount prints a logged habit value without needless decimals
+func formatAmount(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// groupTasksByProject groups tasks by project name, keeping their order. Projects are
+// listed in the order they first appear, tasks without a project ("") come last.
+func groupTasksByProject(tasks []TaskInfo) ([]string, map[string][]TaskInfo) {
+	var projects []string
+	byProject := make(map[string][]TaskInfo)
+	for _, task := range tasks {
+		if _, ok := byProject[task.Project]; !ok && task.Project != "" {
+			projects = append(projects, task.Project)
+		}
+		byProject[task.Project] = append(byProject[task.Project], task)
+	}
+	if len(byProject[""]) > 0 {
+		projects = append(projects, "")
+	}
+	return projects, byProject
+}
@@ -0,0 +1,500 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"habit-tracker-backend/internal/database"
+)
+
+// Recurrence frequencies of task rules
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// defaultRecurrenceHorizonDays is how many days ahead instances of recurring tasks exist
+const defaultRecurrenceHorizonDays = 60
+
+// NextOpenInstance returns a condition on tasks selected as alias that leaves out the open
+// instances of a recurring task except the earliest, so lists show a recurring task once
+// instead of every instance up to the horizon
+func NextOpenInstance(alias string) string {
+	return fmt.Sprintf(`(%[1]s.parent_task_id IS NULL OR %[1]s.completed_at IS NOT NULL OR NOT EXISTS (
+		SELECT 1 FROM tasks earlier
+		WHERE earlier.parent_task_id = %[1]s.parent_task_id AND earlier.completed_at IS NULL
+		  AND earlier.occurrence_date < %[1]s.occurrence_date))`, alias)
+}
+
+// RuleWeekday is a BYDAY entry of a rule. N selects the Nth such weekday of the month,
+// counted from the end when negative; 0 means every one.
+type RuleWeekday struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// RecurrenceRule is the subset of an RFC 5545 RRULE that tasks support:
+// FREQ, INTERVAL, BYDAY, BYMONTHDAY, COUNT and UNTIL. Weeks start on Monday.
+type RecurrenceRule struct {
+	Freq       string
+	Interval   int
+	ByDay      []RuleWeekday
+	ByMonthDay []int      // negative days count from the end of the month
+	Count      int        // total number of occurrences, 0 for no limit
+	Until      *time.Time // last day an occurrence may fall on, nil for no end
+}
+
+var ruleWeekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// ParseRecurrenceRule parses a rule such as "FREQ=WEEKLY;BYDAY=MO,TH" or
+// "RRULE:FREQ=MONTHLY;BYDAY=2TU;COUNT=6"
+func ParseRecurrenceRule(raw string) (RecurrenceRule, error) {
+	rule := RecurrenceRule{Interval: 1}
+	raw = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(raw)), "RRULE:")
+	for _, part := range strings.Split(raw, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return rule, fmt.Errorf("invalid rule part %q", part)
+		}
+		switch key {
+		case "FREQ":
+			switch value {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				rule.Freq = value
+			default:
+				return rule, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 999 {
+				return rule, fmt.Errorf("INTERVAL must be between 1 and 999")
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, entry := range strings.Split(value, ",") {
+				day, err := parseRuleWeekday(entry)
+				if err != nil {
+					return rule, err
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, entry := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(entry)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return rule, fmt.Errorf("invalid BYMONTHDAY %q", entry)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 1000 {
+				return rule, fmt.Errorf("COUNT must be between 1 and 1000")
+			}
+			rule.Count = n
+		case "UNTIL":
+			// Only the day matters, a time such as T235959Z is ignored
+			if len(value) < 8 {
+				return rule, fmt.Errorf("invalid UNTIL %q", value)
+			}
+			until, err := time.Parse("20060102", value[:8])
+			if err != nil {
+				return rule, fmt.Errorf("invalid UNTIL %q", value)
+			}
+			rule.Until = &until
+		case "WKST":
+			if value != "MO" {
+				return rule, fmt.Errorf("only WKST=MO is supported")
+			}
+		default:
+			return rule, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return rule, fmt.Errorf("FREQ is required")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return rule, fmt.Errorf("COUNT and UNTIL can't be combined")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != FreqMonthly {
+			return rule, fmt.Errorf("numbered BYDAY entries need FREQ=MONTHLY")
+		}
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != FreqMonthly {
+		return rule, fmt.Errorf("BYMONTHDAY needs FREQ=MONTHLY")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq == FreqYearly {
+		return rule, fmt.Errorf("BYDAY is not supported with FREQ=YEARLY")
+	}
+	return rule, nil
+}
+
+// parseRuleWeekday parses a BYDAY entry such as "MO", "2TU" or "-1FR"
+func parseRuleWeekday(entry string) (RuleWeekday, error) {
+	entry = strings.TrimSpace(entry)
+	if len(entry) < 2 {
+		return RuleWeekday{}, fmt.Errorf("invalid BYDAY %q", entry)
+	}
+	code := entry[len(entry)-2:]
+	for i, name := range ruleWeekdays {
+		if name != code {
+			continue
+		}
+		day := RuleWeekday{Weekday: time.Weekday(i)}
+		if prefix := entry[:len(entry)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return RuleWeekday{}, fmt.Errorf("invalid BYDAY %q", entry)
+			}
+			day.N = n
+		}
+		return day, nil
+	}
+	return RuleWeekday{}, fmt.Errorf("invalid BYDAY %q", entry)
+}
+
+// String formats the rule as an RRULE value
+func (r RecurrenceRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = ruleWeekdays[day.Weekday]
+			if day.N != 0 {
+				days[i] = strconv.Itoa(day.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences returns the occurrences of a series starting at start whose day lies
+// between from and to (inclusive). They keep the time of day of start, in its location.
+func (r RecurrenceRule) Occurrences(start, from, to time.Time) []time.Time {
+	loc := start.Location()
+	first := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	fromStr, toStr := from.Format(DateLayout), to.Format(DateLayout)
+	if r.Until != nil && r.Until.Format(DateLayout) < toStr {
+		toStr = r.Until.Format(DateLayout)
+	}
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	var occurrences []time.Time
+	count := 0
+	for period := 0; ; period++ {
+		periodStart, days := r.periodDays(first, period*interval)
+		if periodStart.Format(DateLayout) > toStr {
+			return occurrences
+		}
+		for _, day := range days {
+			if day.Before(first) {
+				continue
+			}
+			dayStr := day.Format(DateLayout)
+			if dayStr > toStr {
+				return occurrences
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return occurrences
+			}
+			if dayStr >= fromStr {
+				occurrences = append(occurrences, time.Date(day.Year(), day.Month(), day.Day(),
+					start.Hour(), start.Minute(), start.Second(), 0, loc))
+			}
+		}
+	}
+}
+
+// periodDays returns the first day of the period offset periods of the rule's frequency
+// after the one containing first, and the days in it that match the rule, in order
+func (r RecurrenceRule) periodDays(first time.Time, offset int) (time.Time, []time.Time) {
+	loc := first.Location()
+	switch r.Freq {
+	case FreqWeekly:
+		weekStart := first.AddDate(0, 0, -(isoWeekday(first)-1)+offset*7)
+		if len(r.ByDay) == 0 {
+			return weekStart, []time.Time{weekStart.AddDate(0, 0, isoWeekday(first)-1)}
+		}
+		var days []time.Time
+		for i := 0; i < 7; i++ {
+			if day := weekStart.AddDate(0, 0, i); r.hasWeekday(day) {
+				days = append(days, day)
+			}
+		}
+		return weekStart, days
+	case FreqMonthly:
+		monthStart := time.Date(first.Year(), first.Month()+time.Month(offset), 1, 0, 0, 0, 0, loc)
+		return monthStart, r.monthDays(monthStart, first.Day())
+	case FreqYearly:
+		yearStart := time.Date(first.Year()+offset, 1, 1, 0, 0, 0, 0, loc)
+		day := time.Date(first.Year()+offset, first.Month(), first.Day(), 0, 0, 0, 0, loc)
+		if day.Day() != first.Day() {
+			// February 29th in a year without it
+			return yearStart, nil
+		}
+		return yearStart, []time.Time{day}
+	default:
+		day := first.AddDate(0, 0, offset)
+		if len(r.ByDay) > 0 && !r.hasWeekday(day) {
+			return day, nil
+		}
+		return day, []time.Time{day}
+	}
+}
+
+// monthDays returns the days of a month matching BYDAY and BYMONTHDAY, or the day
+// of the month the series started on when neither is set
+func (r RecurrenceRule) monthDays(monthStart time.Time, startDay int) []time.Time {
+	length := monthStart.AddDate(0, 1, -1).Day()
+	matches := make(map[int]bool)
+
+	if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+		if startDay <= length {
+			matches[startDay] = true
+		}
+	}
+	for _, n := range r.ByMonthDay {
+		day := n
+		if n < 0 {
+			day = length + n + 1
+		}
+		if day >= 1 && day <= length {
+			matches[day] = true
+		}
+	}
+	if len(r.ByDay) > 0 {
+		byDay := make(map[int]bool)
+		for _, rd := range r.ByDay {
+			var days []int
+			for day := 1; day <= length; day++ {
+				if monthStart.AddDate(0, 0, day-1).Weekday() == rd.Weekday {
+					days = append(days, day)
+				}
+			}
+			switch {
+			case rd.N == 0:
+				for _, day := range days {
+					byDay[day] = true
+				}
+			case rd.N > 0 && rd.N <= len(days):
+				byDay[days[rd.N-1]] = true
+			case rd.N < 0 && -rd.N <= len(days):
+				byDay[days[len(days)+rd.N]] = true
+			}
+		}
+		if len(r.ByMonthDay) > 0 {
+			// Both set: BYMONTHDAY limits the BYDAY matches
+			for day := range matches {
+				if !byDay[day] {
+					delete(matches, day)
+				}
+			}
+		} else {
+			matches = byDay
+		}
+	}
+
+	var days []int
+	for day := range matches {
+		days = append(days, day)
+	}
+	sort.Ints(days)
+	result := make([]time.Time, len(days))
+	for i, day := range days {
+		result[i] = monthStart.AddDate(0, 0, day-1)
+	}
+	return result
+}
+
+// hasWeekday reports whether BYDAY contains the weekday of day
+func (r RecurrenceRule) hasWeekday(day time.Time) bool {
+	for _, rd := range r.ByDay {
+		if rd.Weekday == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// RecurrenceHorizonDays is how many days ahead instances of recurring tasks are created:
+// TASK_RECURRENCE_HORIZON_DAYS, 60 by default
+func RecurrenceHorizonDays() int {
+	if raw := os.Getenv("TASK_RECURRENCE_HORIZON_DAYS"); raw != "" {
+		if days, err := strconv.Atoi(raw); err == nil && days > 0 && days <= 3660 {
+			return days
+		}
+		log.Printf("Ignoring invalid TASK_RECURRENCE_HORIZON_DAYS %q", raw)
+	}
+	return defaultRecurrenceHorizonDays
+}
+
+// recurringTemplate is a recurring task row its instances are created from
+type recurringTemplate struct {
+	ID             int
+	UserID         int
+	Title          string
+	Description    string
+	Priority       string
+	Start          time.Time // due date of the first occurrence
+	Rule           string
+	GeneratedUntil *time.Time
+	ParentID       *int
+	ProjectID      *int
+}
+
+// MaterializeRecurringTasks creates the instances of recurring tasks up to the horizon,
+// for one user or for all users when userID is 0. Templates remember up to which day
+// instances were created, so instances deleted by the user don't come back. A template
+// that fails, e.g. with a corrupt rule, is logged and skipped so the others still get
+// their instances; it is tried again next time.
+func MaterializeRecurringTasks(userID int) error {
+	query := `
+		SELECT id, user_id, title, description, priority, due_date, recurrence_rule, generated_until, parent_id, project_id
+		FROM tasks
+		WHERE is_recurring_template = TRUE AND recurrence_rule IS NOT NULL AND due_date IS NOT NULL`
+	var args []interface{}
+	if userID != 0 {
+		query += " AND user_id = ?"
+		args = append(args, userID)
+	}
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query recurring tasks: %v", err)
+	}
+	var templates []recurringTemplate
+	for rows.Next() {
+		var t recurringTemplate
+		var description sql.NullString
+		var generatedUntil sql.NullTime
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Title, &description, &t.Priority, &t.Start, &t.Rule, &generatedUntil, &t.ParentID, &t.ProjectID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan recurring task: %v", err)
+		}
+		t.Description = description.String
+		if generatedUntil.Valid {
+			t.GeneratedUntil = &generatedUntil.Time
+		}
+		templates = append(templates, t)
+	}
+	rows.Close()
+
+	horizonDays := RecurrenceHorizonDays()
+	locations := make(map[int]*time.Location)
+	for _, t := range templates {
+		loc, ok := locations[t.UserID]
+		if !ok {
+			loc = UserLocation(t.UserID)
+			locations[t.UserID] = loc
+		}
+		if err := materializeTemplate(t, Today(loc).AddDate(0, 0, horizonDays)); err != nil {
+			log.Printf("Failed to create instances of task %d: %v", t.ID, err)
+		}
+	}
+	return nil
+}
+
+// materializeTemplate creates the instances of a template that fall after the day it was
+// last materialized until horizon
+func materializeTemplate(t recurringTemplate, horizon time.Time) error {
+	rule, err := ParseRecurrenceRule(t.Rule)
+	if err != nil {
+		return err
+	}
+	loc := horizon.Location()
+	start := t.Start.In(loc)
+	from := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	if t.GeneratedUntil != nil {
+		next := time.Date(t.GeneratedUntil.Year(), t.GeneratedUntil.Month(), t.GeneratedUntil.Day()+1, 0, 0, 0, 0, loc)
+		if next.After(from) {
+			from = next
+		}
+	}
+	if from.After(horizon) {
+		return nil
+	}
+
+	occurrences := rule.Occurrences(start, from, horizon)
+	if len(occurrences) > 0 {
+		var position int
+		err := database.DB.QueryRow(`
+			SELECT COALESCE(MAX(position) + 1, 0) FROM tasks WHERE user_id = ? AND parent_id <=> ?
+		`, t.UserID, t.ParentID).Scan(&position)
+		if err != nil {
+			return err
+		}
+		for i, occurrence := range occurrences {
+			// The unique (parent_task_id, occurrence_date) key makes concurrent runs harmless
+			result, err := database.DB.Exec(`
+				INSERT IGNORE INTO tasks (user_id, title, description, priority, due_date, parent_task_id, occurrence_date, parent_id, position, project_id)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, t.UserID, t.Title, t.Description, t.Priority, occurrence, t.ID, occurrence.Format(DateLayout), t.ParentID, position+i, t.ProjectID)
+			if err != nil {
+				return err
+			}
+			if inserted, _ := result.RowsAffected(); inserted == 1 {
+				instanceID, _ := result.LastInsertId()
+				_, err = database.DB.Exec(`
+					INSERT INTO task_labels (task_id, label_id)
+					SELECT ?, label_id FROM task_labels WHERE task_id = ?
+				`, instanceID, t.ID)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE tasks SET generated_until = ?, updated_at = updated_at WHERE id = ?
+	`, horizon.Format(DateLayout), t.ID)
+	return err
+}
+
+// StartRecurringTaskJob keeps the instances of all recurring tasks materialized up to the
+// horizon, checking once at startup and then every interval
+func StartRecurringTaskJob(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := MaterializeRecurringTasks(0); err != nil {
+				log.Printf("Recurring task job failed: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
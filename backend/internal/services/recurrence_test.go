@@ -0,0 +1,144 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRecurrenceRule(t *testing.T) {
+	until := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		raw     string
+		want    RecurrenceRule
+		wantErr bool
+	}{
+		{
+			name: "last friday of the month",
+			raw:  "FREQ=MONTHLY;BYDAY=-1FR",
+			want: RecurrenceRule{Freq: FreqMonthly, Interval: 1, ByDay: []RuleWeekday{{N: -1, Weekday: time.Friday}}},
+		},
+		{
+			name: "interval with weekdays, lower case and prefix",
+			raw:  "rrule:freq=weekly;interval=2;byday=mo,th",
+			want: RecurrenceRule{Freq: FreqWeekly, Interval: 2, ByDay: []RuleWeekday{{Weekday: time.Monday}, {Weekday: time.Thursday}}},
+		},
+		{
+			name: "31st of the month",
+			raw:  "FREQ=MONTHLY;BYMONTHDAY=31",
+			want: RecurrenceRule{Freq: FreqMonthly, Interval: 1, ByMonthDay: []int{31}},
+		},
+		{
+			name: "count",
+			raw:  "FREQ=DAILY;COUNT=5",
+			want: RecurrenceRule{Freq: FreqDaily, Interval: 1, Count: 5},
+		},
+		{
+			name: "until ignores the time",
+			raw:  "FREQ=WEEKLY;UNTIL=20260331T235959Z",
+			want: RecurrenceRule{Freq: FreqWeekly, Interval: 1, Until: &until},
+		},
+		{name: "missing freq", raw: "BYDAY=MO", wantErr: true},
+		{name: "unsupported freq", raw: "FREQ=HOURLY", wantErr: true},
+		{name: "numbered weekday outside monthly", raw: "FREQ=WEEKLY;BYDAY=-1FR", wantErr: true},
+		{name: "sixth weekday", raw: "FREQ=MONTHLY;BYDAY=6MO", wantErr: true},
+		{name: "month day outside monthly", raw: "FREQ=DAILY;BYMONTHDAY=31", wantErr: true},
+		{name: "month day 32", raw: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: true},
+		{name: "count and until", raw: "FREQ=DAILY;COUNT=3;UNTIL=20260101", wantErr: true},
+		{name: "zero interval", raw: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRecurrenceRule(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseRecurrenceRule(%q) = %+v, want an error", tt.raw, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRecurrenceRule(%q) failed: %v", tt.raw, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRecurrenceRule(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+			again, err := ParseRecurrenceRule(got.String())
+			if err != nil || !reflect.DeepEqual(again, got) {
+				t.Errorf("%q doesn't parse back to the same rule: %+v, %v", got.String(), again, err)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		rule     string
+		start    time.Time
+		from, to time.Time
+		want     []time.Time
+	}{
+		{
+			name:  "last friday of the month",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR",
+			start: day(1, 1),
+			from:  day(1, 1), to: day(4, 30),
+			want: []time.Time{day(1, 30), day(2, 27), day(3, 27), day(4, 24)},
+		},
+		{
+			name:  "every other week on monday and thursday",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH",
+			start: day(1, 8),
+			from:  day(1, 1), to: day(2, 8),
+			want: []time.Time{day(1, 8), day(1, 19), day(1, 22), day(2, 2), day(2, 5)},
+		},
+		{
+			name:  "31st skips shorter months",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31",
+			start: day(1, 31),
+			from:  day(1, 1), to: day(6, 30),
+			want: []time.Time{day(1, 31), day(3, 31), day(5, 31)},
+		},
+		{
+			name:  "last day of the month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: day(1, 15),
+			from:  day(1, 1), to: day(3, 31),
+			want: []time.Time{day(1, 31), day(2, 28), day(3, 31)},
+		},
+		{
+			name:  "count is counted from the start, not from",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: day(1, 1),
+			from:  day(1, 2), to: day(1, 10),
+			want: []time.Time{day(1, 2), day(1, 3)},
+		},
+		{
+			name:  "until ends the series",
+			rule:  "FREQ=WEEKLY;UNTIL=20260119",
+			start: day(1, 5),
+			from:  day(1, 1), to: day(2, 28),
+			want: []time.Time{day(1, 5), day(1, 12), day(1, 19)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRecurrenceRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRecurrenceRule(%q) failed: %v", tt.rule, err)
+			}
+			start := tt.start.Add(9*time.Hour + 30*time.Minute)
+			var want []time.Time
+			for _, d := range tt.want {
+				want = append(want, d.Add(9*time.Hour+30*time.Minute))
+			}
+			if got := rule.Occurrences(start, tt.from, tt.to); !reflect.DeepEqual(got, want) {
+				t.Errorf("Occurrences() = %v, want %v", got, want)
+			}
+		})
+	}
+}
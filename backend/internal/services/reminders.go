@@ -0,0 +1,310 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"habit-tracker-backend/internal/database"
+)
+
+// wholeDayReminderHour is when tasks due on a day without a time of day (due at
+// 23:59 as set by DueAt, or at midnight) are reminded of that day
+const wholeDayReminderHour = 9
+
+// ParseTimeOfDay validates a local time of day written as "HH:MM" (or "HH:MM:SS",
+// as MySQL returns TIME values) and returns it as "HH:MM"
+func ParseTimeOfDay(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		if t, err = time.Parse("15:04:05", s); err != nil {
+			return "", fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+		}
+	}
+	return t.Format("15:04"), nil
+}
+
+// atTimeOfDay returns the moment clock ("HH:MM") is reached on day
+func atTimeOfDay(day time.Time, clock string) (time.Time, error) {
+	clock, err := ParseTimeOfDay(clock)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, _ := time.Parse("15:04", clock)
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, day.Location()), nil
+}
+
+// reminderSender is what the reminder jobs send through
+type reminderSender interface {
+	Send(n Notification) error
+}
+
+// SendReminders sends the reminders that are due at now: tasks whose due date has come,
+// habits not yet done at their reminder time and the journal nudge at the user's
+// "journal_reminder_time". Each reminder has a key derived from what it is about, so
+// running this repeatedly, after a restart or on several servers sends it only once.
+// A failure for one user is logged and doesn't keep the others from their reminders.
+func SendReminders(sender reminderSender, now time.Time) error {
+	locations := make(map[int]*time.Location)
+	location := func(userID int) *time.Location {
+		loc, ok := locations[userID]
+		if !ok {
+			loc = UserLocation(userID)
+			locations[userID] = loc
+		}
+		return loc
+	}
+
+	return errors.Join(
+		remindDueTasks(sender, now, location),
+		remindHabits(sender, now, location),
+		nudgeJournals(sender, now, location),
+	)
+}
+
+// remindDueTasks reminds of open tasks at their due time, or in the morning of their due
+// day when they have no time of day. Tasks overdue for more than a day when the job runs,
+// such as on its first run, are not reminded of anymore.
+func remindDueTasks(sender reminderSender, now time.Time, location func(int) *time.Location) error {
+	rows, err := database.DB.Query(`
+		SELECT id, user_id, title, due_date
+		FROM tasks
+		WHERE completed_at IS NULL AND is_recurring_template = FALSE
+		  AND due_date > ? AND due_date <= ?
+	`, now.Add(-24*time.Hour), now.Add(24*time.Hour))
+	if err != nil {
+		return fmt.Errorf("failed to query due tasks: %v", err)
+	}
+	var reminders []Notification
+	for rows.Next() {
+		var id, userID int
+		var title string
+		var due time.Time
+		if err := rows.Scan(&id, &userID, &title, &due); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan due task: %v", err)
+		}
+
+		due = due.In(location(userID))
+		remindAt, body := due, "Fällig um "+due.Format("15:04")
+		if clock := due.Format("15:04"); clock == "23:59" || clock == "00:00" {
+			remindAt = time.Date(due.Year(), due.Month(), due.Day(), wholeDayReminderHour, 0, 0, 0, due.Location())
+			body = "Heute fällig"
+		}
+		if now.Before(remindAt) {
+			continue
+		}
+		reminders = append(reminders, Notification{
+			UserID: userID,
+			Kind:   NotificationTaskDue,
+			Title:  title,
+			Body:   body,
+			URL:    "/todos",
+			// A new due date is a new reminder
+			Key: fmt.Sprintf("task_due:%d:%d", id, due.Unix()),
+		})
+	}
+	rows.Close()
+
+	for _, n := range reminders {
+		if err := sender.Send(n); err != nil {
+			log.Printf("Failed to send reminder %q to user %d: %v", n.Key, n.UserID, err)
+		}
+	}
+	return nil
+}
+
+// habitReminder is an active habit with a reminder time
+type habitReminder struct {
+	ID        int
+	UserID    int
+	Name      string
+	Schedule  string
+	Days      sql.NullString
+	Count     sql.NullInt64
+	Interval  sql.NullInt64
+	Target    sql.NullFloat64
+	Time      string
+	CreatedAt time.Time
+}
+
+// remindHabits reminds of habits that are due today and not yet done once the user's
+// local time passes their reminder time. Nobody is reminded during a rest period.
+func remindHabits(sender reminderSender, now time.Time, location func(int) *time.Location) error {
+	rows, err := database.DB.Query(`
+		SELECT id, user_id, name, schedule_type, schedule_days, schedule_count, schedule_interval, target_value, reminder_time, created_at
+		FROM habits
+		WHERE is_active = TRUE AND reminder_time IS NOT NULL
+		ORDER BY user_id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query habit reminders: %v", err)
+	}
+	byUser := make(map[int][]habitReminder)
+	var users []int
+	for rows.Next() {
+		var h habitReminder
+		if err := rows.Scan(&h.ID, &h.UserID, &h.Name, &h.Schedule, &h.Days, &h.Count, &h.Interval, &h.Target, &h.Time, &h.CreatedAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan habit reminder: %v", err)
+		}
+		if byUser[h.UserID] == nil {
+			users = append(users, h.UserID)
+		}
+		byUser[h.UserID] = append(byUser[h.UserID], h)
+	}
+	rows.Close()
+
+	for _, userID := range users {
+		if err := remindUserHabits(sender, now, location(userID), userID, byUser[userID]); err != nil {
+			log.Printf("Failed to send habit reminders to user %d: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+// remindUserHabits sends one user's habit reminders that are due at now. A habit is done
+// for today once its target is reached: a completion for yes/no habits and a logged
+// total reaching the current target for measurable ones, as a raised target leaves a
+// completion recorded earlier that day in place.
+func remindUserHabits(sender reminderSender, now time.Time, loc *time.Location, userID int, habits []habitReminder) error {
+	today := Today(loc)
+	date := today.Format(DateLayout)
+
+	var resting bool
+	err := database.DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM rest_periods WHERE user_id = ? AND start_date <= ? AND end_date >= ?)
+	`, userID, date, date).Scan(&resting)
+	if err != nil {
+		return fmt.Errorf("failed to check rest periods: %v", err)
+	}
+	if resting {
+		return nil
+	}
+
+	var pending []habitReminder
+	periodsStart := today
+	for _, h := range habits {
+		remindAt, err := atTimeOfDay(today, h.Time)
+		if err != nil || now.Before(remindAt) {
+			continue
+		}
+		schedule := ScheduleFromColumns(h.Schedule, h.Days, h.Count, h.Interval)
+		if start := PeriodStart(schedule, StartOfDay(h.CreatedAt, loc), today); start.Before(periodsStart) {
+			periodsStart = start
+		}
+		pending = append(pending, h)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	completed, err := CompletionsByHabit(userID, periodsStart)
+	if err != nil {
+		return err
+	}
+	logged, err := LoggedTotals(userID, today)
+	if err != nil {
+		return err
+	}
+	for _, h := range pending {
+		done := completed[h.ID][date] > 0
+		if h.Target.Valid {
+			done = logged[h.ID] >= h.Target.Float64
+		}
+		schedule := ScheduleFromColumns(h.Schedule, h.Days, h.Count, h.Interval)
+		if done || !IsDue(schedule, StartOfDay(h.CreatedAt, loc), today, completed[h.ID]) {
+			continue
+		}
+		n := Notification{
+			UserID: userID,
+			Kind:   NotificationHabitReminder,
+			Title:  h.Name,
+			Body:   "Heute noch nicht erledigt",
+			URL:    "/habits",
+			Key:    fmt.Sprintf("habit:%d:%s", h.ID, date),
+		}
+		if err := sender.Send(n); err != nil {
+			log.Printf("Failed to send reminder %q to user %d: %v", n.Key, userID, err)
+		}
+	}
+	return nil
+}
+
+// nudgeJournals reminds users who set a "journal_reminder_time" to write today's journal
+// entry, unless they already did
+func nudgeJournals(sender reminderSender, now time.Time, location func(int) *time.Location) error {
+	rows, err := database.DB.Query(`
+		SELECT id, JSON_UNQUOTE(JSON_EXTRACT(settings, '$.journal_reminder_time'))
+		FROM users
+		WHERE JSON_TYPE(JSON_EXTRACT(settings, '$.journal_reminder_time')) = 'STRING'
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query journal reminders: %v", err)
+	}
+	type journalReminder struct {
+		UserID int
+		Time   string
+	}
+	var reminders []journalReminder
+	for rows.Next() {
+		var r journalReminder
+		if err := rows.Scan(&r.UserID, &r.Time); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan journal reminder: %v", err)
+		}
+		reminders = append(reminders, r)
+	}
+	rows.Close()
+
+	for _, r := range reminders {
+		today := Today(location(r.UserID))
+		remindAt, err := atTimeOfDay(today, r.Time)
+		if err != nil || now.Before(remindAt) {
+			continue
+		}
+		date := today.Format(DateLayout)
+		var written bool
+		err = database.DB.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM journal_entries WHERE user_id = ? AND entry_date = ?)
+		`, r.UserID, date).Scan(&written)
+		if err != nil {
+			log.Printf("Failed to check journal of user %d: %v", r.UserID, err)
+			continue
+		}
+		if written {
+			continue
+		}
+		n := Notification{
+			UserID: r.UserID,
+			Kind:   NotificationJournalNudge,
+			Title:  "Zeit für dein Journal",
+			Body:   "Wie war dein Tag? Nimm dir ein paar Minuten für deinen Eintrag.",
+			URL:    "/journal",
+			Key:    "journal:" + date,
+		}
+		if err := sender.Send(n); err != nil {
+			log.Printf("Failed to send reminder %q to user %d: %v", n.Key, r.UserID, err)
+		}
+	}
+	return nil
+}
+
+// StartNotificationJob sends due reminders through the inbox, email and Web Push,
+// checking once at startup and then every interval
+func StartNotificationJob(dispatcher *NotificationDispatcher, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := SendReminders(dispatcher, time.Now()); err != nil {
+				log.Printf("Notification job failed: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"habit-tracker-backend/internal/models"
+)
+
+// Habit schedule types
+const (
+	ScheduleDaily    = "daily"     // every day
+	ScheduleWeekdays = "weekdays"  // on specific weekdays
+	SchedulePerWeek  = "per_week"  // Count times per calendar week
+	SchedulePerMonth = "per_month" // Count times per calendar month
+	ScheduleInterval = "interval"  // once every IntervalDays days
+)
+
+// DateLayout is the format used for calendar days
+const DateLayout = "2006-01-02"
+
+// PeriodResult is the progress of a habit in one period of its schedule
+type PeriodResult struct {
+	Start     time.Time // first day of the period
+	End       time.Time // day after the last day of the period
+	Completed int
+	Required  int
+	Excused   bool // missed, but on a rest or frozen day: skipped by streaks and rates
+}
+
+// Met reports whether the target of the period was reached
+func (p PeriodResult) Met() bool {
+	return p.Completed >= p.Required
+}
+
+// NormalizeSchedule validates a schedule and drops fields that don't apply to its type.
+// An empty type means daily.
+func NormalizeSchedule(s *models.HabitSchedule) error {
+	switch s.Type {
+	case "", ScheduleDaily:
+		*s = models.HabitSchedule{Type: ScheduleDaily}
+	case ScheduleWeekdays:
+		seen := make(map[int]bool)
+		var days []int
+		for _, day := range s.Days {
+			if day < 1 || day > 7 {
+				return fmt.Errorf("weekdays must be between 1 (Monday) and 7 (Sunday)")
+			}
+			if !seen[day] {
+				seen[day] = true
+				days = append(days, day)
+			}
+		}
+		if len(days) == 0 {
+			return fmt.Errorf("a weekdays schedule needs at least one day")
+		}
+		sort.Ints(days)
+		*s = models.HabitSchedule{Type: ScheduleWeekdays, Days: days}
+	case SchedulePerWeek:
+		if s.Count < 1 || s.Count > 7 {
+			return fmt.Errorf("count must be between 1 and 7 for a per_week schedule")
+		}
+		*s = models.HabitSchedule{Type: SchedulePerWeek, Count: s.Count}
+	case SchedulePerMonth:
+		if s.Count < 1 || s.Count > 31 {
+			return fmt.Errorf("count must be between 1 and 31 for a per_month schedule")
+		}
+		*s = models.HabitSchedule{Type: SchedulePerMonth, Count: s.Count}
+	case ScheduleInterval:
+		if s.IntervalDays < 1 || s.IntervalDays > 365 {
+			return fmt.Errorf("interval_days must be between 1 and 365")
+		}
+		*s = models.HabitSchedule{Type: ScheduleInterval, IntervalDays: s.IntervalDays}
+	default:
+		return fmt.Errorf("unknown schedule type %q", s.Type)
+	}
+	return nil
+}
+
+// FormatDays stores a weekday set as "1,3,5"
+func FormatDays(days []int) string {
+	parts := make([]string, len(days))
+	for i, day := range days {
+		parts[i] = strconv.Itoa(day)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseDays reads a weekday set stored by FormatDays
+func ParseDays(raw string) []int {
+	var days []int
+	for _, part := range strings.Split(raw, ",") {
+		if day, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// ScheduleFromColumns builds a habit schedule from its database columns
+func ScheduleFromColumns(scheduleType string, days sql.NullString, count, interval sql.NullInt64) models.HabitSchedule {
+	schedule := models.HabitSchedule{Type: scheduleType}
+	switch scheduleType {
+	case ScheduleWeekdays:
+		schedule.Days = ParseDays(days.String)
+	case SchedulePerWeek, SchedulePerMonth:
+		schedule.Count = int(count.Int64)
+	case ScheduleInterval:
+		schedule.IntervalDays = int(interval.Int64)
+	}
+	return schedule
+}
+
+// IsScheduledDay reports whether the schedule requires the habit on that exact day.
+// Quota and interval schedules can be done on any day of their period, so they never are.
+func IsScheduledDay(s models.HabitSchedule, day time.Time) bool {
+	switch s.Type {
+	case ScheduleDaily, "":
+		return true
+	case ScheduleWeekdays:
+		return containsDay(s.Days, isoWeekday(day))
+	default:
+		return false
+	}
+}
+
+// IsDue reports whether the habit should be done on day: it is a scheduled day, or the
+// period containing day still needs completions (not counting those made on day itself).
+func IsDue(s models.HabitSchedule, anchor, day time.Time, completed map[string]int) bool {
+	switch s.Type {
+	case ScheduleDaily, "", ScheduleWeekdays:
+		return IsScheduledDay(s, day)
+	}
+
+	start, _, required, ok := periodContaining(s, anchor, day)
+	if !ok {
+		return false
+	}
+	done := 0
+	for d := start; d.Before(day); d = d.AddDate(0, 0, 1) {
+		done += completed[d.Format(DateLayout)]
+	}
+	return done < required
+}
+
+// PeriodStart returns the first day of the period containing day, or day itself
+// when the schedule has no period there
+func PeriodStart(s models.HabitSchedule, anchor, day time.Time) time.Time {
+	if start, _, _, ok := periodContaining(s, anchor, day); ok {
+		return start
+	}
+	return day
+}
+
+// EvaluateSchedule splits the days from anchor (the day the habit was created) to today
+// into schedule periods, oldest first, and counts the completions in each.
+// completed maps days to completions; periods starting on an excluded day are skipped.
+func EvaluateSchedule(s models.HabitSchedule, anchor, today time.Time, completed map[string]int, excluded func(day time.Time) bool) []PeriodResult {
+	var results []PeriodResult
+	skipping := false
+	for day := anchor; !day.After(today); day = day.AddDate(0, 0, 1) {
+		start, end, required, ok := periodContaining(s, anchor, day)
+		if !ok {
+			continue
+		}
+		if len(results) == 0 || !results[len(results)-1].Start.Equal(start) {
+			if skipping = excluded != nil && excluded(start); skipping {
+				continue
+			}
+			results = append(results, PeriodResult{Start: start, End: end, Required: required})
+		} else if skipping {
+			continue
+		}
+		results[len(results)-1].Completed += completed[day.Format(DateLayout)]
+	}
+
+	// A week or month the habit was created in midway only counts once its target is met
+	if len(results) > 0 && results[0].Start.Before(anchor) && !results[0].Met() {
+		results = results[1:]
+	}
+
+	return results
+}
+
+// ExcusePeriods marks the periods that missed their target but contain an excused day
+// up to today, so streaks, gaps and rates skip them
+func ExcusePeriods(results []PeriodResult, today time.Time, excused func(day time.Time) bool) {
+	for i := range results {
+		if results[i].Met() {
+			continue
+		}
+		for day := results[i].Start; day.Before(results[i].End) && !day.After(today); day = day.AddDate(0, 0, 1) {
+			if excused(day) {
+				results[i].Excused = true
+				break
+			}
+		}
+	}
+}
+
+// Streaks returns the current and best number of consecutive periods whose target was met.
+// The period containing today doesn't break the current streak while it is still open,
+// and excused periods neither break nor extend a streak.
+func Streaks(results []PeriodResult, today time.Time) (currentStreak, bestStreak int) {
+	i := len(results) - 1
+	if i >= 0 && results[i].End.After(today) && !results[i].Met() {
+		i--
+	}
+	for ; i >= 0 && (results[i].Met() || results[i].Excused); i-- {
+		if results[i].Met() {
+			currentStreak++
+		}
+	}
+
+	streak := 0
+	for _, result := range results {
+		if result.Excused {
+			continue
+		}
+		if result.Met() {
+			streak++
+			if streak > bestStreak {
+				bestStreak = streak
+			}
+		} else {
+			streak = 0
+		}
+	}
+
+	return currentStreak, bestStreak
+}
+
+// LongestGap returns the most consecutive periods whose target was missed.
+// The period containing today doesn't count while it is still open.
+func LongestGap(results []PeriodResult, today time.Time) int {
+	longest, gap := 0, 0
+	for _, result := range results {
+		if result.Excused {
+			continue
+		}
+		if result.Met() || result.End.After(today) {
+			gap = 0
+			continue
+		}
+		gap++
+		if gap > longest {
+			longest = gap
+		}
+	}
+	return longest
+}
+
+// CompletionRate returns the share of periods ending after since whose target was met,
+// from 0 to 1. A still open period only counts once it is met; excused ones don't count.
+func CompletionRate(results []PeriodResult, today, since time.Time) float64 {
+	total, met := 0, 0
+	for _, result := range results {
+		if !result.End.After(since) || result.Excused {
+			continue
+		}
+		if result.End.After(today) && !result.Met() {
+			continue
+		}
+		total++
+		if result.Met() {
+			met++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(met) / float64(total)
+}
+
+// periodContaining returns the period of the schedule that contains day and how many
+// completions it requires. ok is false when day belongs to no period.
+func periodContaining(s models.HabitSchedule, anchor, day time.Time) (start, end time.Time, required int, ok bool) {
+	switch s.Type {
+	case ScheduleWeekdays:
+		if !containsDay(s.Days, isoWeekday(day)) {
+			return time.Time{}, time.Time{}, 0, false
+		}
+		return day, day.AddDate(0, 0, 1), 1, true
+	case SchedulePerWeek:
+		start = day.AddDate(0, 0, -(isoWeekday(day) - 1))
+		return start, start.AddDate(0, 0, 7), s.Count, true
+	case SchedulePerMonth:
+		start = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+		return start, start.AddDate(0, 1, 0), s.Count, true
+	case ScheduleInterval:
+		elapsed := daysBetween(anchor, day)
+		if elapsed < 0 || s.IntervalDays < 1 {
+			return time.Time{}, time.Time{}, 0, false
+		}
+		start = anchor.AddDate(0, 0, elapsed-elapsed%s.IntervalDays)
+		return start, start.AddDate(0, 0, s.IntervalDays), 1, true
+	default:
+		return day, day.AddDate(0, 0, 1), 1, true
+	}
+}
+
+// isoWeekday numbers weekdays from 1 (Monday) to 7 (Sunday)
+func isoWeekday(day time.Time) int {
+	if day.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(day.Weekday())
+}
+
+func containsDay(days []int, day int) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// daysBetween counts calendar days from a to b, unaffected by DST changes
+func daysBetween(a, b time.Time) int {
+	ua := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	ub := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(ub.Sub(ua).Hours() / 24)
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"strconv"
+	"time"
+
+	"habit-tracker-backend/internal/models"
+)
+
+// StatsWindows are the numbers of days completion rates are reported for
+var StatsWindows = []int{7, 30, 90, 365}
+
+// HeatmapDays is how many days the heatmap covers, ending today
+const HeatmapDays = 365
+
+// HabitHistory is what the statistics of one habit are computed from
+type HabitHistory struct {
+	HabitID   int
+	Schedule  models.HabitSchedule
+	Anchor    time.Time                // the day the habit was created
+	Completed map[string]int           // completions per day
+	Archived  func(day time.Time) bool // whether the habit was archived on day
+	Excused   func(day time.Time) bool // rest and frozen days, may be nil
+}
+
+// active reports whether the habit existed and wasn't archived on day
+func (h HabitHistory) active(day time.Time) bool {
+	return !day.Before(h.Anchor) && (h.Archived == nil || !h.Archived(day))
+}
+
+// excused reports whether misses on day don't count
+func (h HabitHistory) excused(day time.Time) bool {
+	return h.Excused != nil && h.Excused(day)
+}
+
+// Periods evaluates the habit's schedule from its creation until today
+func (h HabitHistory) Periods(today time.Time) []PeriodResult {
+	results := EvaluateSchedule(h.Schedule, h.Anchor, today, h.Completed, h.Archived)
+	if h.Excused != nil {
+		ExcusePeriods(results, today, h.Excused)
+	}
+	return results
+}
+
+// WeekdayStats is how often habits were due and done on one weekday
+type WeekdayStats struct {
+	Weekday   int     `json:"weekday"` // 1 (Monday) to 7 (Sunday)
+	Due       int     `json:"due"`
+	Completed int     `json:"completed"`
+	Rate      float64 `json:"rate"`
+}
+
+// HeatmapDay is how many habits were due and completed on one day
+type HeatmapDay struct {
+	Date      string `json:"date"`
+	Due       int    `json:"due"`
+	Completed int    `json:"completed"`
+}
+
+// HabitStats summarizes the history of one habit or of all habits of a user
+type HabitStats struct {
+	CompletionRates map[string]float64 `json:"completion_rates"` // by window, e.g. "30d"
+	CurrentStreak   int                `json:"current_streak"`
+	BestStreak      int                `json:"best_streak"`
+	LongestGap      int                `json:"longest_gap"` // most consecutive missed periods
+	Weekdays        []WeekdayStats     `json:"weekdays"`
+	Heatmap         []HeatmapDay       `json:"heatmap"`
+}
+
+// ComputeHabitStats computes the statistics of one habit up to today.
+// Streaks and gaps are counted in the periods of its schedule.
+func ComputeHabitStats(history HabitHistory, today time.Time) HabitStats {
+	return computeStats([]HabitHistory{history}, history.Periods(today), today)
+}
+
+// ComputeOverallStats computes the statistics of several habits together up to today.
+// Streaks and gaps are counted in days on which all scheduled habits were done (see DailyResults).
+func ComputeOverallStats(histories []HabitHistory, today time.Time) HabitStats {
+	return computeStats(histories, DailyResults(histories, today), today)
+}
+
+// computeStats computes completion rates over the schedule periods of all habits and
+// streaks over streakPeriods
+func computeStats(histories []HabitHistory, streakPeriods []PeriodResult, today time.Time) HabitStats {
+	var periods []PeriodResult
+	for _, h := range histories {
+		periods = append(periods, h.Periods(today)...)
+	}
+
+	stats := HabitStats{CompletionRates: make(map[string]float64)}
+	for _, days := range StatsWindows {
+		stats.CompletionRates[strconv.Itoa(days)+"d"] = CompletionRate(periods, today, today.AddDate(0, 0, -days))
+	}
+
+	stats.CurrentStreak, stats.BestStreak = Streaks(streakPeriods, today)
+	stats.LongestGap = LongestGap(streakPeriods, today)
+
+	stats.Weekdays = make([]WeekdayStats, 7)
+	for i := range stats.Weekdays {
+		stats.Weekdays[i].Weekday = i + 1
+	}
+	first := today.AddDate(0, 0, -(HeatmapDays - 1))
+	for day := first; !day.After(today); day = day.AddDate(0, 0, 1) {
+		dayStr := day.Format(DateLayout)
+		heat := HeatmapDay{Date: dayStr}
+		weekday := &stats.Weekdays[isoWeekday(day)-1]
+		for _, h := range histories {
+			if !h.active(day) {
+				continue
+			}
+			done := h.Completed[dayStr] > 0
+			if done {
+				heat.Completed++
+			}
+			// Today only counts once done, it isn't missed yet; nor are excused days
+			if IsDue(h.Schedule, h.Anchor, day, h.Completed) && (done || (day.Before(today) && !h.excused(day))) {
+				heat.Due++
+				weekday.Due++
+				if done {
+					weekday.Completed++
+				}
+			}
+		}
+		stats.Heatmap = append(stats.Heatmap, heat)
+	}
+	for i := range stats.Weekdays {
+		if stats.Weekdays[i].Due > 0 {
+			stats.Weekdays[i].Rate = float64(stats.Weekdays[i].Completed) / float64(stats.Weekdays[i].Due)
+		}
+	}
+
+	return stats
+}
+
+// DailyResults returns, oldest first, one result per day on which at least one habit
+// was scheduled, with how many of those habits were completed. Days run from the first
+// completion until today. A habit counts from the day it was created, only on its
+// scheduled days and not while it was archived, so archiving or adding a habit never
+// rewrites history. Quota and interval habits can be done on any day of their period
+// and are left out. Missed days that any of the habits excuses are marked excused.
+func DailyResults(histories []HabitHistory, today time.Time) []PeriodResult {
+	first := ""
+	for _, h := range histories {
+		for date, count := range h.Completed {
+			if count > 0 && (first == "" || date < first) {
+				first = date
+			}
+		}
+	}
+	if first == "" {
+		return nil
+	}
+	firstDay, err := time.ParseInLocation(DateLayout, first, today.Location())
+	if err != nil {
+		return nil
+	}
+
+	var days []PeriodResult
+	for day := firstDay; !day.After(today); day = day.AddDate(0, 0, 1) {
+		dayStr := day.Format(DateLayout)
+		result := PeriodResult{Start: day, End: day.AddDate(0, 0, 1)}
+		for _, h := range histories {
+			if !h.active(day) || !IsScheduledDay(h.Schedule, day) {
+				continue
+			}
+			result.Required++
+			if h.Completed[dayStr] > 0 {
+				result.Completed++
+			}
+		}
+		if result.Required == 0 {
+			continue
+		}
+		if !result.Met() {
+			for _, h := range histories {
+				if h.excused(day) {
+					result.Excused = true
+					break
+				}
+			}
+		}
+		days = append(days, result)
+	}
+	return days
+}
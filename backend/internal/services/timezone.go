@@ -0,0 +1,68 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"habit-tracker-backend/internal/database"
+)
+
+// DefaultLocation is the time zone of users who haven't set one:
+// DEFAULT_TIMEZONE when it is a valid IANA name, otherwise the server's zone
+func DefaultLocation() *time.Location {
+	if name := os.Getenv("DEFAULT_TIMEZONE"); name != "" {
+		if loc, err := LoadTimezone(name); err == nil {
+			return loc
+		}
+		log.Printf("Ignoring invalid DEFAULT_TIMEZONE %q", name)
+	}
+	return time.Local
+}
+
+// LoadTimezone validates an IANA time zone name such as "Europe/Berlin"
+func LoadTimezone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	// time.LoadLocation treats "" as UTC and "Local" as the server zone, neither is a user setting
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("invalid time zone %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q", name)
+	}
+	return loc, nil
+}
+
+// UserLocation returns the time zone stored in the user's settings ("timezone"),
+// falling back to DefaultLocation when it is missing or invalid
+func UserLocation(userID int) *time.Location {
+	var name sql.NullString
+	err := database.DB.QueryRow(`
+		SELECT JSON_UNQUOTE(JSON_EXTRACT(settings, '$.timezone')) FROM users WHERE id = ?
+	`, userID).Scan(&name)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Failed to load time zone of user %d: %v", userID, err)
+	}
+	if name.Valid && name.String != "" && name.String != "null" {
+		if loc, err := LoadTimezone(name.String); err == nil {
+			return loc
+		}
+	}
+	return DefaultLocation()
+}
+
+// Today returns midnight of the current day in loc
+func Today(loc *time.Location) time.Time {
+	now := time.Now().In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+}
+
+// StartOfDay returns midnight of the day t falls on in loc
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
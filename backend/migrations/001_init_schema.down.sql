@@ -0,0 +1,11 @@
+-- Rollback 001: Drop the initial schema
+-- Child tables are dropped first so foreign keys don't block the drop
+
+DROP TABLE IF EXISTS chat_messages;
+DROP TABLE IF EXISTS chat_sessions;
+DROP TABLE IF EXISTS journal_entries;
+DROP TABLE IF EXISTS tasks;
+DROP TABLE IF EXISTS habit_completions;
+DROP TABLE IF EXISTS habits;
+DROP TABLE IF EXISTS user_sessions;
+DROP TABLE IF EXISTS users;
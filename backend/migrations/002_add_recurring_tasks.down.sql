@@ -0,0 +1,9 @@
+-- Rollback 002: Remove recurring task columns
+
+ALTER TABLE tasks DROP FOREIGN KEY fk_parent_task;
+
+ALTER TABLE tasks
+DROP COLUMN parent_task_id,
+DROP COLUMN is_recurring_template,
+DROP COLUMN recurrence_interval_weeks,
+DROP COLUMN recurrence_end_date;
@@ -0,0 +1,4 @@
+-- Rollback 003: Remove Notes/Plans tables
+
+DROP TABLE IF EXISTS checklist_items;
+DROP TABLE IF EXISTS notes;
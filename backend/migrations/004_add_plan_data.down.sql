@@ -0,0 +1,3 @@
+-- Rollback 004: Remove plan data table
+
+DROP TABLE IF EXISTS plan_data;
@@ -0,0 +1,3 @@
+-- Rollback 005: Remove media attachments table
+
+DROP TABLE IF EXISTS media_attachments;
@@ -0,0 +1,4 @@
+-- Rollback 006: Remove meditation tables
+
+DROP TABLE IF EXISTS meditation_messages;
+DROP TABLE IF EXISTS meditation_sessions;
@@ -0,0 +1,13 @@
+-- Rollback 007: Restore the original user_sessions layout
+
+DELETE FROM user_sessions;
+
+ALTER TABLE user_sessions
+DROP INDEX idx_session_family,
+DROP INDEX unique_session_token,
+DROP COLUMN ip_address,
+DROP COLUMN user_agent,
+DROP COLUMN revoked_at,
+DROP COLUMN replaced_by,
+DROP COLUMN family_id,
+MODIFY COLUMN token VARCHAR(500) NOT NULL;
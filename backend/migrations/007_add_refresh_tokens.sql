@@ -0,0 +1,17 @@
+-- Migration 007: Use user_sessions as the refresh token store
+-- token holds the SHA-256 hash of the refresh token, never the token itself.
+-- Every rotation inserts a new row with the same family_id; presenting an
+-- already rotated token revokes the whole family.
+
+-- The table was never written before, but make sure no stale rows block the column change
+DELETE FROM user_sessions;
+
+ALTER TABLE user_sessions
+MODIFY COLUMN token CHAR(64) NOT NULL,
+ADD COLUMN family_id CHAR(32) NOT NULL AFTER user_id,
+ADD COLUMN replaced_by INT NULL,
+ADD COLUMN revoked_at TIMESTAMP NULL,
+ADD COLUMN user_agent VARCHAR(255) NULL,
+ADD COLUMN ip_address VARCHAR(45) NULL,
+ADD UNIQUE KEY unique_session_token (token),
+ADD INDEX idx_session_family (family_id, revoked_at);
@@ -0,0 +1,6 @@
+-- Rollback 008: Drop password reset and email verification tokens
+
+DROP TABLE IF EXISTS auth_tokens;
+
+ALTER TABLE users
+DROP COLUMN email_verified_at;
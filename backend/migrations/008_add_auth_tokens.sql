@@ -0,0 +1,19 @@
+-- Migration 008: Password reset and email verification tokens
+-- token_hash holds the SHA-256 hash of the emailed token, never the token itself.
+-- Tokens are single-use: used_at is set when the token is redeemed.
+
+ALTER TABLE users
+ADD COLUMN email_verified_at TIMESTAMP NULL AFTER name;
+
+CREATE TABLE IF NOT EXISTS auth_tokens (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    purpose ENUM('password_reset', 'email_verification') NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_auth_token (token_hash),
+    INDEX idx_auth_tokens_user (user_id, purpose, used_at)
+);
@@ -0,0 +1,6 @@
+-- Rollback 009: Drop habit archiving
+
+DROP TABLE IF EXISTS habit_archive_periods;
+
+ALTER TABLE habits
+DROP COLUMN archived_at;
@@ -0,0 +1,24 @@
+-- Migration 009: Habit archiving
+-- archived_at is set while a habit is archived (is_active = false).
+-- habit_archive_periods keeps every archived stretch so streaks can exclude a
+-- habit only for the days it was actually archived.
+
+ALTER TABLE habits
+ADD COLUMN archived_at TIMESTAMP NULL AFTER is_active;
+
+CREATE TABLE IF NOT EXISTS habit_archive_periods (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    habit_id INT NOT NULL,
+    user_id INT NOT NULL,
+    archived_on DATE NOT NULL,
+    restored_on DATE NULL,
+    FOREIGN KEY (habit_id) REFERENCES habits(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_archive_periods_user (user_id, habit_id)
+);
+
+-- Habits deactivated before this migration count as archived since their last update
+INSERT INTO habit_archive_periods (habit_id, user_id, archived_on)
+SELECT id, user_id, DATE(updated_at) FROM habits WHERE is_active = false;
+
+UPDATE habits SET archived_at = updated_at, updated_at = updated_at WHERE is_active = false;
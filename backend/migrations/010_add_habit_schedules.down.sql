@@ -0,0 +1,7 @@
+-- Rollback 010: Drop habit schedules
+
+ALTER TABLE habits
+DROP COLUMN schedule_interval,
+DROP COLUMN schedule_count,
+DROP COLUMN schedule_days,
+DROP COLUMN schedule_type;
@@ -0,0 +1,18 @@
+-- Migration 010: Habit schedules
+-- schedule_type decides which of the other columns apply:
+--   daily      every day
+--   weekdays   schedule_days, ISO weekdays "1,3,5" (1 = Monday)
+--   per_week   schedule_count times per calendar week
+--   per_month  schedule_count times per calendar month
+--   interval   once every schedule_interval days, counted from creation
+
+ALTER TABLE habits
+ADD COLUMN schedule_type ENUM('daily', 'weekdays', 'per_week', 'per_month', 'interval') NOT NULL DEFAULT 'daily' AFTER target_frequency,
+ADD COLUMN schedule_days VARCHAR(20) NULL AFTER schedule_type,
+ADD COLUMN schedule_count INT NULL AFTER schedule_days,
+ADD COLUMN schedule_interval INT NULL AFTER schedule_count;
+
+-- A target frequency below 7 meant "N times per week"
+UPDATE habits
+SET schedule_type = 'per_week', schedule_count = target_frequency, updated_at = updated_at
+WHERE target_frequency BETWEEN 1 AND 6;
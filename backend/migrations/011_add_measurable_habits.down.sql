@@ -0,0 +1,7 @@
+-- Rollback 011: Drop measurable habits
+
+DROP TABLE IF EXISTS habit_logs;
+
+ALTER TABLE habits
+DROP COLUMN target_value,
+DROP COLUMN unit;
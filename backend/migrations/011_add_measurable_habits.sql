@@ -0,0 +1,20 @@
+-- Migration 011: Measurable habits
+-- A habit with a target_value is measurable: values are logged in habit_logs and
+-- the day's habit_completions row exists once the logged total reaches the target.
+
+ALTER TABLE habits
+ADD COLUMN unit VARCHAR(20) NULL AFTER schedule_interval,
+ADD COLUMN target_value DECIMAL(10,2) NULL AFTER unit;
+
+CREATE TABLE IF NOT EXISTS habit_logs (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    habit_id INT NOT NULL,
+    user_id INT NOT NULL,
+    value DECIMAL(10,2) NOT NULL,
+    log_date DATE NOT NULL,
+    logged_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (habit_id) REFERENCES habits(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_habit_logs_day (habit_id, log_date),
+    INDEX idx_habit_logs_user_day (user_id, log_date)
+);
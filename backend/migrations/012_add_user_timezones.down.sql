@@ -0,0 +1,6 @@
+-- Rollback 012: Derive completion dates from completed_at again
+
+DROP INDEX idx_habit_completions_user_date ON habit_completions;
+
+ALTER TABLE habit_completions
+MODIFY COLUMN completed_date DATE GENERATED ALWAYS AS (DATE(completed_at)) STORED;
@@ -0,0 +1,9 @@
+-- Migration 012: Per-user time zones
+-- completed_date was generated from completed_at in the MySQL session zone. It becomes a
+-- regular column the backend fills with the day of the completion in the user's time zone
+-- (users.settings.timezone). Existing rows keep their dates.
+
+ALTER TABLE habit_completions
+MODIFY COLUMN completed_date DATE NOT NULL;
+
+CREATE INDEX idx_habit_completions_user_date ON habit_completions (user_id, completed_date);
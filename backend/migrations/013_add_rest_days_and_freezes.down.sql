@@ -0,0 +1,4 @@
+-- Rollback 013: Drop rest days and streak freezes
+
+DROP TABLE IF EXISTS streak_freezes;
+DROP TABLE IF EXISTS rest_periods;
@@ -0,0 +1,24 @@
+-- Migration 013: Rest days and streak freezes
+-- rest_periods are date ranges a user declared as rest or vacation; missed habits on
+-- those days don't break streaks. streak_freezes records the missed days that were
+-- covered automatically by one of the user's monthly freeze tokens.
+
+CREATE TABLE IF NOT EXISTS rest_periods (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    reason VARCHAR(255) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_rest_periods_user (user_id, start_date)
+);
+
+CREATE TABLE IF NOT EXISTS streak_freezes (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    frozen_on DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_user_freeze (user_id, frozen_on)
+);
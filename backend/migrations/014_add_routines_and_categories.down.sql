@@ -0,0 +1,13 @@
+-- Rollback 014: Drop routines and custom habit categories
+-- Habits in custom categories move to 'morning'.
+
+DROP TABLE IF EXISTS routine_habits;
+DROP TABLE IF EXISTS routines;
+
+UPDATE habits SET category = 'morning', updated_at = updated_at
+WHERE category NOT IN ('morning', 'afternoon', 'evening');
+
+ALTER TABLE habits
+MODIFY COLUMN category ENUM('morning', 'afternoon', 'evening') NOT NULL;
+
+DROP TABLE IF EXISTS habit_categories;
@@ -0,0 +1,50 @@
+-- Migration 014: Routines and custom habit categories
+-- habits.category becomes free text naming one of the user's habit_categories; every
+-- user starts with the former ENUM values. Routines are ordered groups of habits.
+
+CREATE TABLE IF NOT EXISTS habit_categories (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    icon VARCHAR(50) NULL,
+    color VARCHAR(20) NULL,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_user_category (user_id, name)
+);
+
+INSERT IGNORE INTO habit_categories (user_id, name, position)
+SELECT u.id, d.name, d.position
+FROM users u
+CROSS JOIN (
+    SELECT 'morning' AS name, 0 AS position
+    UNION ALL SELECT 'afternoon', 1
+    UNION ALL SELECT 'evening', 2
+) d;
+
+ALTER TABLE habits
+MODIFY COLUMN category VARCHAR(50) NOT NULL;
+
+CREATE TABLE IF NOT EXISTS routines (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    icon VARCHAR(50),
+    color VARCHAR(20),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_routines_user (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS routine_habits (
+    routine_id INT NOT NULL,
+    habit_id INT NOT NULL,
+    position INT NOT NULL,
+    PRIMARY KEY (routine_id, habit_id),
+    FOREIGN KEY (routine_id) REFERENCES routines(id) ON DELETE CASCADE,
+    FOREIGN KEY (habit_id) REFERENCES habits(id) ON DELETE CASCADE,
+    INDEX idx_routine_habits_habit (habit_id)
+);
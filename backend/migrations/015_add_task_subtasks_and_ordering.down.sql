@@ -0,0 +1,7 @@
+-- Rollback 015: Drop subtasks and manual task ordering
+
+ALTER TABLE tasks
+DROP FOREIGN KEY fk_task_parent,
+DROP INDEX idx_tasks_user_parent_position,
+DROP COLUMN position,
+DROP COLUMN parent_id;
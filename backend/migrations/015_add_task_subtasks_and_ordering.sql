@@ -0,0 +1,12 @@
+-- Migration 015: Subtasks and manual task ordering
+-- parent_id nests a task under another one. It is independent of parent_task_id,
+-- which links generated instances of a recurring task. position orders tasks
+-- among their siblings; existing tasks keep their creation order.
+
+ALTER TABLE tasks
+ADD COLUMN parent_id INT NULL,
+ADD COLUMN position INT NOT NULL DEFAULT 0,
+ADD CONSTRAINT fk_task_parent FOREIGN KEY (parent_id) REFERENCES tasks(id) ON DELETE CASCADE,
+ADD INDEX idx_tasks_user_parent_position (user_id, parent_id, position);
+
+UPDATE tasks SET position = id, updated_at = updated_at;
@@ -0,0 +1,17 @@
+-- Rollback 016: Drop recurrence rules; instances are kept as plain tasks
+
+UPDATE tasks SET parent_task_id = NULL WHERE parent_task_id IS NOT NULL;
+DELETE FROM tasks WHERE is_recurring_template = TRUE;
+
+-- The unique key backs fk_parent_task, so the constraint is recreated around it
+ALTER TABLE tasks DROP FOREIGN KEY fk_parent_task;
+
+ALTER TABLE tasks
+DROP INDEX unique_task_occurrence,
+DROP INDEX idx_tasks_recurring_template,
+DROP COLUMN generated_until,
+DROP COLUMN occurrence_date,
+DROP COLUMN recurrence_rule;
+
+ALTER TABLE tasks
+ADD CONSTRAINT fk_parent_task FOREIGN KEY (parent_task_id) REFERENCES tasks(id) ON DELETE CASCADE;
@@ -0,0 +1,13 @@
+-- Migration 016: Recurring tasks as templates with recurrence rules
+-- A recurring task is a template row (is_recurring_template) holding an RRULE in
+-- recurrence_rule; its due_date is the first occurrence. Instances reference the
+-- template through parent_task_id and are created up to a rolling horizon;
+-- generated_until records how far, so deleted instances are not created again.
+-- Instances generated up front by earlier versions stay plain tasks.
+
+ALTER TABLE tasks
+ADD COLUMN recurrence_rule VARCHAR(255) NULL,
+ADD COLUMN occurrence_date DATE NULL,
+ADD COLUMN generated_until DATE NULL,
+ADD UNIQUE KEY unique_task_occurrence (parent_task_id, occurrence_date),
+ADD INDEX idx_tasks_recurring_template (is_recurring_template, user_id);
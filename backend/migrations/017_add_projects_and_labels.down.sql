@@ -0,0 +1,9 @@
+-- Rollback 017: Drop projects and labels
+
+ALTER TABLE tasks
+DROP FOREIGN KEY fk_task_project,
+DROP COLUMN project_id;
+
+DROP TABLE IF EXISTS task_labels;
+DROP TABLE IF EXISTS labels;
+DROP TABLE IF EXISTS projects;
@@ -0,0 +1,8 @@
+-- Rollback 018: Unlink tasks from notes/plans
+
+ALTER TABLE tasks
+DROP FOREIGN KEY fk_task_note,
+DROP FOREIGN KEY fk_task_checklist_item,
+DROP INDEX unique_task_checklist_item,
+DROP COLUMN note_id,
+DROP COLUMN checklist_item_id;
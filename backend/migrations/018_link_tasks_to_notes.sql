@@ -0,0 +1,10 @@
+-- Migration 018: Link tasks to notes/plans
+-- A checklist item promoted to a task stays linked to it, so completing one
+-- completes the other. Deleting the note or the item keeps the task unlinked.
+
+ALTER TABLE tasks
+ADD COLUMN note_id INT NULL,
+ADD COLUMN checklist_item_id INT NULL,
+ADD CONSTRAINT fk_task_note FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE SET NULL,
+ADD CONSTRAINT fk_task_checklist_item FOREIGN KEY (checklist_item_id) REFERENCES checklist_items(id) ON DELETE SET NULL,
+ADD UNIQUE KEY unique_task_checklist_item (checklist_item_id);
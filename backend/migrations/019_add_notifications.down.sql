@@ -0,0 +1,7 @@
+-- Rollback 019: Remove reminders and notifications
+
+ALTER TABLE habits
+DROP COLUMN reminder_time;
+
+DROP TABLE IF EXISTS push_subscriptions;
+DROP TABLE IF EXISTS notifications;
@@ -0,0 +1,35 @@
+-- Migration 019: Reminders and notifications
+-- notifications is the in-app inbox and the record of every reminder sent. The
+-- scheduler derives dedupe_key from what it reminds of ("task_due:12:1767225540"),
+-- so a reminder is stored, and pushed or emailed, at most once even across restarts.
+-- push_subscriptions are the Web Push endpoints of a user's browsers.
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    kind VARCHAR(30) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NULL,
+    url VARCHAR(255) NULL,
+    dedupe_key VARCHAR(100) NOT NULL,
+    read_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_notification (user_id, dedupe_key),
+    INDEX idx_notifications_user (user_id, read_at, created_at)
+);
+
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    endpoint VARCHAR(500) NOT NULL,
+    p256dh VARCHAR(255) NOT NULL,
+    auth VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_push_endpoint (endpoint)
+);
+
+-- Local time of day after which a habit that is due and not yet done is reminded of
+ALTER TABLE habits
+ADD COLUMN reminder_time TIME NULL AFTER target_value;
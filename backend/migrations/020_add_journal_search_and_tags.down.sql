@@ -0,0 +1,18 @@
+-- Rollback 020: Store journal tags as JSON again and drop the full-text index
+
+ALTER TABLE journal_entries
+ADD COLUMN tags JSON NULL AFTER content;
+
+UPDATE journal_entries e
+SET e.tags = (
+    SELECT JSON_ARRAYAGG(t.name)
+    FROM journal_entry_tags et
+    INNER JOIN journal_tags t ON t.id = et.tag_id
+    WHERE et.entry_id = e.id
+), e.updated_at = e.updated_at;
+
+DROP TABLE IF EXISTS journal_entry_tags;
+DROP TABLE IF EXISTS journal_tags;
+
+ALTER TABLE journal_entries
+DROP INDEX ft_journal_entries_content;
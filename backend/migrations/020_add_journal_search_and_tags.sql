@@ -0,0 +1,42 @@
+-- Migration 020: Journal full-text search and normalised tags
+-- Tags move from the JSON array in journal_entries.tags to journal_tags, one row
+-- per user and name (compared case-insensitively by the collation), linked to
+-- entries in their original order, so a tag can be renamed or merged in one place.
+
+ALTER TABLE journal_entries
+ADD FULLTEXT INDEX ft_journal_entries_content (content);
+
+CREATE TABLE IF NOT EXISTS journal_tags (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_user_journal_tag (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS journal_entry_tags (
+    entry_id INT NOT NULL,
+    tag_id INT NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (entry_id, tag_id),
+    FOREIGN KEY (entry_id) REFERENCES journal_entries(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES journal_tags(id) ON DELETE CASCADE,
+    INDEX idx_journal_entry_tags_tag (tag_id)
+);
+
+INSERT IGNORE INTO journal_tags (user_id, name)
+SELECT DISTINCT e.user_id, LEFT(TRIM(jt.name), 255)
+FROM journal_entries e
+CROSS JOIN JSON_TABLE(e.tags, '$[*]' COLUMNS (name VARCHAR(1000) PATH '$')) AS jt
+WHERE JSON_TYPE(e.tags) = 'ARRAY' AND TRIM(jt.name) <> '';
+
+INSERT IGNORE INTO journal_entry_tags (entry_id, tag_id, position)
+SELECT e.id, t.id, jt.position - 1
+FROM journal_entries e
+CROSS JOIN JSON_TABLE(e.tags, '$[*]' COLUMNS (position FOR ORDINALITY, name VARCHAR(1000) PATH '$')) AS jt
+INNER JOIN journal_tags t ON t.user_id = e.user_id AND t.name = LEFT(TRIM(jt.name), 255)
+WHERE JSON_TYPE(e.tags) = 'ARRAY';
+
+ALTER TABLE journal_entries
+DROP COLUMN tags;
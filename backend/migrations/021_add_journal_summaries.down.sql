@@ -0,0 +1,3 @@
+-- Rollback 021: Remove cached journal summaries
+
+DROP TABLE IF EXISTS journal_summaries;
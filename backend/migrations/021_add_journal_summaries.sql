@@ -0,0 +1,19 @@
+-- Migration 021: Cached journal summaries
+-- A summary of a user's journal entries over a week or month is generated once and kept
+-- here. entries_hash fingerprints the entries it was generated from; it is regenerated
+-- only when they no longer match.
+
+CREATE TABLE IF NOT EXISTS journal_summaries (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    period ENUM('week', 'month') NOT NULL,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    summary TEXT NOT NULL,
+    entry_count INT NOT NULL,
+    entries_hash CHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_journal_summary (user_id, period, period_start)
+);
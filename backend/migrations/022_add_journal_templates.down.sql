@@ -0,0 +1,13 @@
+-- Rollback 022: Remove structured journal templates
+
+ALTER TABLE journal_entries
+DROP INDEX ft_journal_entries_text,
+ADD FULLTEXT INDEX ft_journal_entries_content (content);
+
+ALTER TABLE journal_entries
+DROP FOREIGN KEY fk_journal_entries_template,
+DROP COLUMN fields_text,
+DROP COLUMN fields,
+DROP COLUMN template_id;
+
+DROP TABLE IF EXISTS journal_templates;
@@ -0,0 +1,27 @@
+-- Migration 022: Structured journal templates
+-- A template lists typed fields (list, scale 1-10, text, checkbox) as a JSON array.
+-- Entries written with one keep their answers in journal_entries.fields, keyed by
+-- field key, and their text in fields_text, which full-text search covers along
+-- with content. Deleting a template keeps the answers of its entries.
+
+CREATE TABLE IF NOT EXISTS journal_templates (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NULL,
+    fields JSON NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_user_journal_template (user_id, name)
+);
+
+ALTER TABLE journal_entries
+ADD COLUMN template_id INT NULL AFTER content,
+ADD COLUMN fields JSON NULL AFTER template_id,
+ADD COLUMN fields_text TEXT NULL AFTER fields,
+ADD CONSTRAINT fk_journal_entries_template FOREIGN KEY (template_id) REFERENCES journal_templates(id) ON DELETE SET NULL;
+
+ALTER TABLE journal_entries
+DROP INDEX ft_journal_entries_content,
+ADD FULLTEXT INDEX ft_journal_entries_text (content, fields_text);
@@ -0,0 +1,4 @@
+-- Rollback 023: Forget which days streak freezes were settled through
+
+ALTER TABLE users
+DROP COLUMN freezes_settled_through;
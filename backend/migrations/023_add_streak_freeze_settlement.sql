@@ -0,0 +1,6 @@
+-- Migration 023: Streak freeze settlement
+-- The streak freeze job settles each user's finished days once. freezes_settled_through
+-- is the last day it settled, so days missed while the server was down are caught up on.
+
+ALTER TABLE users
+ADD COLUMN freezes_settled_through DATE NULL;
@@ -0,0 +1,10 @@
+// Package migrations embeds the versioned SQL migration files so the server
+// binary can apply them without the source tree being present.
+package migrations
+
+import "embed"
+
+// FS contains all NNN_name.sql (up) and NNN_name.down.sql (down) files
+//
+//go:embed *.sql
+var FS embed.FS
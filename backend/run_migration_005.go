@@ -1,71 +0,0 @@
-package main
-
-import (
-	"database/sql"
-	"fmt"
-	"log"
-	"os"
-
-	_ "github.com/go-sql-driver/mysql"
-	"github.com/joho/godotenv"
-)
-
-func main() {
-	// Load environment variables
-	envPaths := []string{"config.env", "../config.env", "../../config.env"}
-	var envLoaded bool
-	for _, path := range envPaths {
-		if err := godotenv.Load(path); err == nil {
-			log.Printf("Loaded config.env from: %s", path)
-			envLoaded = true
-			break
-		}
-	}
-	if !envLoaded {
-		log.Println("No config.env file found, using system environment variables")
-	}
-
-	// Database connection
-	host := os.Getenv("DB_HOST")
-	if host == "" {
-		host = "localhost"
-	}
-	port := os.Getenv("DB_PORT")
-	if port == "" {
-		port = "3306"
-	}
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		user, password, host, port, dbname)
-
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-	defer db.Close()
-
-	// Test connection
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
-	}
-
-	log.Println("Database connection established")
-
-	// Read migration file
-	migrationSQL, err := os.ReadFile("migrations/005_add_media_attachments.sql")
-	if err != nil {
-		log.Fatalf("Failed to read migration file: %v", err)
-	}
-
-	// Execute migration
-	_, err = db.Exec(string(migrationSQL))
-	if err != nil {
-		log.Fatalf("Failed to execute migration: %v", err)
-	}
-
-	log.Println("Migration 005 executed successfully: media_attachments table created")
-}
-
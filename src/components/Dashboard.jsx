@@ -25,7 +25,7 @@ const Dashboard = () => {
       try {
         const [habitsData, tasksData] = await Promise.all([
           habitsAPI.getHabits(),
-          tasksAPI.getTasks()
+          tasksAPI.getTasks({ instances: 'all' })
         ])
         // Ensure arrays are never null/undefined
         setHabits(Array.isArray(habitsData) ? habitsData : [])
@@ -87,7 +87,7 @@ const Dashboard = () => {
     try {
       await tasksAPI.completeTask(taskId)
       // Reload tasks to update completion status
-      const updatedTasks = await tasksAPI.getTasks()
+      const updatedTasks = await tasksAPI.getTasks({ instances: 'all' })
       setTasks(updatedTasks)
       // Reload will also update progress bar automatically
     } catch (error) {
@@ -216,7 +216,7 @@ const Dashboard = () => {
       await tasksAPI.createTask(taskData)
       
       // Reload tasks
-      const updatedTasks = await tasksAPI.getTasks()
+      const updatedTasks = await tasksAPI.getTasks({ instances: 'all' })
       setTasks(updatedTasks)
       
       // Reset form and close modal
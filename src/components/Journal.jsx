@@ -50,7 +50,9 @@ const Journal = () => {
 
   useEffect(() => {
     // Always check for today's entry on mount
-    const today = new Date().toISOString().split('T')[0]
+    // Local calendar day; toISOString() would give the UTC day
+    const now = new Date()
+    const today = `${now.getFullYear()}-${String(now.getMonth() + 1).padStart(2, '0')}-${String(now.getDate()).padStart(2, '0')}`
     setSelectedDate(today)
     checkExistingEntry()
     loadRecentEntries()
@@ -86,8 +88,8 @@ const Journal = () => {
   const checkExistingEntry = async () => {
     setCheckingEntry(true)
     try {
-      const today = new Date().toISOString().split('T')[0]
-      const entry = await journalAPI.getJournalEntryByDate(today)
+      // The backend resolves "today" in the user's time zone
+      const entry = await journalAPI.getJournalEntryByDate('today')
       if (entry) {
         // Entry exists for today
         setHasEntryForToday(true)
@@ -121,25 +123,14 @@ const Journal = () => {
   const generateSummary = async () => {
     setIsGeneratingSummary(true)
     try {
-      const entries = await journalAPI.getJournalEntries()
-      const cutoffDate = new Date()
-      cutoffDate.setDate(cutoffDate.getDate() - summaryDays)
-      
-      const filteredEntries = entries.filter(entry => {
-        const entryDate = new Date(entry.entry_date)
-        return entryDate >= cutoffDate
-      }).sort((a, b) => new Date(a.entry_date) - new Date(b.entry_date))
-
-      if (filteredEntries.length === 0) {
-        alert(`Keine Journal-Einträge in den letzten ${summaryDays} Tagen gefunden.`)
-        setIsGeneratingSummary(false)
-        return
-      }
-
-      const summaryText = await journalAPI.generateSummary(filteredEntries, summaryDays)
+      const summaryText = await journalAPI.generateSummary(summaryDays)
       setSummary(summaryText)
       setShowSummaryDialog(false)
     } catch (error) {
+      if (error.message === 'No journal entries in this period') {
+        alert(`Keine Journal-Einträge in den letzten ${summaryDays} Tagen gefunden.`)
+        return
+      }
       console.error('Failed to generate summary:', error)
       alert(`Fehler beim Erstellen der Zusammenfassung: ${error.message || 'Bitte versuche es erneut.'}`)
     } finally {
@@ -1,4 +1,4 @@
-import React, { createContext, useContext, useState, useEffect } from 'react'
+import React, { createContext, useContext, useState, useEffect, useRef } from 'react'
 import { authAPI, authUtils } from '../services/api'
 
 const AuthContext = createContext()
@@ -15,12 +15,34 @@ export const AuthProvider = ({ children }) => {
   const [user, setUser] = useState(null)
   const [loading, setLoading] = useState(true)
   const [isAuthenticated, setIsAuthenticated] = useState(false)
+  const refreshTimer = useRef(null)
+
+  // Renew the access token shortly before it expires
+  const scheduleRefresh = (expiresIn) => {
+    clearTimeout(refreshTimer.current)
+    if (!expiresIn) return
+    const delay = Math.max((expiresIn - 60) * 1000, 10000)
+    refreshTimer.current = setTimeout(async () => {
+      try {
+        const tokens = await authAPI.refresh()
+        authUtils.storeAuthData(tokens)
+        scheduleRefresh(tokens.expires_in)
+      } catch (error) {
+        console.error('Token refresh failed:', error)
+        logout()
+      }
+    }, delay)
+  }
 
   useEffect(() => {
     // Check if user is already logged in
     const checkAuth = async () => {
       try {
         if (authUtils.isAuthenticated()) {
+          // The stored access token may have expired while the app was closed
+          const tokens = await authAPI.refresh()
+          authUtils.storeAuthData(tokens)
+          scheduleRefresh(tokens.expires_in)
           const userData = await authAPI.getMe()
           setUser(userData)
           setIsAuthenticated(true)
@@ -34,12 +56,14 @@ export const AuthProvider = ({ children }) => {
     }
 
     checkAuth()
+    return () => clearTimeout(refreshTimer.current)
   }, [])
 
   const login = async (credentials) => {
     try {
       const response = await authAPI.login(credentials)
       authUtils.storeAuthData(response)
+      scheduleRefresh(response.expires_in)
       setUser(response.user)
       setIsAuthenticated(true)
       return response
@@ -52,6 +76,7 @@ export const AuthProvider = ({ children }) => {
     try {
       const response = await authAPI.register(userData)
       authUtils.storeAuthData(response)
+      scheduleRefresh(response.expires_in)
       setUser(response.user)
       setIsAuthenticated(true)
       return response
@@ -61,6 +86,8 @@ export const AuthProvider = ({ children }) => {
   }
 
   const logout = () => {
+    clearTimeout(refreshTimer.current)
+    authAPI.logout().catch((error) => console.error('Logout failed:', error))
     authUtils.logout()
     setUser(null)
     setIsAuthenticated(false)
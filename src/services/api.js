@@ -10,7 +10,8 @@ export const authAPI = {
       headers: {
         'Content-Type': 'application/json',
       },
-      body: JSON.stringify(userData),
+      // Days, streaks and due dates are evaluated in the browser's time zone
+      body: JSON.stringify({ timezone: Intl.DateTimeFormat().resolvedOptions().timeZone, ...userData }),
     });
     
     if (!response.ok) {
@@ -39,6 +40,99 @@ export const authAPI = {
     return response.json();
   },
 
+  // Exchange the refresh token for a new token pair
+  refresh: async () => {
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (!refreshToken) {
+      throw new Error('No refresh token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/auth/refresh`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ refresh_token: refreshToken }),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Token refresh failed');
+    }
+
+    return response.json();
+  },
+
+  // Revoke the current session on the server
+  logout: async () => {
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (!refreshToken) {
+      return;
+    }
+
+    await fetch(`${API_BASE_URL}/auth/logout`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ refresh_token: refreshToken }),
+    });
+  },
+
+  // Request a password reset email
+  forgotPassword: async (email) => {
+    const response = await fetch(`${API_BASE_URL}/auth/forgot-password`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ email }),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Password reset request failed');
+    }
+
+    return response.json();
+  },
+
+  // Set a new password with the token from the reset email
+  resetPassword: async (token, password) => {
+    const response = await fetch(`${API_BASE_URL}/auth/reset-password`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ token, password }),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Password reset failed');
+    }
+
+    return response.json();
+  },
+
+  // Confirm the email address with the token from the verification email
+  verifyEmail: async (token) => {
+    const response = await fetch(`${API_BASE_URL}/auth/verify-email`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ token }),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Email verification failed');
+    }
+
+    return response.json();
+  },
+
   // Get current user
   getMe: async () => {
     const token = localStorage.getItem('token');
@@ -46,87 +140,1565 @@ export const authAPI = {
       throw new Error('No token found');
     }
 
-    const response = await fetch(`${API_BASE_URL}/auth/me`, {
-      method: 'GET',
+    const response = await fetch(`${API_BASE_URL}/auth/me`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+    
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to get user info');
+    }
+    
+    return response.json();
+  },
+
+  // Update user settings, e.g. { timezone: 'Europe/Berlin' }
+  updateSettings: async (settings) => {
+    const token = localStorage.getItem('token');
+    const response = await fetch(`${API_BASE_URL}/auth/settings`, {
+      method: 'PUT',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(settings),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to update settings');
+    }
+
+    return response.json();
+  },
+};
+
+// API Service for Habits
+export const habitsAPI = {
+  // Get all habits
+  getHabits: async () => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+    
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch habits');
+    }
+    
+    return response.json();
+  },
+
+  // Create a new habit
+  createHabit: async (habitData) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(habitData),
+    });
+    
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to create habit');
+    }
+    
+    return response.json();
+  },
+
+  // Get habit completions for a week
+  getHabitCompletions: async (habitId = null, startDate = null) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    let url = `${API_BASE_URL}/habits/completions`;
+    const params = new URLSearchParams();
+    if (habitId) {
+      params.append('habit_id', habitId);
+    }
+    if (startDate) {
+      params.append('start_date', startDate);
+    }
+    if (params.toString()) {
+      url += '?' + params.toString();
+    }
+
+    const response = await fetch(url, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+    
+    if (!response.ok) {
+      let errorData;
+      try {
+        errorData = await response.json();
+      } catch (e) {
+        errorData = { error: `HTTP ${response.status}: ${response.statusText}` };
+      }
+      const errorMessage = errorData.details ? `${errorData.error}: ${errorData.details}` : (errorData.error || 'Failed to fetch completions');
+      console.error('Get completions API error:', errorMessage, errorData);
+      throw new Error(errorMessage);
+    }
+    
+    return response.json();
+  },
+
+  // Complete a habit
+  completeHabit: async (habitId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/${habitId}/complete`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+    
+    if (!response.ok) {
+      let errorData;
+      try {
+        errorData = await response.json();
+      } catch (e) {
+        errorData = { error: `HTTP ${response.status}: ${response.statusText}` };
+      }
+      const errorMessage = errorData.details ? `${errorData.error}: ${errorData.details}` : (errorData.error || 'Failed to complete habit');
+      console.error('Complete habit API error:', errorMessage, errorData);
+      throw new Error(errorMessage);
+    }
+    
+    return response.json();
+  },
+
+  // Log a value for a measurable habit, optionally for a past day (YYYY-MM-DD)
+  logHabit: async (habitId, value, date = null) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/${habitId}/log`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(date ? { value, date } : { value }),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to log habit value');
+    }
+
+    return response.json();
+  },
+
+  // Set or clear a habit completion for a past day (YYYY-MM-DD)
+  setHabitCompletion: async (habitId, date, completed) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/${habitId}/completions/${date}`, {
+      method: 'PUT',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ completed }),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to update completion');
+    }
+
+    return response.json();
+  },
+
+  // Get archived habits
+  getArchivedHabits: async () => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits?archived=true`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch archived habits');
+    }
+
+    return response.json();
+  },
+
+  // Completion rates, streaks, weekday success and a year of heatmap data for one habit
+  getHabitStats: async (habitId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/${habitId}/stats`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch habit statistics');
+    }
+
+    return response.json();
+  },
+
+  // The same statistics across all habits
+  getAllHabitStats: async () => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/stats`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch habit statistics');
+    }
+
+    return response.json();
+  },
+
+  // Update a habit
+  updateHabit: async (habitId, habitData) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/${habitId}`, {
+      method: 'PUT',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(habitData),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to update habit');
+    }
+
+    return response.json();
+  },
+
+  // Delete a habit and its history
+  deleteHabit: async (habitId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/${habitId}`, {
+      method: 'DELETE',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to delete habit');
+    }
+
+    return response.json();
+  },
+
+  // List rest/vacation periods; missed habits on these days keep streaks
+  getRestPeriods: async () => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/rest-periods`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch rest periods');
+    }
+
+    return response.json();
+  },
+
+  // Declare rest days from startDate to endDate (YYYY-MM-DD, inclusive)
+  createRestPeriod: async (startDate, endDate, reason = '') => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/rest-periods`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ start_date: startDate, end_date: endDate, reason }),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to create rest period');
+    }
+
+    return response.json();
+  },
+
+  // Delete a rest period
+  deleteRestPeriod: async (periodId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/rest-periods/${periodId}`, {
+      method: 'DELETE',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to delete rest period');
+    }
+
+    return response.json();
+  },
+
+  // Streak freezes left this month and the days they covered
+  getStreakFreezes: async () => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/freezes`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch streak freezes');
+    }
+
+    return response.json();
+  },
+
+  // Get the user's habit categories
+  getHabitCategories: async () => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/categories`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch categories');
+    }
+
+    return response.json();
+  },
+
+  // Create a habit category
+  createHabitCategory: async (categoryData) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/categories`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(categoryData),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to create category');
+    }
+
+    return response.json();
+  },
+
+  // Update a habit category
+  updateHabitCategory: async (categoryId, categoryData) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/categories/${categoryId}`, {
+      method: 'PUT',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(categoryData),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to update category');
+    }
+
+    return response.json();
+  },
+
+  // Delete an unused habit category
+  deleteHabitCategory: async (categoryId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/categories/${categoryId}`, {
+      method: 'DELETE',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to delete category');
+    }
+
+    return response.json();
+  },
+
+  // Get routines with today's progress
+  getRoutines: async () => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/routines`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch routines');
+    }
+
+    return response.json();
+  },
+
+  // Get a single routine
+  getRoutine: async (routineId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/routines/${routineId}`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch routine');
+    }
+
+    return response.json();
+  },
+
+  // Create a routine from an ordered list of habit_ids
+  createRoutine: async (routineData) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/routines`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(routineData),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to create routine');
+    }
+
+    return response.json();
+  },
+
+  // Update a routine
+  updateRoutine: async (routineId, routineData) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/routines/${routineId}`, {
+      method: 'PUT',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(routineData),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to update routine');
+    }
+
+    return response.json();
+  },
+
+  // Delete a routine
+  deleteRoutine: async (routineId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/routines/${routineId}`, {
+      method: 'DELETE',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to delete routine');
+    }
+
+    return response.json();
+  },
+
+  // Complete all open steps of a routine for today
+  completeRoutine: async (routineId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/routines/${routineId}/complete`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to complete routine');
+    }
+
+    return response.json();
+  },
+
+  // Complete the next open step of a routine
+  stepRoutine: async (routineId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/routines/${routineId}/step`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to complete routine step');
+    }
+
+    return response.json();
+  },
+
+  // Archive a habit
+  archiveHabit: async (habitId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/${habitId}/archive`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to archive habit');
+    }
+
+    return response.json();
+  },
+
+  // Restore an archived habit
+  unarchiveHabit: async (habitId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/habits/${habitId}/unarchive`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to unarchive habit');
+    }
+
+    return response.json();
+  },
+};
+
+// API Service for Tasks
+export const tasksAPI = {
+  // Get tasks, optionally filtered: { view, status, priority, due, due_from, due_to, q, sort, instances }.
+  // Recurring tasks are listed by their next open instance unless instances is 'all'.
+  getTasks: async (filters = {}) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    let url = `${API_BASE_URL}/tasks`;
+    const params = new URLSearchParams();
+    Object.entries(filters).forEach(([key, value]) => {
+      if (value !== undefined && value !== null && value !== '') {
+        params.append(key, value);
+      }
+    });
+    if (params.toString()) {
+      url += '?' + params.toString();
+    }
+
+    const response = await fetch(url, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+    
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch tasks');
+    }
+    
+    return response.json();
+  },
+
+  // Get one page of tasks; pass the returned nextCursor to get the next page
+  getTaskPage: async (filters = {}, limit = 50, cursor = null) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const params = new URLSearchParams();
+    Object.entries(filters).forEach(([key, value]) => {
+      if (value !== undefined && value !== null && value !== '') {
+        params.append(key, value);
+      }
+    });
+    params.append('limit', limit);
+    if (cursor) {
+      params.append('cursor', cursor);
+    }
+
+    const response = await fetch(`${API_BASE_URL}/tasks?${params.toString()}`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+    
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch tasks');
+    }
+    
+    const tasks = await response.json();
+    return { tasks: tasks || [], nextCursor: response.headers.get('X-Next-Cursor') };
+  },
+
+  // Get the predefined task views (Today, Upcoming, Someday)
+  getTaskViews: async () => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/tasks/views`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch task views');
+    }
+
+    return response.json();
+  },
+
+  // Create a new task
+  createTask: async (taskData) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/tasks`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(taskData),
+    });
+    
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to create task');
+    }
+    
+    return response.json();
+  },
+
+  // Complete a task
+  completeTask: async (taskId, cascade = false) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/tasks/${taskId}/complete${cascade ? '?cascade=true' : ''}`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+    
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to complete task');
+    }
+    
+    return response.json();
+  },
+
+  // Update a task; parent_id 0 moves it to the top level. For an occurrence of a
+  // recurring task, scope 'following' or 'all' edits the series
+  updateTask: async (taskId, taskData, scope = 'this') => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/tasks/${taskId}?scope=${scope}`, {
+      method: 'PUT',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(taskData),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to update task');
+    }
+
+    return response.json();
+  },
+
+  // Delete a task and its subtasks; scope 'following' or 'all' for recurring series
+  deleteTask: async (taskId, scope = 'this') => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/tasks/${taskId}?scope=${scope}`, {
+      method: 'DELETE',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to delete task');
+    }
+
+    return response.json();
+  },
+
+  // Get the recurring task templates
+  getRecurringTasks: async () => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/tasks/recurring`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch recurring tasks');
+    }
+
+    return response.json();
+  },
+
+  // Set the order of the tasks under one parent (null for top level)
+  reorderTasks: async (taskIds, parentId = null) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/tasks/reorder`, {
+      method: 'PUT',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ parent_id: parentId, task_ids: taskIds }),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to reorder tasks');
+    }
+
+    return response.json();
+  },
+
+  // Create a task from one line like "Zahnarzt anrufen morgen 9 Uhr !hoch #gesundheit"; options: { preview, use_ai, project_id }
+  quickAddTask: async (text, options = {}) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/tasks/quick`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ text, ...options }),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to add task');
+    }
+
+    return response.json();
+  },
+
+  // Get projects with their progress; archived ones when archived is true
+  getProjects: async (archived = false) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/projects${archived ? '?archived=true' : ''}`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch projects');
+    }
+
+    return response.json();
+  },
+
+  // Get a project with its progress
+  getProject: async (projectId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/projects/${projectId}`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch project');
+    }
+
+    return response.json();
+  },
+
+  // Create a new project
+  createProject: async (projectData) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/projects`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(projectData),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to create project');
+    }
+
+    return response.json();
+  },
+
+  // Update a project
+  updateProject: async (projectId, projectData) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/projects/${projectId}`, {
+      method: 'PUT',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(projectData),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to update project');
+    }
+
+    return response.json();
+  },
+
+  // Delete a project; its tasks are kept without a project
+  deleteProject: async (projectId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/projects/${projectId}`, {
+      method: 'DELETE',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to delete project');
+    }
+
+    return response.json();
+  },
+
+  // Archive a project
+  archiveProject: async (projectId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/projects/${projectId}/archive`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to archive project');
+    }
+
+    return response.json();
+  },
+
+  // Restore an archived project
+  unarchiveProject: async (projectId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/projects/${projectId}/unarchive`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to restore project');
+    }
+
+    return response.json();
+  },
+
+  // Get all task labels
+  getLabels: async () => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/labels`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch labels');
+    }
+
+    return response.json();
+  },
+
+  // Create a new task label
+  createLabel: async (labelData) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/labels`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(labelData),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to create label');
+    }
+
+    return response.json();
+  },
+
+  // Update a task label
+  updateLabel: async (labelId, labelData) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/labels/${labelId}`, {
+      method: 'PUT',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(labelData),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to update label');
+    }
+
+    return response.json();
+  },
+
+  // Delete a task label
+  deleteLabel: async (labelId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/labels/${labelId}`, {
+      method: 'DELETE',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to delete label');
+    }
+
+    return response.json();
+  },
+};
+
+// API Service for Notifications
+export const notificationsAPI = {
+  // Get notifications, newest first ({ unread: true, limit })
+  getNotifications: async (filters = {}) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    let url = `${API_BASE_URL}/notifications`;
+    const params = new URLSearchParams();
+    Object.entries(filters).forEach(([key, value]) => {
+      if (value !== undefined && value !== null && value !== '') {
+        params.append(key, value);
+      }
+    });
+    if (params.toString()) {
+      url += '?' + params.toString();
+    }
+
+    const response = await fetch(url, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch notifications');
+    }
+
+    return response.json();
+  },
+
+  // Mark a notification as read
+  markNotificationRead: async (id) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/notifications/${id}/read`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to mark notification as read');
+    }
+
+    return response.json();
+  },
+
+  // Mark all notifications as read
+  markAllNotificationsRead: async () => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/notifications/read-all`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to mark notifications as read');
+    }
+
+    return response.json();
+  },
+
+  // Get the VAPID public key for pushManager.subscribe
+  getPushKey: async () => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/notifications/push/key`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch push key');
+    }
+
+    return response.json();
+  },
+
+  // Register a PushSubscription of this browser
+  subscribePush: async (subscription) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/notifications/push/subscriptions`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(subscription),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to save push subscription');
+    }
+
+    return response.json();
+  },
+
+  // Unregister a push endpoint
+  unsubscribePush: async (endpoint) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/notifications/push/subscriptions`, {
+      method: 'DELETE',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ endpoint }),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to delete push subscription');
+    }
+
+    return response.json();
+  },
+};
+
+// API Service for Insights
+export const insightsAPI = {
+  // Get mood time series, weekly averages and per-habit mood differences of the last days (default 90)
+  getMoodInsights: async (days) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const query = days ? `?days=${days}` : '';
+    const response = await fetch(`${API_BASE_URL}/insights/mood${query}`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch mood insights');
+    }
+
+    return response.json();
+  },
+};
+
+// API Service for Journal
+export const journalAPI = {
+  // Get all journal entries
+  getJournalEntries: async () => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/journal`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+    
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch journal entries');
+    }
+    
+    return response.json();
+  },
+
+  // Get journal entry by date
+  getJournalEntryByDate: async (date) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/journal/${date}`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+    
+    if (!response.ok) {
+      // 404 means no entry exists for this date - this is normal
+      if (response.status === 404) {
+        throw new Error('Journal entry not found');
+      }
+      const error = await response.json().catch(() => ({ error: 'Failed to fetch journal entry' }));
+      throw new Error(error.error || 'Failed to fetch journal entry');
+    }
+    
+    return response.json();
+  },
+
+  // Create or update journal entry
+  createOrUpdateJournalEntry: async (entryData) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/journal`, {
+      method: 'POST',
       headers: {
         'Authorization': `Bearer ${token}`,
         'Content-Type': 'application/json',
       },
+      body: JSON.stringify(entryData),
     });
     
     if (!response.ok) {
       const error = await response.json();
-      throw new Error(error.error || 'Failed to get user info');
+      throw new Error(error.error || 'Failed to save journal entry');
     }
     
     return response.json();
   },
-};
 
-// API Service for Habits
-export const habitsAPI = {
-  // Get all habits
-  getHabits: async () => {
+  // Update journal entry
+  updateJournalEntry: async (entryId, entryData) => {
     const token = localStorage.getItem('token');
     if (!token) {
       throw new Error('No token found');
     }
 
-    const response = await fetch(`${API_BASE_URL}/habits`, {
-      method: 'GET',
+    const response = await fetch(`${API_BASE_URL}/journal/${entryId}`, {
+      method: 'PUT',
       headers: {
         'Authorization': `Bearer ${token}`,
         'Content-Type': 'application/json',
       },
+      body: JSON.stringify(entryData),
     });
     
     if (!response.ok) {
       const error = await response.json();
-      throw new Error(error.error || 'Failed to fetch habits');
+      throw new Error(error.error || 'Failed to update journal entry');
     }
     
     return response.json();
   },
 
-  // Create a new habit
-  createHabit: async (habitData) => {
+  // Delete journal entry
+  deleteJournalEntry: async (entryId) => {
     const token = localStorage.getItem('token');
     if (!token) {
       throw new Error('No token found');
     }
 
-    const response = await fetch(`${API_BASE_URL}/habits`, {
-      method: 'POST',
+    const response = await fetch(`${API_BASE_URL}/journal/${entryId}`, {
+      method: 'DELETE',
       headers: {
         'Authorization': `Bearer ${token}`,
         'Content-Type': 'application/json',
       },
-      body: JSON.stringify(habitData),
     });
     
     if (!response.ok) {
       const error = await response.json();
-      throw new Error(error.error || 'Failed to create habit');
+      throw new Error(error.error || 'Failed to delete journal entry');
     }
     
     return response.json();
   },
 
-  // Get habit completions for a week
-  getHabitCompletions: async (habitId = null, startDate = null) => {
+  // Search journal entries ({ q, tags: 'a,b', mood, from, to, limit, template_id,
+  // fields: { key: value } filters on the template's fields })
+  searchJournalEntries: async (filters = {}) => {
     const token = localStorage.getItem('token');
     if (!token) {
       throw new Error('No token found');
     }
 
-    let url = `${API_BASE_URL}/habits/completions`;
+    let url = `${API_BASE_URL}/journal/search`;
     const params = new URLSearchParams();
-    if (habitId) {
-      params.append('habit_id', habitId);
-    }
-    if (startDate) {
-      params.append('start_date', startDate);
-    }
+    Object.entries(filters).forEach(([key, value]) => {
+      if (key === 'fields' && value) {
+        Object.entries(value).forEach(([field, fieldValue]) => params.append(`fields[${field}]`, fieldValue));
+      } else if (value !== undefined && value !== null && value !== '') {
+        params.append(key, value);
+      }
+    });
     if (params.toString()) {
       url += '?' + params.toString();
     }
@@ -138,275 +1710,254 @@ export const habitsAPI = {
         'Content-Type': 'application/json',
       },
     });
-    
+
     if (!response.ok) {
-      let errorData;
-      try {
-        errorData = await response.json();
-      } catch (e) {
-        errorData = { error: `HTTP ${response.status}: ${response.statusText}` };
-      }
-      const errorMessage = errorData.details ? `${errorData.error}: ${errorData.details}` : (errorData.error || 'Failed to fetch completions');
-      console.error('Get completions API error:', errorMessage, errorData);
-      throw new Error(errorMessage);
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to search journal entries');
     }
-    
+
     return response.json();
   },
 
-  // Complete a habit
-  completeHabit: async (habitId) => {
+  // Get journal tags with entry counts
+  getJournalTags: async () => {
     const token = localStorage.getItem('token');
     if (!token) {
       throw new Error('No token found');
     }
 
-    const response = await fetch(`${API_BASE_URL}/habits/${habitId}/complete`, {
-      method: 'POST',
+    const response = await fetch(`${API_BASE_URL}/journal/tags`, {
+      method: 'GET',
       headers: {
         'Authorization': `Bearer ${token}`,
         'Content-Type': 'application/json',
       },
     });
-    
+
     if (!response.ok) {
-      let errorData;
-      try {
-        errorData = await response.json();
-      } catch (e) {
-        errorData = { error: `HTTP ${response.status}: ${response.statusText}` };
-      }
-      const errorMessage = errorData.details ? `${errorData.error}: ${errorData.details}` : (errorData.error || 'Failed to complete habit');
-      console.error('Complete habit API error:', errorMessage, errorData);
-      throw new Error(errorMessage);
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch journal tags');
     }
-    
+
     return response.json();
   },
-};
 
-// API Service for Tasks
-export const tasksAPI = {
-  // Get all tasks
-  getTasks: async () => {
+  // Rename a journal tag on all entries (merges into an existing tag of that name)
+  renameJournalTag: async (tagId, name) => {
     const token = localStorage.getItem('token');
     if (!token) {
       throw new Error('No token found');
     }
 
-    const response = await fetch(`${API_BASE_URL}/tasks`, {
-      method: 'GET',
+    const response = await fetch(`${API_BASE_URL}/journal/tags/${tagId}`, {
+      method: 'PUT',
       headers: {
         'Authorization': `Bearer ${token}`,
         'Content-Type': 'application/json',
       },
+      body: JSON.stringify({ name }),
     });
-    
+
     if (!response.ok) {
       const error = await response.json();
-      throw new Error(error.error || 'Failed to fetch tasks');
+      throw new Error(error.error || 'Failed to rename tag');
     }
-    
+
     return response.json();
   },
 
-  // Create a new task
-  createTask: async (taskData) => {
+  // Remove a journal tag from all entries
+  deleteJournalTag: async (tagId) => {
     const token = localStorage.getItem('token');
     if (!token) {
       throw new Error('No token found');
     }
 
-    const response = await fetch(`${API_BASE_URL}/tasks`, {
-      method: 'POST',
+    const response = await fetch(`${API_BASE_URL}/journal/tags/${tagId}`, {
+      method: 'DELETE',
       headers: {
         'Authorization': `Bearer ${token}`,
         'Content-Type': 'application/json',
       },
-      body: JSON.stringify(taskData),
     });
-    
+
     if (!response.ok) {
       const error = await response.json();
-      throw new Error(error.error || 'Failed to create task');
+      throw new Error(error.error || 'Failed to delete tag');
     }
-    
+
     return response.json();
   },
 
-  // Complete a task
-  completeTask: async (taskId) => {
+  // Get journal templates
+  getJournalTemplates: async () => {
     const token = localStorage.getItem('token');
     if (!token) {
       throw new Error('No token found');
     }
 
-    const response = await fetch(`${API_BASE_URL}/tasks/${taskId}/complete`, {
-      method: 'POST',
+    const response = await fetch(`${API_BASE_URL}/journal/templates`, {
+      method: 'GET',
       headers: {
         'Authorization': `Bearer ${token}`,
         'Content-Type': 'application/json',
       },
     });
-    
+
     if (!response.ok) {
       const error = await response.json();
-      throw new Error(error.error || 'Failed to complete task');
+      throw new Error(error.error || 'Failed to fetch journal templates');
     }
-    
+
     return response.json();
   },
-};
 
-// API Service for Journal
-export const journalAPI = {
-  // Get all journal entries
-  getJournalEntries: async () => {
+  // Create a journal template ({ name, description, fields: [{ key, label, type: 'list' | 'scale' | 'text' | 'checkbox', required }] })
+  createJournalTemplate: async (template) => {
     const token = localStorage.getItem('token');
     if (!token) {
       throw new Error('No token found');
     }
 
-    const response = await fetch(`${API_BASE_URL}/journal`, {
-      method: 'GET',
+    const response = await fetch(`${API_BASE_URL}/journal/templates`, {
+      method: 'POST',
       headers: {
         'Authorization': `Bearer ${token}`,
         'Content-Type': 'application/json',
       },
+      body: JSON.stringify(template),
     });
-    
+
     if (!response.ok) {
       const error = await response.json();
-      throw new Error(error.error || 'Failed to fetch journal entries');
+      throw new Error(error.error || 'Failed to create journal template');
     }
-    
+
     return response.json();
   },
 
-  // Get journal entry by date
-  getJournalEntryByDate: async (date) => {
+  // Replace the name, description and fields of a journal template
+  updateJournalTemplate: async (templateId, template) => {
     const token = localStorage.getItem('token');
     if (!token) {
       throw new Error('No token found');
     }
 
-    const response = await fetch(`${API_BASE_URL}/journal/${date}`, {
-      method: 'GET',
+    const response = await fetch(`${API_BASE_URL}/journal/templates/${templateId}`, {
+      method: 'PUT',
       headers: {
         'Authorization': `Bearer ${token}`,
         'Content-Type': 'application/json',
       },
+      body: JSON.stringify(template),
     });
-    
+
     if (!response.ok) {
-      // 404 means no entry exists for this date - this is normal
-      if (response.status === 404) {
-        throw new Error('Journal entry not found');
-      }
-      const error = await response.json().catch(() => ({ error: 'Failed to fetch journal entry' }));
-      throw new Error(error.error || 'Failed to fetch journal entry');
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to update journal template');
     }
-    
+
     return response.json();
   },
 
-  // Create or update journal entry
-  createOrUpdateJournalEntry: async (entryData) => {
+  // Delete a journal template; its entries keep their answers
+  deleteJournalTemplate: async (templateId) => {
     const token = localStorage.getItem('token');
     if (!token) {
       throw new Error('No token found');
     }
 
-    const response = await fetch(`${API_BASE_URL}/journal`, {
-      method: 'POST',
+    const response = await fetch(`${API_BASE_URL}/journal/templates/${templateId}`, {
+      method: 'DELETE',
       headers: {
         'Authorization': `Bearer ${token}`,
         'Content-Type': 'application/json',
       },
-      body: JSON.stringify(entryData),
     });
-    
+
     if (!response.ok) {
       const error = await response.json();
-      throw new Error(error.error || 'Failed to save journal entry');
+      throw new Error(error.error || 'Failed to delete journal template');
     }
-    
+
     return response.json();
   },
 
-  // Update journal entry
-  updateJournalEntry: async (entryId, entryData) => {
+  // Get statistics of the answers to a template's fields over the last days (default 90)
+  getJournalTemplateInsights: async (templateId, days) => {
     const token = localStorage.getItem('token');
     if (!token) {
       throw new Error('No token found');
     }
 
-    const response = await fetch(`${API_BASE_URL}/journal/${entryId}`, {
-      method: 'PUT',
+    const response = await fetch(`${API_BASE_URL}/journal/templates/${templateId}/insights${days ? `?days=${days}` : ''}`, {
+      method: 'GET',
       headers: {
         'Authorization': `Bearer ${token}`,
         'Content-Type': 'application/json',
       },
-      body: JSON.stringify(entryData),
     });
-    
+
     if (!response.ok) {
       const error = await response.json();
-      throw new Error(error.error || 'Failed to update journal entry');
+      throw new Error(error.error || 'Failed to fetch journal template insights');
     }
-    
+
     return response.json();
   },
 
-  // Delete journal entry
-  deleteJournalEntry: async (entryId) => {
+  // Generate AI questions for journal
+  generateJournalQuestions: async (contextData) => {
     const token = localStorage.getItem('token');
     if (!token) {
       throw new Error('No token found');
     }
 
-    const response = await fetch(`${API_BASE_URL}/journal/${entryId}`, {
-      method: 'DELETE',
+    const response = await fetch(`${API_BASE_URL}/journal/generate-questions`, {
+      method: 'POST',
       headers: {
         'Authorization': `Bearer ${token}`,
         'Content-Type': 'application/json',
       },
+      body: JSON.stringify(contextData),
     });
     
     if (!response.ok) {
       const error = await response.json();
-      throw new Error(error.error || 'Failed to delete journal entry');
+      throw new Error(error.error || 'Failed to generate questions');
     }
     
     return response.json();
   },
 
-  // Generate AI questions for journal
-  generateJournalQuestions: async (contextData) => {
+  // Generate a summary of the journal entries of the last days
+  generateSummary: async (days) => {
     const token = localStorage.getItem('token');
     if (!token) {
       throw new Error('No token found');
     }
 
-    const response = await fetch(`${API_BASE_URL}/journal/generate-questions`, {
+    const response = await fetch(`${API_BASE_URL}/journal/summarize`, {
       method: 'POST',
       headers: {
         'Authorization': `Bearer ${token}`,
         'Content-Type': 'application/json',
       },
-      body: JSON.stringify(contextData),
+      body: JSON.stringify({ days }),
     });
     
     if (!response.ok) {
       const error = await response.json();
-      throw new Error(error.error || 'Failed to generate questions');
+      throw new Error(error.error || 'Failed to generate summary');
     }
     
-    return response.json();
+    const data = await response.json();
+    return data.summary;
   },
 
-  // Generate summary of journal entries
-  generateSummary: async (entries, days) => {
+  // Get the summary of the week or month ('week' | 'month') containing date (default today),
+  // generated once and regenerated only when its entries changed
+  getPeriodSummary: async (period, date) => {
     const token = localStorage.getItem('token');
     if (!token) {
       throw new Error('No token found');
@@ -418,16 +1969,43 @@ export const journalAPI = {
         'Authorization': `Bearer ${token}`,
         'Content-Type': 'application/json',
       },
-      body: JSON.stringify({ entries, days }),
+      body: JSON.stringify({ period, date }),
     });
-    
+
     if (!response.ok) {
       const error = await response.json();
       throw new Error(error.error || 'Failed to generate summary');
     }
-    
-    const data = await response.json();
-    return data.summary;
+
+    return response.json();
+  },
+
+  // Get generated week and month summaries, newest first ({ period, limit })
+  getJournalSummaries: async (filters = {}) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const params = new URLSearchParams();
+    if (filters.period) params.append('period', filters.period);
+    if (filters.limit) params.append('limit', filters.limit);
+    const query = params.toString() ? `?${params.toString()}` : '';
+
+    const response = await fetch(`${API_BASE_URL}/journal/summaries${query}`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch journal summaries');
+    }
+
+    return response.json();
   },
 };
 export const chatAPI = {
@@ -536,6 +2114,7 @@ export const authUtils = {
   // Logout user
   logout: () => {
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
     localStorage.removeItem('user');
   },
 
@@ -547,7 +2126,10 @@ export const authUtils = {
   // Store auth data
   storeAuthData: (authResponse) => {
     localStorage.setItem('token', authResponse.token);
-    localStorage.setItem('user', JSON.stringify(authResponse.user));
+    localStorage.setItem('refresh_token', authResponse.refresh_token);
+    if (authResponse.user) {
+      localStorage.setItem('user', JSON.stringify(authResponse.user));
+    }
   },
 
   // Get stored user
@@ -764,6 +2346,53 @@ export const notesAPI = {
     return response.json();
   },
 
+  // Turn a checklist item into a linked task; without due_date it is due on the matching plan milestone
+  promoteChecklistItem: async (noteId, itemId, taskData = {}) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/notes/${noteId}/checklist/${itemId}/promote`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(taskData),
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to promote checklist item');
+    }
+
+    return response.json();
+  },
+
+  // Get the checklist progress and milestones of a plan
+  getPlanProgress: async (noteId) => {
+    const token = localStorage.getItem('token');
+    if (!token) {
+      throw new Error('No token found');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/notes/${noteId}/plan/progress`, {
+      method: 'GET',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+    });
+
+    if (!response.ok) {
+      const error = await response.json();
+      throw new Error(error.error || 'Failed to fetch plan progress');
+    }
+
+    return response.json();
+  },
+
   // Get plan data for a note
   getPlanData: async (noteId) => {
     const token = localStorage.getItem('token');